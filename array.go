@@ -0,0 +1,188 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import "math"
+
+// ArrayIter represents a pull-based iterator over the elements of an encoded array,
+// which reads one element at a time instead of materializing the entire array. The
+// iteration stops early if any of the elements fails to be read.
+//
+//	it := r.Array()
+//	for it.Next() {
+//		name := it.Text()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ArrayIter struct {
+	src    *Reader
+	length int
+	next   int
+	err    error
+}
+
+// Array reads the length of an array and returns an iterator over its elements.
+// Each element must be read exactly once after a call to Next, either with the
+// iterator or directly with the reader. If the iteration is stopped early, the
+// reader remains positioned in the middle of the array.
+func (r *Reader) Array() *ArrayIter {
+	length, err := r.ReadUvarint()
+	if err == nil && length > math.MaxInt {
+		length, err = 0, errSize
+	}
+
+	return &ArrayIter{
+		src:    r,
+		length: int(length),
+		err:    err,
+	}
+}
+
+// Len returns the number of elements in the array
+func (it *ArrayIter) Len() int {
+	return it.length
+}
+
+// Err returns the first error encountered while reading the array
+func (it *ArrayIter) Err() error {
+	return it.err
+}
+
+// Next advances the iterator to the next element of the array. It returns false
+// when there are no more elements or an error has occurred.
+func (it *ArrayIter) Next() bool {
+	if it.err != nil || it.next >= it.length {
+		return false
+	}
+
+	it.next++
+	return true
+}
+
+// fail keeps the first error encountered
+func (it *ArrayIter) fail(err error) {
+	if it.err == nil {
+		it.err = err
+	}
+}
+
+// Text reads the current element as a string
+func (it *ArrayIter) Text() string {
+	v, err := it.src.ReadString()
+	it.fail(err)
+	return v
+}
+
+// Bytes reads the current element as a byte slice
+func (it *ArrayIter) Bytes() []byte {
+	v, err := it.src.ReadBytes()
+	it.fail(err)
+	return v
+}
+
+// Bool reads the current element as a bool
+func (it *ArrayIter) Bool() bool {
+	v, err := it.src.ReadBool()
+	it.fail(err)
+	return v
+}
+
+// Uvarint reads the current element as a variable-size uint64
+func (it *ArrayIter) Uvarint() uint64 {
+	v, err := it.src.ReadUvarint()
+	it.fail(err)
+	return v
+}
+
+// Uint8 reads the current element as a uint8
+func (it *ArrayIter) Uint8() uint8 {
+	v, err := it.src.ReadUint8()
+	it.fail(err)
+	return v
+}
+
+// Uint16 reads the current element as a uint16
+func (it *ArrayIter) Uint16() uint16 {
+	v, err := it.src.ReadUint16()
+	it.fail(err)
+	return v
+}
+
+// Uint32 reads the current element as a uint32
+func (it *ArrayIter) Uint32() uint32 {
+	v, err := it.src.ReadUint32()
+	it.fail(err)
+	return v
+}
+
+// Uint64 reads the current element as a uint64
+func (it *ArrayIter) Uint64() uint64 {
+	v, err := it.src.ReadUint64()
+	it.fail(err)
+	return v
+}
+
+// Uint reads the current element as a uint
+func (it *ArrayIter) Uint() uint {
+	v, err := it.src.ReadUint()
+	it.fail(err)
+	return v
+}
+
+// Varint reads the current element as a variable-size int64
+func (it *ArrayIter) Varint() int64 {
+	v, err := it.src.ReadVarint()
+	it.fail(err)
+	return v
+}
+
+// Int8 reads the current element as an int8
+func (it *ArrayIter) Int8() int8 {
+	v, err := it.src.ReadInt8()
+	it.fail(err)
+	return v
+}
+
+// Int16 reads the current element as an int16
+func (it *ArrayIter) Int16() int16 {
+	v, err := it.src.ReadInt16()
+	it.fail(err)
+	return v
+}
+
+// Int32 reads the current element as an int32
+func (it *ArrayIter) Int32() int32 {
+	v, err := it.src.ReadInt32()
+	it.fail(err)
+	return v
+}
+
+// Int64 reads the current element as an int64
+func (it *ArrayIter) Int64() int64 {
+	v, err := it.src.ReadInt64()
+	it.fail(err)
+	return v
+}
+
+// Int reads the current element as an int
+func (it *ArrayIter) Int() int {
+	v, err := it.src.ReadInt()
+	it.fail(err)
+	return v
+}
+
+// Float32 reads the current element as a float32
+func (it *ArrayIter) Float32() float32 {
+	v, err := it.src.ReadFloat32()
+	it.fail(err)
+	return v
+}
+
+// Float64 reads the current element as a float64
+func (it *ArrayIter) Float64() float64 {
+	v, err := it.src.ReadFloat64()
+	it.fail(err)
+	return v
+}
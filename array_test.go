@@ -0,0 +1,109 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayIter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewWriter(buffer)
+	assert.NoError(t, w.WriteStrings([]string{"a", "b", "c"}))
+	assert.NoError(t, w.WriteUint32s([]uint32{1, 2, 3}))
+	assert.NoError(t, w.WriteString("end"))
+
+	r := NewReader(newNetworkSource(buffer.Bytes()))
+	var names []string
+	it := r.Array()
+	assert.Equal(t, 3, it.Len())
+	for it.Next() {
+		names = append(names, it.Text())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	// Stop early and skip the remaining elements
+	it = r.Array()
+	for it.Next() {
+		if it.Uint32() == 2 {
+			break
+		}
+	}
+	assert.NoError(t, it.Err())
+	for it.Next() {
+		it.Uint32()
+	}
+
+	end, err := r.ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "end", end)
+}
+
+func TestArrayIterTypes(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewWriter(buffer)
+	assert.NoError(t, w.WriteRange(1, func(i int, w *Writer) error {
+		w.WriteBytes([]byte("hi"))
+		w.WriteBool(true)
+		w.WriteUvarint(1)
+		w.WriteUint8(2)
+		w.WriteUint16(3)
+		w.WriteUint32(4)
+		w.WriteUint64(5)
+		w.WriteUint(6)
+		w.WriteVarint(-1)
+		w.WriteInt8(-2)
+		w.WriteInt16(-3)
+		w.WriteInt32(-4)
+		w.WriteInt64(-5)
+		w.WriteInt(-6)
+		w.WriteFloat32(1.5)
+		return w.WriteFloat64(2.5)
+	}))
+
+	it := NewReader(buffer).Array()
+	assert.True(t, it.Next())
+	assert.Equal(t, []byte("hi"), it.Bytes())
+	assert.Equal(t, true, it.Bool())
+	assert.Equal(t, uint64(1), it.Uvarint())
+	assert.Equal(t, uint8(2), it.Uint8())
+	assert.Equal(t, uint16(3), it.Uint16())
+	assert.Equal(t, uint32(4), it.Uint32())
+	assert.Equal(t, uint64(5), it.Uint64())
+	assert.Equal(t, uint(6), it.Uint())
+	assert.Equal(t, int64(-1), it.Varint())
+	assert.Equal(t, int8(-2), it.Int8())
+	assert.Equal(t, int16(-3), it.Int16())
+	assert.Equal(t, int32(-4), it.Int32())
+	assert.Equal(t, int64(-5), it.Int64())
+	assert.Equal(t, int(-6), it.Int())
+	assert.Equal(t, float32(1.5), it.Float32())
+	assert.Equal(t, float64(2.5), it.Float64())
+	assert.False(t, it.Next())
+	assert.NoError(t, it.Err())
+}
+
+func TestArrayIterErrors(t *testing.T) {
+	it := NewReader(bytes.NewBuffer(nil)).Array()
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+
+	it = NewReader(bytes.NewBuffer([]byte{0x2, 0x1, 'a'})).Array()
+	assert.True(t, it.Next())
+	assert.Equal(t, "a", it.Text())
+	assert.True(t, it.Next())
+	assert.Equal(t, "", it.Text())
+	assert.Equal(t, uint64(0), it.Uvarint())
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+
+	it = NewReader(bytes.NewBuffer([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})).Array()
+	assert.Equal(t, 0, it.Len())
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+}
@@ -0,0 +1,658 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/kelindar/iostream"
+)
+
+var (
+	errLength = errors.New("avro: invalid length")
+	errUnion  = errors.New("avro: invalid union branch")
+	errEnum   = errors.New("avro: invalid enum symbol")
+	errInt    = errors.New("avro: int is out of range")
+	errDepth  = errors.New("avro: records are nested too deeply")
+)
+
+// The maximum length of bytes, strings and blocks
+const maxLength = math.MaxInt32
+
+// The maximum number of items of an array whose items are empty, such as nulls.
+// Since empty items do not consume any input, their count can not be checked
+// against the size of the input.
+const maxEmpty = 64 * 1024
+
+// The maximum nesting of records. Since recursive schemas can nest records without
+// limit, a corrupt input could otherwise exhaust the stack.
+const maxDepth = 1024
+
+// decodeFn decodes a value written with a schema into a value of another schema
+type decodeFn func(r *iostream.Reader) (interface{}, error)
+
+// skipFn skips a value written with a schema
+type skipFn func(r *iostream.Reader) error
+
+// Decoder represents a decoder of values described by a schema.
+type Decoder struct {
+	src    *iostream.Reader
+	decode decodeFn
+}
+
+// NewDecoder creates a new decoder of values written with the schema.
+func NewDecoder(schema *Schema, src *iostream.Reader) *Decoder {
+	decode, err := resolve(schema, schema)
+	if err != nil {
+		decode = fail(err)
+	}
+
+	return &Decoder{
+		src:    src,
+		decode: decode,
+	}
+}
+
+// NewResolvingDecoder creates a new decoder of values written with the writer
+// schema, which are converted into values of the reader schema. It returns an
+// error if the schemas are not compatible.
+func NewResolvingDecoder(writer, reader *Schema, src *iostream.Reader) (*Decoder, error) {
+	decode, err := resolve(writer, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Decoder{
+		src:    src,
+		decode: decode,
+	}, nil
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (d *Decoder) Offset() int64 {
+	return d.src.Offset()
+}
+
+// Decode decodes the next value. Values are represented the same way as they are
+// by the encoder, with int, long, float and double decoded as int32, int64,
+// float32 and float64 respectively.
+func (d *Decoder) Decode() (interface{}, error) {
+	return d.decode(d.src)
+}
+
+// --------------------------- Resolution ---------------------------
+
+// resolver compiles the functions which decode or skip values. The functions
+// are cached per schema, so recursive schemas can reference themselves.
+type resolver struct {
+	decoders map[[2]*Schema]*decodeFn
+	skippers map[*Schema]*skipFn
+	depth    int // The nesting of the records being decoded or skipped
+}
+
+// resolve compiles a function which decodes values of the writer schema into
+// values of the reader schema.
+func resolve(writer, reader *Schema) (decodeFn, error) {
+	c := &resolver{
+		decoders: make(map[[2]*Schema]*decodeFn),
+		skippers: make(map[*Schema]*skipFn),
+	}
+	return c.decoder(writer, reader)
+}
+
+// decoder returns a function which decodes the writer schema into the reader schema
+func (c *resolver) decoder(w, rd *Schema) (decodeFn, error) {
+	key := [2]*Schema{w, rd}
+	if fn, ok := c.decoders[key]; ok {
+		return func(r *iostream.Reader) (interface{}, error) {
+			return (*fn)(r)
+		}, nil
+	}
+
+	fn := new(decodeFn)
+	c.decoders[key] = fn
+	out, err := c.compile(w, rd)
+	if err != nil {
+		delete(c.decoders, key)
+		return nil, err
+	}
+
+	*fn = out
+	return out, nil
+}
+
+// compile compiles a function which decodes the writer schema into the reader schema
+func (c *resolver) compile(w, rd *Schema) (decodeFn, error) {
+	switch {
+	case w.Type == Union:
+		return c.compileUnion(w, rd), nil
+	case rd.Type == Union:
+		if branch := branchOf(w, rd); branch != nil {
+			return c.decoder(w, branch)
+		}
+		return nil, fmt.Errorf("avro: %s is not in the reader union", typeName(w))
+	case !compatible(w, rd):
+		return nil, fmt.Errorf("avro: %s can not be read as %s", typeName(w), typeName(rd))
+	}
+
+	switch w.Type {
+	case Null:
+		return func(r *iostream.Reader) (interface{}, error) {
+			return nil, nil
+		}, nil
+	case Boolean:
+		return func(r *iostream.Reader) (interface{}, error) {
+			return r.ReadBool()
+		}, nil
+	case Int, Long:
+		return func(r *iostream.Reader) (interface{}, error) {
+			v, err := r.ReadVarint()
+			switch {
+			case err != nil:
+				return nil, err
+			case rd.Type == Int && (v < math.MinInt32 || v > math.MaxInt32):
+				return nil, errInt
+			}
+
+			switch rd.Type {
+			case Int:
+				return int32(v), nil
+			case Float:
+				return float32(v), nil
+			case Double:
+				return float64(v), nil
+			default:
+				return v, nil
+			}
+		}, nil
+	case Float:
+		return func(r *iostream.Reader) (interface{}, error) {
+			v, err := r.ReadFloat32()
+			if rd.Type == Double {
+				return float64(v), err
+			}
+			return v, err
+		}, nil
+	case Double:
+		return func(r *iostream.Reader) (interface{}, error) {
+			return r.ReadFloat64()
+		}, nil
+	case Bytes, String:
+		return func(r *iostream.Reader) (interface{}, error) {
+			b, err := readBytes(r)
+			switch {
+			case err != nil:
+				return nil, err
+			case rd.Type == String:
+				return string(b), nil
+			default:
+				return append([]byte{}, b...), nil
+			}
+		}, nil
+	case Fixed:
+		return func(r *iostream.Reader) (interface{}, error) {
+			b, err := r.Slice(w.Size)
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte{}, b...), nil
+		}, nil
+	case Enum:
+		return compileEnum(w, rd), nil
+	case Array:
+		return c.compileArray(w, rd)
+	case Map:
+		return c.compileMap(w, rd)
+	default:
+		return c.compileRecord(w, rd)
+	}
+}
+
+// compileUnion compiles a decoder for every branch of the writer union. Branches
+// which can not be resolved only fail if they are actually encountered.
+func (c *resolver) compileUnion(w, rd *Schema) decodeFn {
+	branches := make([]decodeFn, len(w.Branches))
+	for i, branch := range w.Branches {
+		fn, err := c.decoder(branch, rd)
+		if err != nil {
+			fn = fail(err)
+		}
+		branches[i] = fn
+	}
+
+	return func(r *iostream.Reader) (interface{}, error) {
+		i, err := r.ReadVarint()
+		switch {
+		case err != nil:
+			return nil, err
+		case i < 0 || i >= int64(len(branches)):
+			return nil, errUnion
+		default:
+			return branches[i](r)
+		}
+	}
+}
+
+// compileEnum compiles a decoder of enum symbols, which fails if the writer
+// symbol is not a symbol of the reader enum.
+func compileEnum(w, rd *Schema) decodeFn {
+	symbols := make([]interface{}, len(w.Symbols))
+	for i, symbol := range w.Symbols {
+		if indexOf(rd.Symbols, symbol) >= 0 {
+			symbols[i] = symbol
+		}
+	}
+
+	return func(r *iostream.Reader) (interface{}, error) {
+		i, err := r.ReadVarint()
+		switch {
+		case err != nil:
+			return nil, err
+		case i < 0 || i >= int64(len(symbols)) || symbols[i] == nil:
+			return nil, errEnum
+		default:
+			return symbols[i], nil
+		}
+	}
+}
+
+// compileArray compiles a decoder of arrays
+func (c *resolver) compileArray(w, rd *Schema) (decodeFn, error) {
+	items, err := c.decoder(w.Items, rd.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	empty := isEmpty(w.Items, make(map[*Schema]bool))
+
+	return func(r *iostream.Reader) (interface{}, error) {
+		out := make([]interface{}, 0)
+		if err := readBlocks(r, empty, func() error {
+			v, err := items(r)
+			out = append(out, v)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}, nil
+}
+
+// compileMap compiles a decoder of maps
+func (c *resolver) compileMap(w, rd *Schema) (decodeFn, error) {
+	values, err := c.decoder(w.Values, rd.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(r *iostream.Reader) (interface{}, error) {
+		out := make(map[string]interface{})
+		if err := readBlocks(r, false, func() error {
+			key, err := readBytes(r)
+			if err != nil {
+				return err
+			}
+
+			name := string(key)
+			out[name], err = values(r)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}, nil
+}
+
+// compileRecord compiles a decoder of records. Fields which are not in the reader
+// schema are skipped, and fields which are not in the writer schema are set to
+// their default value.
+func (c *resolver) compileRecord(w, rd *Schema) (decodeFn, error) {
+	type step struct {
+		name   string
+		decode decodeFn
+		skip   skipFn
+	}
+
+	steps := make([]step, 0, len(w.Fields))
+	found := make(map[*Field]bool, len(rd.Fields))
+	for _, wf := range w.Fields {
+		rf := rd.field(wf.Name)
+		if rf == nil {
+			steps = append(steps, step{skip: c.skipper(wf.Type)})
+			continue
+		}
+
+		decode, err := c.decoder(wf.Type, rf.Type)
+		if err != nil {
+			return nil, fmt.Errorf("avro: field %q of %s, %w", rf.Name, rd.Name, err)
+		}
+
+		found[rf] = true
+		steps = append(steps, step{name: rf.Name, decode: decode})
+	}
+
+	var defaults []*Field
+	for _, rf := range rd.Fields {
+		switch {
+		case found[rf]:
+		case rf.HasDefault:
+			defaults = append(defaults, rf)
+		default:
+			return nil, fmt.Errorf("avro: field %q of %s is missing and has no default", rf.Name, rd.Name)
+		}
+	}
+
+	return func(r *iostream.Reader) (interface{}, error) {
+		if err := c.enter(); err != nil {
+			return nil, err
+		}
+		defer c.leave()
+
+		out := make(map[string]interface{}, len(rd.Fields))
+		for _, step := range steps {
+			if step.skip != nil {
+				if err := step.skip(r); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			v, err := step.decode(r)
+			if err != nil {
+				return nil, err
+			}
+			out[step.name] = v
+		}
+
+		for _, field := range defaults {
+			out[field.Name] = field.Default
+		}
+		return out, nil
+	}, nil
+}
+
+// --------------------------- Skipping ---------------------------
+
+// skipper returns a function which skips values of the writer schema
+func (c *resolver) skipper(w *Schema) skipFn {
+	if fn, ok := c.skippers[w]; ok {
+		return func(r *iostream.Reader) error {
+			return (*fn)(r)
+		}
+	}
+
+	fn := new(skipFn)
+	c.skippers[w] = fn
+	*fn = c.compileSkip(w)
+	return *fn
+}
+
+// compileSkip compiles a function which skips values of the writer schema
+func (c *resolver) compileSkip(w *Schema) skipFn {
+	switch w.Type {
+	case Null:
+		return func(r *iostream.Reader) error {
+			return nil
+		}
+	case Boolean:
+		return skipN(1)
+	case Int, Long, Enum:
+		return func(r *iostream.Reader) error {
+			return r.SkipUvarint()
+		}
+	case Float:
+		return skipN(4)
+	case Double:
+		return skipN(8)
+	case Fixed:
+		return skipN(w.Size)
+	case Bytes, String:
+		return func(r *iostream.Reader) error {
+			_, err := readBytes(r)
+			return err
+		}
+	case Array, Map:
+		item := w.Items
+		if w.Type == Map {
+			item = w.Values
+		}
+
+		skipItem := c.skipper(item)
+		empty := w.Type == Array && isEmpty(item, make(map[*Schema]bool))
+		return func(r *iostream.Reader) error {
+			return skipBlocks(r, empty, func() error {
+				if w.Type == Map {
+					if _, err := readBytes(r); err != nil {
+						return err
+					}
+				}
+				return skipItem(r)
+			})
+		}
+	case Union:
+		branches := make([]skipFn, len(w.Branches))
+		for i, branch := range w.Branches {
+			branches[i] = c.skipper(branch)
+		}
+
+		return func(r *iostream.Reader) error {
+			i, err := r.ReadVarint()
+			switch {
+			case err != nil:
+				return err
+			case i < 0 || i >= int64(len(branches)):
+				return errUnion
+			default:
+				return branches[i](r)
+			}
+		}
+	default:
+		fields := make([]skipFn, len(w.Fields))
+		for i, field := range w.Fields {
+			fields[i] = c.skipper(field.Type)
+		}
+
+		return func(r *iostream.Reader) error {
+			if err := c.enter(); err != nil {
+				return err
+			}
+			defer c.leave()
+
+			for _, skip := range fields {
+				if err := skip(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// enter increments the nesting of records, up to the maximum depth
+func (c *resolver) enter() error {
+	if c.depth >= maxDepth {
+		return errDepth
+	}
+
+	c.depth++
+	return nil
+}
+
+// leave decrements the nesting of records
+func (c *resolver) leave() {
+	c.depth--
+}
+
+// skipN returns a function which skips a fixed number of bytes
+func skipN(n int) skipFn {
+	return func(r *iostream.Reader) error {
+		return r.SkipN(n)
+	}
+}
+
+// --------------------------- Helpers ---------------------------
+
+// compatible returns whether values of the writer schema can be read with the
+// reader schema, without checking the nested schemas.
+func compatible(w, rd *Schema) bool {
+	switch {
+	case w.Type == rd.Type && w.Type == Fixed:
+		return rd.hasName(w.Name) && w.Size == rd.Size
+	case w.Type == rd.Type && w.isNamed():
+		return rd.hasName(w.Name)
+	case w.Type == rd.Type:
+		return true
+	}
+
+	switch w.Type {
+	case Int:
+		return rd.Type == Long || rd.Type == Float || rd.Type == Double
+	case Long:
+		return rd.Type == Float || rd.Type == Double
+	case Float:
+		return rd.Type == Double
+	case String:
+		return rd.Type == Bytes
+	case Bytes:
+		return rd.Type == String
+	default:
+		return false
+	}
+}
+
+// branchOf returns the first branch of the reader union which matches the writer
+// schema, preferring branches of the same type over promotions.
+func branchOf(w, rd *Schema) *Schema {
+	for _, branch := range rd.Branches {
+		if branch.Type == w.Type && compatible(w, branch) {
+			return branch
+		}
+	}
+
+	for _, branch := range rd.Branches {
+		if compatible(w, branch) {
+			return branch
+		}
+	}
+	return nil
+}
+
+// fail returns a decoder which always fails with the error
+func fail(err error) decodeFn {
+	return func(r *iostream.Reader) (interface{}, error) {
+		return nil, err
+	}
+}
+
+// readBytes reads a byte string prefixed with its length. The returned slice is
+// only valid until the next read.
+func readBytes(r *iostream.Reader) ([]byte, error) {
+	size, err := r.ReadVarint()
+	switch {
+	case err != nil:
+		return nil, err
+	case size < 0 || size > maxLength:
+		return nil, errLength
+	default:
+		return r.Slice(int(size))
+	}
+}
+
+// readBlocks calls the function for every item of an array or a map, which are
+// encoded as a series of blocks terminated by an empty block. If the items are
+// empty, their total count is limited to maxEmpty.
+func readBlocks(r *iostream.Reader, empty bool, fn func() error) error {
+	for total := int64(0); ; {
+		count, err := r.ReadVarint()
+		switch {
+		case err != nil:
+			return err
+		case count == 0:
+			return nil
+		case count < 0:
+			count = -count
+			if _, err := r.ReadVarint(); err != nil {
+				return err
+			}
+		}
+
+		if empty && (count < 0 || count > maxEmpty-total) {
+			return errLength
+		}
+
+		total += count
+
+		for i := int64(0); i < count; i++ {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// skipBlocks skips the items of an array or a map. When the size of a block
+// is known, the whole block is skipped at once. If the items are empty, their
+// total count is limited to maxEmpty.
+func skipBlocks(r *iostream.Reader, empty bool, fn func() error) error {
+	for total := int64(0); ; {
+		count, err := r.ReadVarint()
+		switch {
+		case err != nil:
+			return err
+		case count == 0:
+			return nil
+		case count < 0:
+			size, err := r.ReadVarint()
+			switch {
+			case err != nil:
+				return err
+			case size < 0 || size > maxLength:
+				return errLength
+			}
+
+			if err := r.SkipN(int(size)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if empty && count > maxEmpty-total {
+			return errLength
+		}
+
+		total += count
+		for i := int64(0); i < count; i++ {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isEmpty returns whether the values of the schema are encoded with zero bytes,
+// such as nulls or records whose fields are all empty.
+func isEmpty(s *Schema, seen map[*Schema]bool) bool {
+	switch s.Type {
+	case Null:
+		return true
+	case Fixed:
+		return s.Size == 0
+	case Record:
+		if seen[s] {
+			return false
+		}
+
+		seen[s] = true
+		for _, f := range s.Fields {
+			if !isEmpty(f.Type, seen) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
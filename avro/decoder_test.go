@@ -0,0 +1,302 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package avro
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	for _, tc := range fixtures {
+		dec := NewDecoder(mustParse(tc.Schema), newReader(tc.Buffer))
+		v, err := dec.Decode()
+		assert.NoError(t, err, tc.Schema)
+		assert.Equal(t, tc.Value, v, tc.Schema)
+		assert.Equal(t, int64(len(tc.Buffer)), dec.Offset(), tc.Schema)
+	}
+}
+
+func TestDecodeRecursive(t *testing.T) {
+	schema := mustParse(testSchema)
+	value := map[string]interface{}{
+		"id":    int64(1),
+		"name":  "child",
+		"kind":  "B",
+		"hash":  []byte{1, 2, 3, 4},
+		"tags":  []interface{}{"a", "b"},
+		"attrs": map[string]interface{}{"y": 2.5},
+		"parent": map[string]interface{}{
+			"id":     int64(0),
+			"name":   "root",
+			"hash":   []byte{0, 0, 0, 0},
+			"parent": nil,
+		},
+		"data": []byte{},
+		"time": int64(1234),
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewEncoder(schema, iostream.NewWriter(buffer)).Encode(value))
+
+	out, err := NewDecoder(schema, newReader(buffer.Bytes())).Decode()
+	assert.NoError(t, err)
+
+	// Missing fields of the parent are encoded with their default values
+	parent := value["parent"].(map[string]interface{})
+	parent["kind"] = "A"
+	parent["tags"] = []interface{}{}
+	parent["attrs"] = map[string]interface{}{"x": 1.5}
+	parent["data"] = []byte{0xff}
+	parent["time"] = int64(0)
+	assert.Equal(t, value, out)
+}
+
+func TestDecodeBlocks(t *testing.T) {
+	schema := mustParse(`{"type": "array", "items": "int"}`)
+
+	// Two blocks, the second one with a negative count and its size in bytes
+	input := []byte{0x02, 0x02, 0x03, 0x04, 0x04, 0x06, 0x00}
+	v, err := NewDecoder(schema, newReader(input)).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int32(1), int32(2), int32(3)}, v)
+}
+
+func TestResolve(t *testing.T) {
+	for _, tc := range []struct {
+		Writer string
+		Reader string
+		Value  interface{}
+		Expect interface{}
+	}{
+		{`"int"`, `"long"`, 1, int64(1)},
+		{`"int"`, `"float"`, 1, float32(1)},
+		{`"int"`, `"double"`, 1, float64(1)},
+		{`"long"`, `"double"`, 1, float64(1)},
+		{`"float"`, `"double"`, float32(1.5), float64(1.5)},
+		{`"string"`, `"bytes"`, "a", []byte("a")},
+		{`"bytes"`, `"string"`, []byte("a"), "a"},
+		{`"int"`, `["null", "long", "int"]`, 1, int32(1)},
+		{`"int"`, `["null", "long"]`, 1, int64(1)},
+		{`["null", "int"]`, `"long"`, 1, int64(1)},
+		{`["null", "int"]`, `["string", "int", "null"]`, nil, nil},
+		{`{"type": "array", "items": "int"}`, `{"type": "array", "items": "long"}`, []int{1}, []interface{}{int64(1)}},
+		{`{"type": "map", "values": "int"}`, `{"type": "map", "values": "long"}`, map[string]int{"a": 1}, map[string]interface{}{"a": int64(1)}},
+		{`{"type": "enum", "name": "E", "symbols": ["A", "B"]}`, `{"type": "enum", "name": "X", "aliases": ["E"], "symbols": ["B"]}`, "B", "B"},
+		{`{"type": "fixed", "name": "F", "size": 1}`, `{"type": "fixed", "name": "F", "size": 1}`, []byte{1}, []byte{1}},
+		{
+			`{"type": "record", "name": "R", "fields": [
+				{"name": "a", "type": "int"},
+				{"name": "b", "type": {"type": "array", "items": {"type": "map", "values": ["null", "string"]}}},
+				{"name": "c", "type": "string"},
+				{"name": "d", "type": {"type": "record", "name": "S", "fields": [{"name": "x", "type": "double"}]}},
+				{"name": "e", "type": "boolean"},
+				{"name": "f", "type": {"type": "fixed", "name": "F", "size": 2}},
+				{"name": "g", "type": {"type": "enum", "name": "E", "symbols": ["A"]}},
+				{"name": "h", "type": "float"},
+				{"name": "i", "type": "null"}
+			]}`,
+			`{"type": "record", "name": "New", "aliases": ["R"], "fields": [
+				{"name": "z", "type": "string", "default": "zz"},
+				{"name": "title", "aliases": ["c"], "type": "string"},
+				{"name": "a", "type": "long"}
+			]}`,
+			map[string]interface{}{
+				"a": 1,
+				"b": []interface{}{map[string]interface{}{"k": "v", "n": nil}},
+				"c": "hello",
+				"d": map[string]interface{}{"x": 1.5},
+				"e": true,
+				"f": []byte{1, 2},
+				"g": "A",
+				"h": float32(1),
+				"i": nil,
+			},
+			map[string]interface{}{"a": int64(1), "title": "hello", "z": "zz"},
+		},
+	} {
+		writer, reader := mustParse(tc.Writer), mustParse(tc.Reader)
+		buffer := bytes.NewBuffer(nil)
+		assert.NoError(t, NewEncoder(writer, iostream.NewWriter(buffer)).Encode(tc.Value), tc.Writer)
+
+		dec, err := NewResolvingDecoder(writer, reader, newReader(buffer.Bytes()))
+		assert.NoError(t, err, tc.Reader)
+
+		v, err := dec.Decode()
+		assert.NoError(t, err, tc.Reader)
+		assert.Equal(t, tc.Expect, v, tc.Reader)
+		assert.Equal(t, int64(buffer.Len()), dec.Offset(), tc.Reader)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Writer string
+		Reader string
+	}{
+		{`"long"`, `"int"`},
+		{`"double"`, `"float"`},
+		{`"string"`, `"int"`},
+		{`"string"`, `["null", "int"]`},
+		{`{"type": "array", "items": "string"}`, `{"type": "array", "items": "int"}`},
+		{`{"type": "map", "values": "string"}`, `{"type": "map", "values": "int"}`},
+		{`{"type": "fixed", "name": "F", "size": 1}`, `{"type": "fixed", "name": "F", "size": 2}`},
+		{`{"type": "fixed", "name": "F", "size": 1}`, `{"type": "fixed", "name": "G", "size": 1}`},
+		{`{"type": "record", "name": "R", "fields": []}`, `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "string"}]}`, `{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`},
+	} {
+		_, err := NewResolvingDecoder(mustParse(tc.Writer), mustParse(tc.Reader), newReader(nil))
+		assert.Error(t, err, "%s %s", tc.Writer, tc.Reader)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Schema string
+		Buffer []byte
+	}{
+		{`"boolean"`, nil},
+		{`"long"`, nil},
+		{`"float"`, []byte{0}},
+		{`"double"`, []byte{0}},
+		{`"string"`, []byte{0x01}},
+		{`"string"`, []byte{0x04, 0x61}},
+		{`{"type": "fixed", "name": "F", "size": 2}`, []byte{0x01}},
+		{`{"type": "enum", "name": "E", "symbols": ["A"]}`, []byte{0x02}},
+		{`{"type": "enum", "name": "E", "symbols": ["A"]}`, nil},
+		{`{"type": "array", "items": "long"}`, []byte{0x02}},
+		{`{"type": "array", "items": "long"}`, []byte{0x01}},
+		{`{"type": "array", "items": "long"}`, nil},
+		{`{"type": "map", "values": "long"}`, []byte{0x02, 0x02}},
+		{`{"type": "map", "values": "long"}`, []byte{0x02, 0x02, 0x61}},
+		{`["null", "string"]`, []byte{0x04}},
+		{`["null", "string"]`, nil},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`, nil},
+		{`{"type": "array", "items": "null"}`, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{`{"type": "array", "items": {"type": "record", "name": "R", "fields": []}}`, []byte{0x80, 0x80, 0x10, 0x80, 0x80, 0x10}},
+		{`"int"`, []byte{0x80, 0x80, 0x80, 0x80, 0x10}},
+		{`"int"`, []byte{0x81, 0x80, 0x80, 0x80, 0x10}},
+	} {
+		_, err := NewDecoder(mustParse(tc.Schema), newReader(tc.Buffer)).Decode()
+		assert.Error(t, err, "%s %v", tc.Schema, tc.Buffer)
+	}
+
+	// Enum symbols and union branches which can not be resolved fail when decoded
+	for _, tc := range []struct {
+		Writer string
+		Reader string
+		Buffer []byte
+	}{
+		{`{"type": "enum", "name": "E", "symbols": ["A", "B"]}`, `{"type": "enum", "name": "E", "symbols": ["B"]}`, []byte{0x00}},
+		{`["string", "int"]`, `"int"`, []byte{0x00, 0x02, 0x61}},
+	} {
+		dec, err := NewResolvingDecoder(mustParse(tc.Writer), mustParse(tc.Reader), newReader(tc.Buffer))
+		assert.NoError(t, err)
+		_, err = dec.Decode()
+		assert.Error(t, err)
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	schema := mustParse(`{"type": "record", "name": "N", "fields": [{"name": "next", "type": ["null", "N"]}]}`)
+	input := bytes.Repeat([]byte{0x02}, 2*maxDepth)
+
+	_, err := NewDecoder(schema, newReader(input)).Decode()
+	assert.Equal(t, errDepth, err)
+
+	// Records nested up to the limit are decoded
+	dec := NewDecoder(schema, newReader(append(bytes.Repeat([]byte{0x02}, maxDepth-1), 0x00)))
+	_, err = dec.Decode()
+	assert.NoError(t, err)
+
+	// Records which are skipped are limited as well
+	reader := mustParse(`{"type": "record", "name": "N", "fields": []}`)
+	dec, err = NewResolvingDecoder(schema, reader, newReader(input))
+	assert.NoError(t, err)
+	_, err = dec.Decode()
+	assert.Equal(t, errDepth, err)
+
+	// The depth is restored after a failure
+	dec = NewDecoder(schema, newReader(append(input, 0x00)))
+	_, err = dec.Decode()
+	assert.Equal(t, errDepth, err)
+	_, err = dec.Decode()
+	assert.Equal(t, errDepth, err)
+}
+
+func TestSkip(t *testing.T) {
+	writer := mustParse(`{"type": "record", "name": "R", "fields": [
+		{"name": "a", "type": {"type": "array", "items": {"type": "map", "values": "string"}}},
+		{"name": "b", "type": ["null", "R"]},
+		{"name": "c", "type": {"type": "array", "items": "int"}},
+		{"name": "d", "type": "int"}
+	]}`)
+	reader := mustParse(`{"type": "record", "name": "R", "fields": [{"name": "d", "type": "int"}]}`)
+
+	// The array of the field c is written as a block with its size in bytes
+	input := []byte{
+		0x02, 0x02, 0x02, 0x61, 0x02, 0x62, 0x00, 0x00, // a: [{"a": "b"}]
+		0x02, 0x00, 0x00, 0x00, 0x02, // b: {a: [], b: null, c: [], d: 1}
+		0x01, 0x04, 0x02, 0x04, 0x00, // c: [1, 2]
+		0x54, // d: 42
+	}
+
+	dec, err := NewResolvingDecoder(writer, reader, newReader(input))
+	assert.NoError(t, err)
+
+	v, err := dec.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"d": int32(42)}, v)
+	assert.Equal(t, int64(len(input)), dec.Offset())
+
+	// Truncated values must fail while skipping
+	for i := 0; i < len(input); i++ {
+		dec, _ := NewResolvingDecoder(writer, reader, newReader(input[:i]))
+		_, err := dec.Decode()
+		assert.Error(t, err, i)
+	}
+
+	// Arrays of too many empty items must fail while skipping
+	writer = mustParse(`{"type": "record", "name": "R", "fields": [{"name": "a", "type": {"type": "array", "items": "null"}}]}`)
+	reader = mustParse(`{"type": "record", "name": "R", "fields": []}`)
+	dec, err = NewResolvingDecoder(writer, reader, newReader([]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}))
+	assert.NoError(t, err)
+	_, err = dec.Decode()
+	assert.Error(t, err)
+}
+
+func TestSkipTypes(t *testing.T) {
+	writer := mustParse(`{"type": "record", "name": "R", "fields": [
+		{"name": "a", "type": "boolean"},
+		{"name": "b", "type": "long"},
+		{"name": "c", "type": "float"},
+		{"name": "d", "type": "double"},
+		{"name": "e", "type": "bytes"},
+		{"name": "f", "type": {"type": "fixed", "name": "F", "size": 2}},
+		{"name": "g", "type": {"type": "enum", "name": "E", "symbols": ["A"]}},
+		{"name": "h", "type": "null"},
+		{"name": "i", "type": {"type": "map", "values": "int"}}
+	]}`)
+	reader := mustParse(`{"type": "record", "name": "R", "fields": []}`)
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewEncoder(writer, iostream.NewWriter(buffer)).Encode(map[string]interface{}{
+		"a": true, "b": 1, "c": float32(1), "d": 1.0, "e": []byte{1},
+		"f": []byte{1, 2}, "g": "A", "h": nil, "i": map[string]int{"x": 1},
+	}))
+
+	dec, err := NewResolvingDecoder(writer, reader, newReader(buffer.Bytes()))
+	assert.NoError(t, err)
+	v, err := dec.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{}, v)
+	assert.Equal(t, int64(buffer.Len()), dec.Offset())
+}
+
+func newReader(b []byte) *iostream.Reader {
+	return iostream.NewReader(bytes.NewBuffer(b))
+}
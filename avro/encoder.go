@@ -0,0 +1,269 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package avro
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/kelindar/iostream"
+	"github.com/kelindar/iostream/internal/convert"
+)
+
+// Encoder represents an encoder of values described by a schema.
+type Encoder struct {
+	out    *iostream.Writer
+	schema *Schema
+}
+
+// NewEncoder creates a new encoder of values described by the schema.
+func NewEncoder(schema *Schema, out *iostream.Writer) *Encoder {
+	return &Encoder{
+		out:    out,
+		schema: schema,
+	}
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (e *Encoder) Offset() int64 {
+	return e.out.Offset()
+}
+
+// Encode validates and encodes a value. Records are represented as a map of
+// their fields, arrays as slices, maps as maps with string keys, enums as the
+// string of their symbol, while bytes and fixed are represented as byte slices.
+// Values of a union are encoded with the first branch they match.
+func (e *Encoder) Encode(v interface{}) error {
+	return e.encode(e.schema, v)
+}
+
+// encode encodes a value of the schema
+func (e *Encoder) encode(s *Schema, v interface{}) error {
+	switch s.Type {
+	case Null:
+		if v == nil {
+			return nil
+		}
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return e.out.WriteBool(b)
+		}
+	case Int:
+		if i, ok := toLong(v); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return e.out.WriteVarint(i)
+		}
+	case Long:
+		if i, ok := toLong(v); ok {
+			return e.out.WriteVarint(i)
+		}
+	case Float:
+		if f, ok := toDouble(v); ok {
+			return e.out.WriteFloat32(float32(f))
+		}
+	case Double:
+		if f, ok := toDouble(v); ok {
+			return e.out.WriteFloat64(f)
+		}
+	case Bytes, String:
+		switch b := v.(type) {
+		case []byte:
+			return e.writeBytes(b)
+		case string:
+			return e.writeBytes(convert.ToBytes(b))
+		}
+	case Fixed:
+		if b, ok := v.([]byte); ok && len(b) == s.Size {
+			_, err := e.out.Write(b)
+			return err
+		}
+	case Enum:
+		if symbol, ok := v.(string); ok {
+			if i := indexOf(s.Symbols, symbol); i >= 0 {
+				return e.out.WriteVarint(int64(i))
+			}
+		}
+	case Array:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+			return e.writeBlock(rv.Len(), func(i int) error {
+				return e.encode(s.Items, rv.Index(i).Interface())
+			})
+		}
+	case Map:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+			iter := rv.MapRange()
+			return e.writeBlock(rv.Len(), func(int) error {
+				iter.Next()
+				if err := e.writeBytes(convert.ToBytes(iter.Key().String())); err != nil {
+					return err
+				}
+				return e.encode(s.Values, iter.Value().Interface())
+			})
+		}
+	case Record:
+		if record, ok := v.(map[string]interface{}); ok {
+			return e.writeRecord(s, record)
+		}
+	case Union:
+		for i, branch := range s.Branches {
+			if matches(branch, v) {
+				if err := e.out.WriteVarint(int64(i)); err != nil {
+					return err
+				}
+				return e.encode(branch, v)
+			}
+		}
+	}
+
+	return fmt.Errorf("avro: %T is not a valid %s", v, typeName(s))
+}
+
+// writeBytes writes a byte slice prefixed with its length
+func (e *Encoder) writeBytes(v []byte) error {
+	if err := e.out.WriteVarint(int64(len(v))); err != nil {
+		return err
+	}
+
+	_, err := e.out.Write(v)
+	return err
+}
+
+// writeBlock writes the elements of an array or a map as a single block,
+// followed by an empty block which terminates it.
+func (e *Encoder) writeBlock(count int, fn func(i int) error) error {
+	if count > 0 {
+		if err := e.out.WriteVarint(int64(count)); err != nil {
+			return err
+		}
+
+		for i := 0; i < count; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.out.WriteVarint(0)
+}
+
+// writeRecord writes the fields of a record in the order of the schema
+func (e *Encoder) writeRecord(s *Schema, record map[string]interface{}) error {
+	for _, field := range s.Fields {
+		v, ok := record[field.Name]
+		switch {
+		case !ok && field.HasDefault:
+			v = field.Default
+		case !ok:
+			return fmt.Errorf("avro: missing field %q of %s", field.Name, s.Name)
+		}
+
+		if err := e.encode(field.Type, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --------------------------- Validation ---------------------------
+
+// matches returns whether a value can be encoded with the schema, without
+// checking the nested values of arrays, maps and records.
+func matches(s *Schema, v interface{}) bool {
+	switch s.Type {
+	case Null:
+		return v == nil
+	case Boolean:
+		_, ok := v.(bool)
+		return ok
+	case Int:
+		i, ok := toLong(v)
+		return ok && i >= math.MinInt32 && i <= math.MaxInt32
+	case Long:
+		_, ok := toLong(v)
+		return ok
+	case Float, Double:
+		_, ok := toDouble(v)
+		return ok
+	case Bytes:
+		_, ok := v.([]byte)
+		return ok
+	case String:
+		_, ok := v.(string)
+		return ok
+	case Fixed:
+		b, ok := v.([]byte)
+		return ok && len(b) == s.Size
+	case Enum:
+		symbol, ok := v.(string)
+		return ok && indexOf(s.Symbols, symbol) >= 0
+	case Array:
+		rv := reflect.ValueOf(v)
+		return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8
+	case Map:
+		rv := reflect.ValueOf(v)
+		return rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String
+	case Record:
+		record, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, field := range s.Fields {
+			if _, ok := record[field.Name]; !ok && !field.HasDefault {
+				return false
+			}
+		}
+		return len(record) <= len(s.Fields)
+	default:
+		return false
+	}
+}
+
+// typeName returns the name of a schema for error messages
+func typeName(s *Schema) string {
+	if s.isNamed() {
+		return fmt.Sprintf("%s %s", s.Type, s.Name)
+	}
+	return string(s.Type)
+}
+
+// toLong converts an integer of any type to an int64
+func toLong(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		return int64(v), v <= math.MaxInt64
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	default:
+		return 0, false
+	}
+}
+
+// toDouble converts a floating point number of any type to a float64
+func toDouble(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
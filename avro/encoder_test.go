@@ -0,0 +1,115 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package avro
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+// Golden values, from the examples of the Avro specification
+var fixtures = []struct {
+	Schema string
+	Value  interface{}
+	Buffer []byte
+}{
+	{`"null"`, nil, nil},
+	{`"boolean"`, true, []byte{0x01}},
+	{`"int"`, int32(-64), []byte{0x7f}},
+	{`"long"`, int64(64), []byte{0x80, 0x01}},
+	{`"long"`, int64(-2), []byte{0x03}},
+	{`"float"`, float32(1), []byte{0x00, 0x00, 0x80, 0x3f}},
+	{`"double"`, float64(1), []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f}},
+	{`"string"`, "foo", []byte{0x06, 0x66, 0x6f, 0x6f}},
+	{`"bytes"`, []byte{1, 2}, []byte{0x04, 0x01, 0x02}},
+	{`{"type": "fixed", "name": "F", "size": 2}`, []byte{1, 2}, []byte{0x01, 0x02}},
+	{`{"type": "enum", "name": "E", "symbols": ["A", "B"]}`, "B", []byte{0x02}},
+	{`{"type": "array", "items": "long"}`, []interface{}{int64(3), int64(27)}, []byte{0x04, 0x06, 0x36, 0x00}},
+	{`{"type": "array", "items": "long"}`, []interface{}{}, []byte{0x00}},
+	{`{"type": "map", "values": "long"}`, map[string]interface{}{"a": int64(1)}, []byte{0x02, 0x02, 0x61, 0x02, 0x00}},
+	{`["null", "string"]`, "a", []byte{0x02, 0x02, 0x61}},
+	{`["null", "string"]`, nil, []byte{0x00}},
+	{`{"type": "record", "name": "test", "fields": [{"name": "a", "type": "long"}, {"name": "b", "type": "string"}]}`,
+		map[string]interface{}{"a": int64(27), "b": "foo"}, []byte{0x36, 0x06, 0x66, 0x6f, 0x6f}},
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range fixtures {
+		buffer := bytes.NewBuffer(nil)
+		enc := NewEncoder(mustParse(tc.Schema), iostream.NewWriter(buffer))
+		assert.NoError(t, enc.Encode(tc.Value), tc.Schema)
+		assert.Equal(t, tc.Buffer, buffer.Bytes(), tc.Schema)
+		assert.Equal(t, int64(len(tc.Buffer)), enc.Offset())
+	}
+}
+
+func TestEncodeConversions(t *testing.T) {
+	for _, tc := range []struct {
+		Schema string
+		Value  interface{}
+		Buffer []byte
+	}{
+		{`"int"`, 1, []byte{0x02}},
+		{`"int"`, uint8(1), []byte{0x02}},
+		{`"long"`, uint64(1), []byte{0x02}},
+		{`"double"`, float32(1), []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f}},
+		{`"string"`, []byte("a"), []byte{0x02, 0x61}},
+		{`"bytes"`, "a", []byte{0x02, 0x61}},
+		{`{"type": "array", "items": "string"}`, []string{"a"}, []byte{0x02, 0x02, 0x61, 0x00}},
+		{`{"type": "map", "values": "int"}`, map[string]int{"a": 1}, []byte{0x02, 0x02, 0x61, 0x02, 0x00}},
+		{`["null", "long", "double"]`, 1.5, []byte{0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f}},
+		{`["int", "long"]`, int64(math.MaxInt32 + 1), []byte{0x02, 0x80, 0x80, 0x80, 0x80, 0x10}},
+		{`["int", {"type": "array", "items": "int"}]`, []int{1}, []byte{0x02, 0x02, 0x02, 0x00}},
+		{`["int", {"type": "map", "values": "int"}]`, map[string]int{}, []byte{0x02, 0x00}},
+		{`["bytes", {"type": "fixed", "name": "F", "size": 1}]`, []byte{1}, []byte{0x00, 0x02, 0x01}},
+		{`[{"type": "enum", "name": "E", "symbols": ["A"]}, "string"]`, "B", []byte{0x02, 0x02, 0x42}},
+		{`["boolean", "float"]`, float32(0), []byte{0x02, 0x00, 0x00, 0x00, 0x00}},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int", "default": 5}]}`,
+			map[string]interface{}{}, []byte{0x0a}},
+		{`[{"type": "record", "name": "A", "fields": [{"name": "a", "type": "int"}]},
+		   {"type": "record", "name": "B", "fields": [{"name": "b", "type": "int"}]}]`,
+			map[string]interface{}{"b": 1}, []byte{0x02, 0x02}},
+	} {
+		buffer := bytes.NewBuffer(nil)
+		assert.NoError(t, NewEncoder(mustParse(tc.Schema), iostream.NewWriter(buffer)).Encode(tc.Value), tc.Schema)
+		assert.Equal(t, tc.Buffer, buffer.Bytes(), tc.Schema)
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		Schema string
+		Value  interface{}
+	}{
+		{`"null"`, 1},
+		{`"boolean"`, 1},
+		{`"int"`, int64(math.MaxInt32 + 1)},
+		{`"int"`, "1"},
+		{`"long"`, uint64(math.MaxUint64)},
+		{`"float"`, 1},
+		{`"double"`, "1"},
+		{`"string"`, 1},
+		{`{"type": "fixed", "name": "F", "size": 2}`, []byte{1}},
+		{`{"type": "enum", "name": "E", "symbols": ["A"]}`, "B"},
+		{`{"type": "enum", "name": "E", "symbols": ["A"]}`, 0},
+		{`{"type": "array", "items": "long"}`, 1},
+		{`{"type": "array", "items": "long"}`, []string{"a"}},
+		{`{"type": "map", "values": "long"}`, map[int]int{}},
+		{`{"type": "map", "values": "long"}`, map[string]string{"a": "b"}},
+		{`["null", "string"]`, 1},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`, 1},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`, map[string]interface{}{}},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`, map[string]interface{}{"a": "x"}},
+		{`[{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}]`, map[string]interface{}{}},
+		{`[{"type": "record", "name": "R", "fields": []}]`, map[string]interface{}{"a": 1}},
+		{`[{"type": "record", "name": "R", "fields": []}]`, 1},
+	} {
+		err := NewEncoder(mustParse(tc.Schema), iostream.NewWriter(bytes.NewBuffer(nil))).Encode(tc.Value)
+		assert.Error(t, err, "%s %v", tc.Schema, tc.Value)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package avro
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/kelindar/iostream"
+)
+
+var (
+	errMagic = errors.New("avro: not an object container file")
+	errSync  = errors.New("avro: invalid sync marker")
+	errCodec = errors.New("avro: unsupported codec")
+)
+
+// Codecs supported by the object container files
+const (
+	CodecNull    = "null"
+	CodecDeflate = "deflate"
+)
+
+const (
+	syncSize  = 16        // The size of a sync marker
+	blockSize = 64 * 1024 // The size after which a block is written
+)
+
+var (
+	magic      = []byte{'O', 'b', 'j', 1}
+	metaSchema = &Schema{Type: Map, Values: &Schema{Type: Bytes}}
+)
+
+// --------------------------- Writer ---------------------------
+
+// FileWriter represents a writer of object container files, which contain a
+// header with the schema followed by blocks of encoded values.
+type FileWriter struct {
+	out      *iostream.Writer
+	enc      *Encoder
+	block    bytes.Buffer
+	count    int64
+	sync     [syncSize]byte
+	deflate  *flate.Writer
+	deflated bytes.Buffer
+}
+
+// NewFileWriter creates a new writer of an object container file with values of
+// the schema, and writes the header of the file. The codec is either "null" or
+// "deflate", or empty for no compression.
+func NewFileWriter(out io.Writer, schema *Schema, codec string) (*FileWriter, error) {
+	w := &FileWriter{
+		out: iostream.NewWriter(out),
+	}
+
+	switch codec {
+	case "":
+		codec = CodecNull
+	case CodecNull:
+	case CodecDeflate:
+		w.deflate, _ = flate.NewWriter(&w.deflated, flate.DefaultCompression)
+	default:
+		return nil, errCodec
+	}
+
+	if _, err := rand.Read(w.sync[:]); err != nil {
+		return nil, err
+	}
+
+	// Write the header, with the schema and the codec in the metadata
+	w.enc = NewEncoder(schema, iostream.NewWriter(&w.block))
+	if _, err := w.out.Write(magic); err != nil {
+		return nil, err
+	}
+
+	if err := NewEncoder(metaSchema, w.out).Encode(map[string]interface{}{
+		"avro.schema": []byte(schema.String()),
+		"avro.codec":  []byte(codec),
+	}); err != nil {
+		return nil, err
+	}
+
+	if _, err := w.out.Write(w.sync[:]); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Append encodes a value into the current block, and writes the block once it
+// is large enough. If the value is not valid, the block is left unchanged.
+func (w *FileWriter) Append(v interface{}) error {
+	size := w.block.Len()
+	if err := w.enc.Encode(v); err != nil {
+		w.block.Truncate(size)
+		return err
+	}
+
+	if w.count++; w.block.Len() >= blockSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the current block, if there is one, and flushes the underlying writer.
+func (w *FileWriter) Flush() error {
+	if w.count == 0 {
+		return w.out.Flush()
+	}
+
+	data := w.block.Bytes()
+	if w.deflate != nil {
+		w.deflated.Reset()
+		w.deflate.Reset(&w.deflated)
+		if _, err := w.deflate.Write(data); err != nil {
+			return err
+		}
+		if err := w.deflate.Close(); err != nil {
+			return err
+		}
+		data = w.deflated.Bytes()
+	}
+
+	if err := w.out.WriteVarint(w.count); err != nil {
+		return err
+	}
+	if err := w.out.WriteVarint(int64(len(data))); err != nil {
+		return err
+	}
+	if _, err := w.out.Write(data); err != nil {
+		return err
+	}
+	if _, err := w.out.Write(w.sync[:]); err != nil {
+		return err
+	}
+
+	w.block.Reset()
+	w.count = 0
+	return w.out.Flush()
+}
+
+// Close writes the current block and flushes the underlying writer. It does not
+// close the underlying writer.
+func (w *FileWriter) Close() error {
+	return w.Flush()
+}
+
+// --------------------------- Reader ---------------------------
+
+// FileReader represents a reader of object container files.
+type FileReader struct {
+	src       *iostream.Reader
+	schema    *Schema
+	meta      map[string]interface{}
+	sync      []byte
+	decode    decodeFn
+	block     *iostream.Reader
+	remaining int64
+	value     interface{}
+	err       error
+}
+
+// NewFileReader creates a new reader of an object container file and reads its
+// header. The values are read with the reader schema, which is resolved against
+// the schema of the file. If the reader schema is nil, the schema of the file is
+// used instead.
+func NewFileReader(src io.Reader, reader *Schema) (*FileReader, error) {
+	r := &FileReader{
+		src: iostream.NewReader(src),
+	}
+
+	if b, err := r.src.Slice(len(magic)); err != nil || !bytes.Equal(b, magic) {
+		return nil, errMagic
+	}
+
+	meta, err := NewDecoder(metaSchema, r.src).Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	r.meta = meta.(map[string]interface{})
+	switch codec := string(r.Metadata("avro.codec")); codec {
+	case "", CodecNull, CodecDeflate:
+	default:
+		return nil, errCodec
+	}
+
+	if r.schema, err = Parse(string(r.Metadata("avro.schema"))); err != nil {
+		return nil, err
+	}
+
+	if reader == nil {
+		reader = r.schema
+	}
+
+	if r.decode, err = resolve(r.schema, reader); err != nil {
+		return nil, err
+	}
+
+	sync, err := r.src.Slice(syncSize)
+	if err != nil {
+		return nil, err
+	}
+
+	r.sync = append([]byte{}, sync...)
+	return r, nil
+}
+
+// Schema returns the schema with which the file was written
+func (r *FileReader) Schema() *Schema {
+	return r.schema
+}
+
+// Metadata returns the value of a metadata key from the header of the file
+func (r *FileReader) Metadata(key string) []byte {
+	b, _ := r.meta[key].([]byte)
+	return b
+}
+
+// Next reads the next value, which is then available through Value. It returns
+// false when there are no more values or if an error occurred.
+func (r *FileReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	for r.remaining == 0 {
+		// The file ends cleanly only at the boundary between two blocks
+		switch b, err := r.src.Peek(1); {
+		case len(b) == 0 && err == io.EOF:
+			return false
+		case len(b) == 0:
+			r.err = err
+			return false
+		}
+
+		if r.err = r.readBlock(); r.err != nil {
+			return false
+		}
+	}
+
+	r.remaining--
+	r.value, r.err = r.decode(r.block)
+	return r.err == nil
+}
+
+// Value returns the value read by the last call to Next
+func (r *FileReader) Value() interface{} {
+	return r.value
+}
+
+// Err returns the first error encountered while reading the file
+func (r *FileReader) Err() error {
+	return r.err
+}
+
+// readBlock reads the next block of values and decompresses it if necessary
+func (r *FileReader) readBlock() error {
+	count, err := r.src.ReadVarint()
+	if err != nil {
+		return err
+	}
+
+	size, err := r.src.ReadVarint()
+	switch {
+	case err != nil:
+		return err
+	case count < 0 || size < 0 || size > maxLength:
+		return errLength
+	}
+
+	data, err := r.src.Slice(int(size))
+	if err != nil {
+		return err
+	}
+
+	if string(r.Metadata("avro.codec")) == CodecDeflate {
+		data, err = io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), maxLength+1))
+	} else {
+		data = append([]byte{}, data...)
+	}
+
+	switch {
+	case err != nil:
+		return err
+	case len(data) > maxLength:
+		return errLength
+	}
+
+	if sync, err := r.src.Slice(syncSize); err != nil || !bytes.Equal(sync, r.sync) {
+		return errSync
+	}
+
+	r.remaining = count
+	r.block = iostream.NewReader(bytes.NewBuffer(data))
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package avro
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile(t *testing.T) {
+	schema := mustParse(`{"type": "record", "name": "R", "fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": "string"}
+	]}`)
+
+	for _, codec := range []string{"", CodecNull, CodecDeflate} {
+		buffer := bytes.NewBuffer(nil)
+		w, err := NewFileWriter(buffer, schema, codec)
+		assert.NoError(t, err)
+		assert.Error(t, w.Append(map[string]interface{}{"id": "invalid"}))
+
+		// Enough records to span several blocks
+		for i := 0; i < 10000; i++ {
+			assert.NoError(t, w.Append(map[string]interface{}{
+				"id": int64(i), "name": "record",
+			}))
+		}
+		assert.NoError(t, w.Close())
+		assert.Equal(t, []byte("Obj\x01"), buffer.Bytes()[:4])
+
+		for _, src := range []io.Reader{
+			bytes.NewBuffer(buffer.Bytes()),
+			bytes.NewReader(buffer.Bytes()),
+		} {
+			r, err := NewFileReader(src, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, schema.String(), r.Schema().String())
+			assert.Equal(t, schema.String(), string(r.Metadata("avro.schema")))
+
+			count := 0
+			for r.Next() {
+				assert.Equal(t, map[string]interface{}{
+					"id": int64(count), "name": "record",
+				}, r.Value())
+				count++
+			}
+
+			assert.NoError(t, r.Err())
+			assert.Equal(t, 10000, count)
+		}
+	}
+}
+
+func TestFileResolve(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w, err := NewFileWriter(buffer, mustParse(`"int"`), CodecDeflate)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Append(1))
+	assert.NoError(t, w.Flush())
+	assert.NoError(t, w.Append(2))
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+
+	r, err := NewFileReader(bytes.NewBuffer(buffer.Bytes()), mustParse(`["null", "double"]`))
+	assert.NoError(t, err)
+	assert.Equal(t, CodecDeflate, string(r.Metadata("avro.codec")))
+
+	var out []interface{}
+	for r.Next() {
+		out = append(out, r.Value())
+	}
+	assert.NoError(t, r.Err())
+	assert.Equal(t, []interface{}{1.0, 2.0}, out)
+
+	_, err = NewFileReader(bytes.NewBuffer(buffer.Bytes()), mustParse(`"string"`))
+	assert.Error(t, err)
+}
+
+func TestFileErrors(t *testing.T) {
+	_, err := NewFileWriter(bytes.NewBuffer(nil), mustParse(`"int"`), "snappy")
+	assert.Error(t, err)
+
+	buffer := bytes.NewBuffer(nil)
+	w, err := NewFileWriter(buffer, mustParse(`"int"`), CodecNull)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Append(1))
+	assert.NoError(t, w.Close())
+	valid := buffer.Bytes()
+
+	// Invalid headers
+	for _, input := range [][]byte{
+		nil,
+		[]byte("Obj\x02"),
+		[]byte("Obj\x01"),
+		valid[:len(valid)-20],
+		header(map[string]interface{}{"avro.schema": []byte(`"int"`), "avro.codec": []byte("snappy")}),
+		header(map[string]interface{}{"avro.schema": []byte(`"unknown"`)}),
+	} {
+		_, err := NewFileReader(bytes.NewBuffer(input), nil)
+		assert.Error(t, err, string(input))
+	}
+
+	// Invalid blocks
+	corrupt := append([]byte{}, valid...)
+	corrupt[len(corrupt)-1]++
+	for _, input := range [][]byte{
+		corrupt,
+		valid[:len(valid)-1],
+		append(valid[:len(valid)-syncSize-3:len(valid)-syncSize-3], 0x01, 0x00), // negative count
+		append(append([]byte{}, valid...), 0x02),
+		append(append([]byte{}, valid...), 0x02, 0x00),
+	} {
+		r, err := NewFileReader(bytes.NewBuffer(input), nil)
+		assert.NoError(t, err)
+		for r.Next() {
+		}
+		assert.Error(t, r.Err())
+		assert.False(t, r.Next())
+	}
+
+	// Errors of the stream between blocks are reported
+	r, err := NewFileReader(io.MultiReader(bytes.NewReader(valid), failingReader{}), nil)
+	assert.NoError(t, err)
+	for r.Next() {
+	}
+	assert.Equal(t, io.ErrClosedPipe, r.Err())
+}
+
+// failingReader is a reader which always fails
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+// header creates the header of a file with the metadata
+func header(meta map[string]interface{}) []byte {
+	buffer := bytes.NewBuffer(nil)
+	buffer.Write(magic)
+	NewEncoder(metaSchema, iostream.NewWriter(buffer)).Encode(meta)
+	buffer.Write(make([]byte, syncSize))
+	return buffer.Bytes()
+}
@@ -0,0 +1,510 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package avro implements the Apache Avro binary encoding on top of the iostream
+// reader and writer. Values are encoded from and decoded into plain Go values as
+// described by a schema, data written with one schema can be read with another
+// compatible schema, and files can be read and written in the Object Container
+// File format.
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Type represents the type of a schema
+type Type string
+
+// Primitive and complex types, as specified by the Avro specification
+const (
+	Null    Type = "null"
+	Boolean Type = "boolean"
+	Int     Type = "int"
+	Long    Type = "long"
+	Float   Type = "float"
+	Double  Type = "double"
+	Bytes   Type = "bytes"
+	String  Type = "string"
+	Record  Type = "record"
+	Enum    Type = "enum"
+	Array   Type = "array"
+	Map     Type = "map"
+	Union   Type = "union"
+	Fixed   Type = "fixed"
+)
+
+// Schema represents a parsed Avro schema
+type Schema struct {
+	Type        Type      // The type of the schema
+	Name        string    // The full name of a record, enum or fixed
+	Aliases     []string  // The full aliases of a record, enum or fixed
+	Fields      []*Field  // The fields of a record
+	Symbols     []string  // The symbols of an enum
+	Items       *Schema   // The schema of the items of an array
+	Values      *Schema   // The schema of the values of a map
+	Branches    []*Schema // The branches of a union
+	Size        int       // The size of a fixed
+	LogicalType string    // The logical type, which does not change the encoding
+}
+
+// Field represents a field of a record
+type Field struct {
+	Name       string      // The name of the field
+	Aliases    []string    // The aliases of the field
+	Type       *Schema     // The schema of the field
+	Default    interface{} // The default value, used when reading data without the field
+	HasDefault bool        // Whether the field has a default value
+	raw        interface{} // The default value as specified in the schema
+}
+
+// Parse parses a schema from its JSON representation.
+func Parse(text string) (*Schema, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var node interface{}
+	if err := decoder.Decode(&node); err != nil {
+		return nil, fmt.Errorf("avro: invalid schema, %w", err)
+	}
+
+	return parse(node, "", make(map[string]*Schema))
+}
+
+// String returns the JSON representation of the schema
+func (s *Schema) String() string {
+	b, _ := json.Marshal(s.toJSON(make(map[string]bool)))
+	return string(b)
+}
+
+// isNamed returns whether the schema is a named type
+func (s *Schema) isNamed() bool {
+	return s.Type == Record || s.Type == Enum || s.Type == Fixed
+}
+
+// hasName returns whether the schema has the name, either as its full name or
+// as one of its aliases.
+func (s *Schema) hasName(name string) bool {
+	if s.Name == name {
+		return true
+	}
+
+	for _, alias := range s.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// field returns the field which corresponds to the name or one of its aliases
+func (s *Schema) field(name string) *Field {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	for _, f := range s.Fields {
+		for _, alias := range f.Aliases {
+			if alias == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// --------------------------- Parsing ---------------------------
+
+// parse parses a schema node decoded from JSON
+func parse(node interface{}, namespace string, names map[string]*Schema) (*Schema, error) {
+	switch v := node.(type) {
+	case string:
+		if isPrimitive(Type(v)) {
+			return &Schema{Type: Type(v)}, nil
+		}
+
+		// Otherwise, this is a reference to a named type
+		for _, name := range []string{fullName(v, namespace), v} {
+			if s, ok := names[name]; ok {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("avro: unknown type %q", v)
+	case []interface{}:
+		return parseUnion(v, namespace, names)
+	case map[string]interface{}:
+		return parseComplex(v, namespace, names)
+	default:
+		return nil, fmt.Errorf("avro: invalid schema %v", node)
+	}
+}
+
+// parseUnion parses the branches of a union
+func parseUnion(nodes []interface{}, namespace string, names map[string]*Schema) (*Schema, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("avro: union has no branches")
+	}
+
+	out := &Schema{Type: Union}
+	seen := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		branch, err := parse(node, namespace, names)
+		if err != nil {
+			return nil, err
+		}
+
+		key := string(branch.Type)
+		if branch.isNamed() {
+			key = branch.Name
+		}
+
+		switch {
+		case branch.Type == Union:
+			return nil, fmt.Errorf("avro: union contains a nested union")
+		case seen[key]:
+			return nil, fmt.Errorf("avro: union contains %q more than once", key)
+		}
+
+		seen[key] = true
+		out.Branches = append(out.Branches, branch)
+	}
+	return out, nil
+}
+
+// parseComplex parses a schema declared as a JSON object
+func parseComplex(node map[string]interface{}, namespace string, names map[string]*Schema) (*Schema, error) {
+	typ, ok := node["type"].(string)
+	if !ok {
+		return parse(node["type"], namespace, names)
+	}
+
+	logical, _ := node["logicalType"].(string)
+	switch t := Type(typ); {
+	case isPrimitive(t):
+		return &Schema{Type: t, LogicalType: logical}, nil
+	case t == Array:
+		items, err := parse(node["items"], namespace, names)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Array, Items: items, LogicalType: logical}, nil
+	case t == Map:
+		values, err := parse(node["values"], namespace, names)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Map, Values: values, LogicalType: logical}, nil
+	case t != Record && t != Enum && t != Fixed && typ != "error":
+		return nil, fmt.Errorf("avro: unknown type %q", typ)
+	}
+
+	// Named types must be registered before their fields, since they can be recursive
+	name, _ := node["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("avro: %s without a name", typ)
+	}
+
+	if ns, ok := node["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+
+	out := &Schema{Type: Type(typ), Name: fullName(name, namespace), LogicalType: logical}
+	if out.Type == "error" {
+		out.Type = Record
+	}
+
+	if _, exists := names[out.Name]; exists {
+		return nil, fmt.Errorf("avro: type %q is defined more than once", out.Name)
+	}
+
+	names[out.Name] = out
+	namespace = namespaceOf(out.Name)
+	out.Aliases = aliasesOf(node, namespace)
+
+	switch out.Type {
+	case Fixed:
+		size, _ := node["size"].(json.Number)
+		n, err := size.Int64()
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("avro: fixed %q has an invalid size", out.Name)
+		}
+		out.Size = int(n)
+
+	case Enum:
+		symbols, _ := node["symbols"].([]interface{})
+		for _, symbol := range symbols {
+			s, ok := symbol.(string)
+			if !ok {
+				return nil, fmt.Errorf("avro: enum %q has an invalid symbol", out.Name)
+			}
+			out.Symbols = append(out.Symbols, s)
+		}
+
+	case Record:
+		fields, ok := node["fields"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("avro: record %q has no fields", out.Name)
+		}
+
+		for _, f := range fields {
+			field, err := parseField(f, namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			out.Fields = append(out.Fields, field)
+		}
+	}
+
+	return out, nil
+}
+
+// parseField parses a field of a record
+func parseField(node interface{}, namespace string, names map[string]*Schema) (*Field, error) {
+	v, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("avro: invalid field %v", node)
+	}
+
+	name, _ := v["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("avro: field without a name")
+	}
+
+	typ, err := parse(v["type"], namespace, names)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Field{Name: name, Type: typ}
+	aliases, _ := v["aliases"].([]interface{})
+	for _, alias := range aliases {
+		if s, ok := alias.(string); ok {
+			out.Aliases = append(out.Aliases, s)
+		}
+	}
+
+	if raw, ok := v["default"]; ok {
+		if out.Default, err = defaultOf(typ, raw); err != nil {
+			return nil, fmt.Errorf("avro: field %q has an invalid default, %w", name, err)
+		}
+		out.raw = raw
+		out.HasDefault = true
+	}
+	return out, nil
+}
+
+// defaultOf converts a default value from its JSON representation
+func defaultOf(s *Schema, v interface{}) (interface{}, error) {
+	switch s.Type {
+	case Null:
+		if v == nil {
+			return nil, nil
+		}
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case Int:
+		if n, ok := v.(json.Number); ok {
+			i, err := n.Int64()
+			return int32(i), err
+		}
+	case Long:
+		if n, ok := v.(json.Number); ok {
+			return n.Int64()
+		}
+	case Float:
+		if n, ok := v.(json.Number); ok {
+			f, err := n.Float64()
+			return float32(f), err
+		}
+	case Double:
+		if n, ok := v.(json.Number); ok {
+			return n.Float64()
+		}
+	case String:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case Bytes, Fixed:
+		if text, ok := v.(string); ok {
+			out := make([]byte, 0, len(text))
+			for _, r := range text {
+				if r > 0xff {
+					return nil, fmt.Errorf("invalid byte %q", r)
+				}
+				out = append(out, byte(r))
+			}
+			return out, nil
+		}
+	case Enum:
+		if symbol, ok := v.(string); ok && indexOf(s.Symbols, symbol) >= 0 {
+			return symbol, nil
+		}
+	case Array:
+		if items, ok := v.([]interface{}); ok {
+			out := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				value, err := defaultOf(s.Items, item)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, value)
+			}
+			return out, nil
+		}
+	case Map:
+		if values, ok := v.(map[string]interface{}); ok {
+			out := make(map[string]interface{}, len(values))
+			for key, item := range values {
+				value, err := defaultOf(s.Values, item)
+				if err != nil {
+					return nil, err
+				}
+				out[key] = value
+			}
+			return out, nil
+		}
+	case Record:
+		if values, ok := v.(map[string]interface{}); ok {
+			out := make(map[string]interface{}, len(s.Fields))
+			for _, field := range s.Fields {
+				item, ok := values[field.Name]
+				switch {
+				case !ok && field.HasDefault:
+					out[field.Name] = field.Default
+					continue
+				case !ok:
+					return nil, fmt.Errorf("missing field %q", field.Name)
+				}
+
+				value, err := defaultOf(field.Type, item)
+				if err != nil {
+					return nil, err
+				}
+				out[field.Name] = value
+			}
+			return out, nil
+		}
+	case Union:
+		// The default value of a union corresponds to its first branch
+		return defaultOf(s.Branches[0], v)
+	}
+
+	return nil, fmt.Errorf("%v is not a valid %s", v, s.Type)
+}
+
+// --------------------------- Serialization ---------------------------
+
+// toJSON converts the schema to a value which can be serialized as JSON. Named
+// types are only written once, and referenced by name afterwards.
+func (s *Schema) toJSON(seen map[string]bool) interface{} {
+	switch s.Type {
+	case Union:
+		out := make([]interface{}, 0, len(s.Branches))
+		for _, branch := range s.Branches {
+			out = append(out, branch.toJSON(seen))
+		}
+		return out
+	case Array:
+		return s.withLogicalType(map[string]interface{}{"type": Array, "items": s.Items.toJSON(seen)})
+	case Map:
+		return s.withLogicalType(map[string]interface{}{"type": Map, "values": s.Values.toJSON(seen)})
+	case Record, Enum, Fixed:
+		if seen[s.Name] {
+			return s.Name
+		}
+
+		seen[s.Name] = true
+		out := map[string]interface{}{"type": s.Type, "name": s.Name}
+		if len(s.Aliases) > 0 {
+			out["aliases"] = s.Aliases
+		}
+
+		switch s.Type {
+		case Record:
+			fields := make([]interface{}, 0, len(s.Fields))
+			for _, f := range s.Fields {
+				field := map[string]interface{}{"name": f.Name, "type": f.Type.toJSON(seen)}
+				if len(f.Aliases) > 0 {
+					field["aliases"] = f.Aliases
+				}
+				if f.HasDefault {
+					field["default"] = f.raw
+				}
+				fields = append(fields, field)
+			}
+			out["fields"] = fields
+		case Enum:
+			out["symbols"] = s.Symbols
+		case Fixed:
+			out["size"] = s.Size
+		}
+		return s.withLogicalType(out)
+	default:
+		if s.LogicalType != "" {
+			return s.withLogicalType(map[string]interface{}{"type": s.Type})
+		}
+		return s.Type
+	}
+}
+
+// withLogicalType adds the logical type to the JSON object, if there is one
+func (s *Schema) withLogicalType(out map[string]interface{}) map[string]interface{} {
+	if s.LogicalType != "" {
+		out["logicalType"] = s.LogicalType
+	}
+	return out
+}
+
+// --------------------------- Names ---------------------------
+
+// isPrimitive returns whether the type is a primitive type
+func isPrimitive(t Type) bool {
+	switch t {
+	case Null, Boolean, Int, Long, Float, Double, Bytes, String:
+		return true
+	default:
+		return false
+	}
+}
+
+// fullName returns the full name of a type within the namespace
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// namespaceOf returns the namespace of a full name
+func namespaceOf(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// aliasesOf returns the full names of the aliases of a named type
+func aliasesOf(node map[string]interface{}, namespace string) (out []string) {
+	aliases, _ := node["aliases"].([]interface{})
+	for _, alias := range aliases {
+		if s, ok := alias.(string); ok {
+			out = append(out, fullName(s, namespace))
+		}
+	}
+	return
+}
+
+// indexOf returns the index of the string in the slice, or -1 if not found
+func indexOf(values []string, v string) int {
+	for i, s := range values {
+		if s == v {
+			return i
+		}
+	}
+	return -1
+}
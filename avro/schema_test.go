@@ -0,0 +1,147 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+	"type": "record",
+	"name": "Event",
+	"namespace": "com.example",
+	"aliases": ["OldEvent"],
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": "string", "aliases": ["title"]},
+		{"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B", "C"]}, "default": "A"},
+		{"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 4}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}, "default": []},
+		{"name": "attrs", "type": {"type": "map", "values": "double"}, "default": {"x": 1.5}},
+		{"name": "parent", "type": ["null", "Event"], "default": null},
+		{"name": "data", "type": "bytes", "default": "ÿ"},
+		{"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}, "default": 0}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(testSchema)
+	assert.NoError(t, err)
+	assert.Equal(t, Record, s.Type)
+	assert.Equal(t, "com.example.Event", s.Name)
+	assert.Equal(t, []string{"com.example.OldEvent"}, s.Aliases)
+	assert.Len(t, s.Fields, 9)
+
+	assert.Equal(t, "com.example.Kind", s.Fields[2].Type.Name)
+	assert.Equal(t, []string{"A", "B", "C"}, s.Fields[2].Type.Symbols)
+	assert.Equal(t, "A", s.Fields[2].Default)
+	assert.Equal(t, 4, s.Fields[3].Type.Size)
+	assert.Equal(t, []interface{}{}, s.Fields[4].Default)
+	assert.Equal(t, map[string]interface{}{"x": 1.5}, s.Fields[5].Default)
+	assert.Equal(t, Union, s.Fields[6].Type.Type)
+	assert.Equal(t, s, s.Fields[6].Type.Branches[1])
+	assert.True(t, s.Fields[6].HasDefault)
+	assert.Nil(t, s.Fields[6].Default)
+	assert.Equal(t, []byte{0xff}, s.Fields[7].Default)
+	assert.Equal(t, "timestamp-millis", s.Fields[8].Type.LogicalType)
+	assert.Equal(t, int64(0), s.Fields[8].Default)
+	assert.Equal(t, s.Fields[1], s.field("title"))
+	assert.Nil(t, s.field("missing"))
+}
+
+func TestSchemaString(t *testing.T) {
+	s, err := Parse(testSchema)
+	assert.NoError(t, err)
+
+	// Parsing the serialized schema must produce the same schema
+	out, err := Parse(s.String())
+	assert.NoError(t, err)
+	assert.Equal(t, s.String(), out.String())
+	assert.Equal(t, `"string"`, s.Fields[1].Type.String())
+	assert.Equal(t, `["null","string"]`, mustParse(`["null", "string"]`).String())
+}
+
+func TestParsePrimitives(t *testing.T) {
+	for _, typ := range []Type{Null, Boolean, Int, Long, Float, Double, Bytes, String} {
+		s, err := Parse(`"` + string(typ) + `"`)
+		assert.NoError(t, err)
+		assert.Equal(t, typ, s.Type)
+
+		s, err = Parse(`{"type": "` + string(typ) + `"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, typ, s.Type)
+	}
+}
+
+func TestParseNamespaces(t *testing.T) {
+	s, err := Parse(`{"type": "record", "name": "a.b.R", "namespace": "ignored", "fields": [
+		{"name": "x", "type": {"type": "fixed", "name": "F", "size": 1}},
+		{"name": "y", "type": "a.b.F"},
+		{"name": "z", "type": {"type": "error", "name": "other.E", "fields": []}}
+	]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.b.R", s.Name)
+	assert.Equal(t, "a.b.F", s.Fields[0].Type.Name)
+	assert.Equal(t, s.Fields[0].Type, s.Fields[1].Type)
+	assert.Equal(t, Record, s.Fields[2].Type.Type)
+	assert.Equal(t, "other.E", s.Fields[2].Type.Name)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		``,
+		`"unknown"`,
+		`123`,
+		`["int", "int"]`,
+		`["int", ["string"]]`,
+		`[123]`,
+		`[]`,
+		`{"type": "record", "name": "R", "fields": [{"name": "a", "type": []}]}`,
+		`{"type": "unknown"}`,
+		`{"type": "array"}`,
+		`{"type": "map"}`,
+		`{"type": "record", "fields": []}`,
+		`{"type": "record", "name": "R"}`,
+		`{"type": "record", "name": "R", "fields": [123]}`,
+		`{"type": "record", "name": "R", "fields": [{"type": "int"}]}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "X"}]}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int", "default": "x"}]}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "R"}, {"name": "b", "type": {"type": "record", "name": "R", "fields": []}}]}`,
+		`{"type": "fixed", "name": "F"}`,
+		`{"type": "fixed", "name": "F", "size": -1}`,
+		`{"type": "enum", "name": "E", "symbols": [1]}`,
+	} {
+		_, err := Parse(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestDefaultErrors(t *testing.T) {
+	for _, tc := range []struct {
+		schema string
+		value  interface{}
+	}{
+		{`"null"`, 1},
+		{`"boolean"`, 1},
+		{`"bytes"`, "Ā"},
+		{`{"type": "enum", "name": "E", "symbols": ["A"]}`, "B"},
+		{`{"type": "array", "items": "int"}`, []interface{}{"x"}},
+		{`{"type": "map", "values": "int"}`, map[string]interface{}{"a": "x"}},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`, map[string]interface{}{}},
+		{`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}]}`, map[string]interface{}{"a": "x"}},
+	} {
+		_, err := defaultOf(mustParse(tc.schema), tc.value)
+		assert.Error(t, err, tc.schema)
+	}
+}
+
+func mustParse(text string) *Schema {
+	s, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
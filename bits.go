@@ -0,0 +1,217 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"errors"
+	"io"
+)
+
+var errBitCount = errors.New("iostream: bit count must be between 0 and 64")
+
+// --------------------------- Bit Writer ---------------------------
+
+// BitWriter represents a bit-level writer on top of a stream writer. Bits are
+// packed least significant bit first and only complete bytes are written into
+// the underlying writer, until the writer is aligned or flushed.
+type BitWriter struct {
+	out    *Writer
+	acc    uint64 // The pending bits, least significant first
+	count  uint   // The number of pending bits
+	offset int64  // The number of bits written
+}
+
+// NewBitWriter creates a new bit-level writer.
+func NewBitWriter(out io.Writer) *BitWriter {
+	return &BitWriter{
+		out: NewWriter(out),
+	}
+}
+
+// Offset returns the number of bits written through this writer.
+func (w *BitWriter) Offset() int64 {
+	return w.offset
+}
+
+// WriteBit writes a single bit
+func (w *BitWriter) WriteBit(v bool) error {
+	if v {
+		return w.WriteBits(1, 1)
+	}
+	return w.WriteBits(0, 1)
+}
+
+// WriteBits writes the n least significant bits of the value, n must be between
+// 0 and 64 or an error is returned.
+func (w *BitWriter) WriteBits(v uint64, n int) error {
+	switch {
+	case n < 0 || n > 64:
+		return errBitCount
+	case n < 64:
+		v &= 1<<uint(n) - 1
+	}
+
+	w.offset += int64(n)
+	for n > 0 {
+		take := 64 - int(w.count)
+		if take > n {
+			take = n
+		}
+
+		w.acc |= v << w.count
+		w.count += uint(take)
+		v >>= uint(take)
+		n -= take
+
+		// Once we have accumulated a full word, write it down
+		if w.count == 64 {
+			if err := w.out.WriteUint64(w.acc); err != nil {
+				return err
+			}
+			w.acc, w.count = 0, 0
+		}
+	}
+	return nil
+}
+
+// AlignByte pads the pending bits with zeroes up to the next byte boundary and
+// writes them into the underlying writer. Once aligned, the underlying writer can
+// be used directly and its offset accounts for all of the bits written.
+func (w *BitWriter) AlignByte() error {
+	if pad := (8 - w.count%8) % 8; pad > 0 {
+		w.offset += int64(pad)
+		w.count += pad
+	}
+
+	for ; w.count > 0; w.count -= 8 {
+		if err := w.out.WriteUint8(uint8(w.acc)); err != nil {
+			return err
+		}
+		w.acc >>= 8
+	}
+	return nil
+}
+
+// Flush aligns the writer to the next byte boundary and flushes the underlying
+// writer.
+func (w *BitWriter) Flush() error {
+	if err := w.AlignByte(); err != nil {
+		return err
+	}
+	return w.out.Flush()
+}
+
+// --------------------------- Bit Reader ---------------------------
+
+// BitReader represents a bit-level reader on top of a stream reader. Bits are
+// read least significant bit first and the underlying reader is consumed one
+// byte at a time, so it can be used directly once the reader is aligned.
+type BitReader struct {
+	src    *Reader
+	acc    uint8 // The remaining bits of the current byte
+	count  uint  // The number of remaining bits
+	offset int64 // The number of bits read
+}
+
+// NewBitReader creates a new bit-level reader.
+func NewBitReader(src io.Reader) *BitReader {
+	return &BitReader{
+		src: NewReader(src),
+	}
+}
+
+// Offset returns the number of bits read through this reader.
+func (r *BitReader) Offset() int64 {
+	return r.offset
+}
+
+// ReadBit reads a single bit
+func (r *BitReader) ReadBit() (bool, error) {
+	v, err := r.ReadBits(1)
+	return v == 1, err
+}
+
+// ReadBits reads n bits, n must be between 0 and 64 or an error is returned.
+func (r *BitReader) ReadBits(n int) (out uint64, err error) {
+	if n < 0 || n > 64 {
+		return 0, errBitCount
+	}
+
+	for shift := uint(0); n > 0; {
+		if r.count == 0 {
+			if r.acc, err = r.src.ReadUint8(); err != nil {
+				return 0, err
+			}
+			r.count = 8
+		}
+
+		take := r.count
+		if take > uint(n) {
+			take = uint(n)
+		}
+
+		out |= uint64(r.acc&(1<<take-1)) << shift
+		r.acc >>= take
+		r.count -= take
+		r.offset += int64(take)
+		shift += take
+		n -= int(take)
+	}
+	return
+}
+
+// AlignByte discards the remaining bits of the current byte. Once aligned, the
+// underlying reader can be used directly.
+func (r *BitReader) AlignByte() {
+	r.offset += int64(r.count)
+	r.acc, r.count = 0, 0
+}
+
+// --------------------------- Bit Packing ---------------------------
+
+// packBits packs the offsets of the values from the base into the destination,
+// using the specified number of bits per value, least significant bit first. It
+// returns the number of bytes written.
+func packBits(dst []byte, src []uint32, base uint32, width uint) int {
+	var acc uint64
+	var count uint
+	n := 0
+	for _, v := range src {
+		acc |= uint64(v-base) << count
+		for count += width; count >= 8; count -= 8 {
+			dst[n] = byte(acc)
+			acc >>= 8
+			n++
+		}
+	}
+
+	if count > 0 {
+		dst[n] = byte(acc)
+		n++
+	}
+	return n
+}
+
+// unpackBits unpacks the values packed with the specified number of bits per
+// value from the source and adds the base to each of them.
+func unpackBits(dst []uint32, src []byte, base uint32, width uint) {
+	var acc uint64
+	var count uint
+	mask := uint64(1)<<width - 1
+	for i := range dst {
+		for ; count < width; count += 8 {
+			acc |= uint64(src[0]) << count
+			src = src[1:]
+		}
+
+		dst[i] = base + uint32(acc&mask)
+		acc >>= width
+		count -= width
+	}
+}
+
+// packedSize returns the number of bytes needed to pack n values of the width.
+func packedSize(n int, width uint) int {
+	return (n*int(width) + 7) / 8
+}
@@ -0,0 +1,201 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitWriter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewBitWriter(buffer)
+	assert.NoError(t, w.WriteBit(true))
+	assert.NoError(t, w.WriteBits(0x2, 3))
+	assert.NoError(t, w.WriteBits(0xff, 4))
+	assert.NoError(t, w.WriteBits(0x1, 2))
+	assert.Equal(t, int64(10), w.Offset())
+	assert.Equal(t, 0, buffer.Len())
+
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, int64(16), w.Offset())
+	assert.Equal(t, []byte{0xf5, 0x01}, buffer.Bytes())
+}
+
+func TestBitRoundTrip(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewBitWriter(buffer)
+	for n := 0; n <= 64; n++ {
+		assert.NoError(t, w.WriteBits(math.MaxUint64, n))
+		assert.NoError(t, w.WriteBit(n%2 == 0))
+	}
+	assert.NoError(t, w.Flush())
+
+	r := NewBitReader(buffer)
+	for n := 0; n <= 64; n++ {
+		v, err := r.ReadBits(n)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(math.MaxUint64)>>uint(64-n), v)
+
+		b, err := r.ReadBit()
+		assert.NoError(t, err)
+		assert.Equal(t, n%2 == 0, b)
+	}
+	r.AlignByte()
+	assert.Equal(t, w.Offset(), r.Offset())
+}
+
+func TestBitInterleaved(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	out := NewWriter(buffer)
+	w := NewBitWriter(out)
+	assert.NoError(t, out.WriteString("hi"))
+	assert.NoError(t, w.WriteBits(0x5, 3))
+	assert.NoError(t, w.AlignByte())
+	assert.Equal(t, int64(4), out.Offset())
+	assert.NoError(t, out.WriteUint16(0xffff))
+	assert.NoError(t, w.WriteBits(0x7, 3))
+	assert.NoError(t, w.AlignByte())
+	assert.Equal(t, int64(7), out.Offset())
+
+	src := NewReader(bytes.NewBuffer(buffer.Bytes()))
+	r := NewBitReader(src)
+	s, err := src.ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", s)
+
+	v, err := r.ReadBits(3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x5), v)
+	r.AlignByte()
+
+	u, err := src.ReadUint16()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0xffff), u)
+
+	v, err = r.ReadBits(3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x7), v)
+	r.AlignByte()
+	assert.Equal(t, int64(7), src.Offset())
+	assert.Equal(t, int64(16), r.Offset())
+}
+
+func TestBitWriterFailures(t *testing.T) {
+	w := NewBitWriter(newLimitWriter(0))
+	assert.NoError(t, w.WriteBits(1, 1))
+	assert.Error(t, w.Flush())
+
+	w = NewBitWriter(newLimitWriter(0))
+	assert.NoError(t, w.WriteBits(1, 63))
+	assert.Error(t, w.WriteBits(1, 2))
+}
+
+func TestBitReaderEOF(t *testing.T) {
+	r := NewBitReader(bytes.NewBuffer([]byte{0xff}))
+	_, err := r.ReadBits(9)
+	assert.Error(t, err)
+}
+
+func TestBitCountInvalid(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewBitWriter(buffer)
+	for _, n := range []int{-1, 65} {
+		assert.Equal(t, errBitCount, w.WriteBits(1, n))
+	}
+	assert.NoError(t, w.WriteBits(math.MaxUint64, 64))
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, int64(64), w.Offset())
+
+	r := NewBitReader(buffer)
+	for _, n := range []int{-1, 65} {
+		_, err := r.ReadBits(n)
+		assert.Equal(t, errBitCount, err)
+	}
+
+	v, err := r.ReadBits(64)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), v)
+	assert.Equal(t, int64(64), r.Offset())
+}
+
+func TestPackedUint32s(t *testing.T) {
+	input := make([]uint32, 3*packedBlock+5)
+	for i := range input {
+		switch i / packedBlock {
+		case 0: // Constant block
+			input[i] = 42
+		case 1: // Full width block
+			input[i] = rand.Uint32() | 0x80000000
+			input[i-i%packedBlock] = 0
+		default:
+			input[i] = 1000 + uint32(rand.Intn(300))
+		}
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WritePackedUint32s(input))
+
+	output, err := NewReader(newNetworkSource(buffer.Bytes())).ReadPackedUint32s()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestPackedUint32sInvalidWidth(t *testing.T) {
+	_, err := NewReader(bytes.NewBuffer([]byte{0x1, 0x0, 0x21, 0x0})).ReadPackedUint32s()
+	assert.Error(t, err)
+}
+
+func TestPackedUint32sInvalidMin(t *testing.T) {
+	_, err := NewReader(bytes.NewBuffer([]byte{0x1, 0x80, 0x80, 0x80, 0x80, 0x10, 0x0})).ReadPackedUint32s()
+	assert.Error(t, err)
+}
+
+func TestPackedUint32sInvalidLength(t *testing.T) {
+	for _, input := range [][]byte{
+		{0x80, 0x80, 0x80, 0x80, 0x10, 0x0, 0x0},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x0, 0x0},
+		{0x80, 0x1, 0x0, 0x20, 0x1, 0x2, 0x3, 0x4},
+	} {
+		_, err := NewReader(bytes.NewBuffer(input)).ReadPackedUint32s()
+		assert.Error(t, err)
+
+		_, err = NewReader(newNetworkSource(input)).ReadPackedUint32s()
+		assert.Error(t, err)
+	}
+}
+
+func TestPackedUint32sStream(t *testing.T) {
+	input := make([]uint32, 100*packedBlock+1)
+	for i := range input {
+		input[i] = uint32(i % 1000)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WritePackedUint32s(input))
+
+	output, err := NewReader(newNetworkSource(buffer.Bytes())).ReadPackedUint32s()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestPackBits(t *testing.T) {
+	for width := uint(0); width <= 32; width++ {
+		input := make([]uint32, 17)
+		for i := range input {
+			input[i] = 7 + uint32(rand.Uint64()&(1<<width-1))
+		}
+
+		packed := make([]byte, packedSize(len(input), width))
+		assert.Equal(t, len(packed), packBits(packed, input, 7, width))
+
+		output := make([]uint32, len(input))
+		unpackBits(output, packed, 7, width)
+		assert.Equal(t, input, output)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package bson implements an encoder and a decoder for BSON documents, on top of
+// the iostream reader and writer. Documents are represented as ordered lists of
+// elements, and the values use the types of this package for the BSON types
+// which do not have an equivalent Go type.
+package bson
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errKey         = errors.New("bson: key contains a null byte")
+	errSize        = errors.New("bson: invalid size")
+	errTerminator  = errors.New("bson: missing null terminator")
+	errType        = errors.New("bson: unsupported element type")
+	errUnsupported = errors.New("bson: unsupported value type")
+	errObjectID    = errors.New("bson: invalid object id")
+	errDepth       = errors.New("bson: documents are nested too deeply")
+)
+
+// maxDepth is the maximum nesting of documents and arrays which can be decoded
+const maxDepth = 1024
+
+// Element types, as specified by the BSON specification
+const (
+	typeDouble     = 0x01
+	typeString     = 0x02
+	typeDocument   = 0x03
+	typeArray      = 0x04
+	typeBinary     = 0x05
+	typeObjectID   = 0x07
+	typeBool       = 0x08
+	typeDateTime   = 0x09
+	typeNull       = 0x0a
+	typeRegex      = 0x0b
+	typeInt32      = 0x10
+	typeTimestamp  = 0x11
+	typeInt64      = 0x12
+	typeDecimal128 = 0x13
+)
+
+// Binary subtypes, as specified by the BSON specification
+const (
+	BinaryGeneric  byte = 0x00
+	BinaryFunction byte = 0x01
+	BinaryOld      byte = 0x02
+	BinaryUUID     byte = 0x04
+	BinaryMD5      byte = 0x05
+	BinaryUser     byte = 0x80
+)
+
+// D represents a document as an ordered list of elements
+type D []E
+
+// E represents an element of a document
+type E struct {
+	Key   string
+	Value interface{}
+}
+
+// M represents a document as an unordered map, which is encoded in the order
+// of its sorted keys.
+type M map[string]interface{}
+
+// A represents an array
+type A []interface{}
+
+// Map converts the document to a map. Nested documents are not converted.
+func (d D) Map() M {
+	out := make(M, len(d))
+	for _, e := range d {
+		out[e.Key] = e.Value
+	}
+	return out
+}
+
+// Binary represents binary data along with its subtype
+type Binary struct {
+	Subtype byte
+	Data    []byte
+}
+
+// Regex represents a regular expression along with its options
+type Regex struct {
+	Pattern string
+	Options string
+}
+
+// Timestamp represents an internal MongoDB timestamp, composed of seconds since
+// the epoch and an ordinal within the second.
+type Timestamp struct {
+	T uint32
+	I uint32
+}
+
+// Decimal128 represents the raw bits of an IEEE 754-2008 128-bit decimal
+type Decimal128 struct {
+	H uint64
+	L uint64
+}
+
+// --------------------------- Object ID ---------------------------
+
+// ObjectID represents a 12-byte unique identifier
+type ObjectID [12]byte
+
+// Global state used to generate object ids
+var (
+	objectIDCounter = randomUint32()
+	objectIDProcess = randomProcess()
+)
+
+// NewObjectID generates a new object id, composed of the current time, a random
+// value unique to the process and an incrementing counter.
+func NewObjectID() (out ObjectID) {
+	binary.BigEndian.PutUint32(out[0:4], uint32(time.Now().Unix()))
+	copy(out[4:9], objectIDProcess[:])
+
+	counter := atomic.AddUint32(&objectIDCounter, 1)
+	out[9], out[10], out[11] = byte(counter>>16), byte(counter>>8), byte(counter)
+	return
+}
+
+// ObjectIDFromHex parses an object id from its hexadecimal representation
+func ObjectIDFromHex(s string) (out ObjectID, err error) {
+	if len(s) != 2*len(out) {
+		return out, errObjectID
+	}
+
+	if _, err := hex.Decode(out[:], []byte(s)); err != nil {
+		return out, errObjectID
+	}
+	return out, nil
+}
+
+// Hex returns the hexadecimal representation of the object id
+func (id ObjectID) Hex() string {
+	return hex.EncodeToString(id[:])
+}
+
+// String returns the string representation of the object id
+func (id ObjectID) String() string {
+	return `ObjectID("` + id.Hex() + `")`
+}
+
+// Timestamp returns the time at which the object id was generated
+func (id ObjectID) Timestamp() time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint32(id[0:4])), 0).UTC()
+}
+
+// randomUint32 returns a random 32-bit integer
+func randomUint32() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// randomProcess returns the random value unique to the process
+func randomProcess() (out [5]byte) {
+	rand.Read(out[:])
+	return
+}
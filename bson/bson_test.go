@@ -0,0 +1,33 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package bson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectID(t *testing.T) {
+	a, b := NewObjectID(), NewObjectID()
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a[4:9], b[4:9])
+	assert.WithinDuration(t, time.Now(), a.Timestamp(), 2*time.Second)
+
+	id, err := ObjectIDFromHex(a.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, a, id)
+	assert.Equal(t, `ObjectID("`+a.Hex()+`")`, a.String())
+
+	_, err = ObjectIDFromHex("123")
+	assert.Error(t, err)
+	_, err = ObjectIDFromHex("zzzzzzzzzzzzzzzzzzzzzzzz")
+	assert.Error(t, err)
+}
+
+func TestMap(t *testing.T) {
+	d := D{{"a", 1}, {"b", D{{"c", 2}}}}
+	assert.Equal(t, M{"a": 1, "b": D{{"c", 2}}}, d.Map())
+}
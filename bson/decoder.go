@@ -0,0 +1,225 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package bson
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/kelindar/iostream"
+)
+
+// Decoder represents a BSON decoder.
+type Decoder struct {
+	src *iostream.Reader
+}
+
+// NewDecoder creates a new BSON decoder over the reader.
+func NewDecoder(src *iostream.Reader) *Decoder {
+	return &Decoder{
+		src: src,
+	}
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (d *Decoder) Offset() int64 {
+	return d.src.Offset()
+}
+
+// Decode decodes the next document. Nested documents are decoded as D, arrays
+// as A, binary data as Binary, date/times as time.Time in UTC, 32-bit and 64-bit
+// integers as int32 and int64 respectively.
+func (d *Decoder) Decode() (D, error) {
+	body, err := readBody(d.src)
+	if err != nil {
+		return nil, err
+	}
+	return readDocument(body, 0)
+}
+
+// readBody reads the size of a document and returns the rest of the document,
+// without copying it. The returned slice is only valid until the next read.
+func readBody(r *iostream.Reader) ([]byte, error) {
+	size, err := r.ReadInt32()
+	switch {
+	case err != nil:
+		return nil, err
+	case size < 5:
+		return nil, errSize
+	default:
+		return r.Slice(int(size) - 4)
+	}
+}
+
+// readDocument reads the elements of a document, up to its null terminator. The
+// depth is the number of documents the document is nested in.
+func readDocument(body []byte, depth int) (D, error) {
+	if depth >= maxDepth {
+		return nil, errDepth
+	}
+
+	r := iostream.NewReader(bytes.NewBuffer(body))
+	out := D{}
+	for {
+		typ, err := r.ReadUint8()
+		switch {
+		case err == io.EOF:
+			return nil, errTerminator
+		case err != nil:
+			return nil, err
+		case typ == 0 && r.Offset() != int64(len(body)):
+			return nil, errSize
+		case typ == 0:
+			return out, nil
+		}
+
+		key, err := readCString(r, body)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := readValue(r, body, typ, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, E{Key: key, Value: value})
+	}
+}
+
+// readValue reads the value of an element of the specified type, within a document
+// at the specified depth.
+func readValue(r *iostream.Reader, body []byte, typ byte, depth int) (interface{}, error) {
+	switch typ {
+	case typeNull:
+		return nil, nil
+	case typeBool:
+		return r.ReadBool()
+	case typeDouble:
+		return r.ReadFloat64()
+	case typeInt32:
+		return r.ReadInt32()
+	case typeInt64:
+		return r.ReadInt64()
+	case typeString:
+		return readString(r)
+	case typeBinary:
+		return readBinary(r)
+	case typeDocument, typeArray:
+		b, err := readBody(r)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := readDocument(b, depth+1)
+		if err != nil || typ == typeDocument {
+			return doc, err
+		}
+
+		// Arrays are documents with the indices as keys, which are ignored
+		out := make(A, 0, len(doc))
+		for _, e := range doc {
+			out = append(out, e.Value)
+		}
+		return out, nil
+	case typeObjectID:
+		b, err := r.Slice(12)
+		if err != nil {
+			return nil, err
+		}
+
+		var id ObjectID
+		copy(id[:], b)
+		return id, nil
+	case typeDateTime:
+		ms, err := r.ReadInt64()
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(ms/1000, ms%1000*1e6).UTC(), nil
+	case typeTimestamp:
+		v, err := r.ReadUint64()
+		return Timestamp{T: uint32(v >> 32), I: uint32(v)}, err
+	case typeDecimal128:
+		hi, lo, err := r.ReadUint128()
+		return Decimal128{H: hi, L: lo}, err
+	case typeRegex:
+		pattern, err := readCString(r, body)
+		if err != nil {
+			return nil, err
+		}
+
+		options, err := readCString(r, body)
+		return Regex{Pattern: pattern, Options: options}, err
+	default:
+		return nil, errType
+	}
+}
+
+// readCString reads a null-terminated string from the body of a document
+func readCString(r *iostream.Reader, body []byte) (string, error) {
+	size := bytes.IndexByte(body[r.Offset():], 0)
+	if size < 0 {
+		return "", errTerminator
+	}
+
+	b, err := r.Slice(size + 1)
+	return string(b[:size]), err
+}
+
+// readString reads a string prefixed with its size and followed by a null byte
+func readString(r *iostream.Reader) (string, error) {
+	size, err := r.ReadInt32()
+	switch {
+	case err != nil:
+		return "", err
+	case size < 1:
+		return "", errSize
+	}
+
+	b, err := r.Slice(int(size))
+	switch {
+	case err != nil:
+		return "", err
+	case b[size-1] != 0:
+		return "", errTerminator
+	default:
+		return string(b[:size-1]), nil
+	}
+}
+
+// readBinary reads binary data along with its subtype
+func readBinary(r *iostream.Reader) (Binary, error) {
+	size, err := r.ReadInt32()
+	switch {
+	case err != nil:
+		return Binary{}, err
+	case size < 0:
+		return Binary{}, errSize
+	}
+
+	subtype, err := r.ReadUint8()
+	if err != nil {
+		return Binary{}, err
+	}
+
+	// The old binary subtype contains the size of the data a second time
+	if subtype == BinaryOld {
+		inner, err := r.ReadInt32()
+		switch {
+		case err != nil:
+			return Binary{}, err
+		case inner != size-4:
+			return Binary{}, errSize
+		}
+		size = inner
+	}
+
+	b, err := r.Slice(int(size))
+	if err != nil {
+		return Binary{}, err
+	}
+	return Binary{Subtype: subtype, Data: append([]byte{}, b...)}, nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	expect := map[string]D{
+		"array":  {{"BSON", A{"awesome", 5.05, int32(1986)}}},
+		"map":    {{"a", nil}, {"b", true}},
+		"nested": {{"d", D{{"x", int64(1)}}}},
+	}
+
+	for _, tc := range fixtures {
+		want, ok := expect[tc.Name]
+		if !ok {
+			want = tc.Document.(D)
+		}
+
+		for _, src := range []io.Reader{
+			bytes.NewBufferString(tc.Buffer),
+			bytes.NewReader([]byte(tc.Buffer)),
+		} {
+			dec := NewDecoder(iostream.NewReader(src))
+			doc, err := dec.Decode()
+			assert.NoError(t, err, tc.Name)
+			assert.Equal(t, want, doc, tc.Name)
+			assert.Equal(t, int64(len(tc.Buffer)), dec.Offset(), tc.Name)
+		}
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, enc.Encode(D{{"i", i}, {"s", "value"}}))
+	}
+
+	dec := NewDecoder(iostream.NewReader(bytes.NewReader(buffer.Bytes())))
+	for i := 0; i < 3; i++ {
+		doc, err := dec.Decode()
+		assert.NoError(t, err)
+		assert.Equal(t, D{{"i", int32(i)}, {"s", "value"}}, doc)
+	}
+
+	_, err := dec.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecodeDateTime(t *testing.T) {
+	for _, v := range []time.Time{
+		time.Unix(0, 0), time.Unix(-1, 5e8), time.Unix(1e10, 999e6), time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		buffer := bytes.NewBuffer(nil)
+		assert.NoError(t, NewEncoder(iostream.NewWriter(buffer)).Encode(D{{"t", v}}))
+
+		doc, err := NewDecoder(iostream.NewReader(buffer)).Decode()
+		assert.NoError(t, err)
+		assert.Equal(t, v.UTC(), doc[0].Value)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	// Every truncated document must fail
+	for _, tc := range fixtures {
+		for i := 0; i < len(tc.Buffer); i++ {
+			_, err := NewDecoder(iostream.NewReader(bytes.NewBufferString(tc.Buffer[:i]))).Decode()
+			assert.Error(t, err, "%s %d", tc.Name, i)
+		}
+	}
+
+	for _, input := range []string{
+		"\x04\x00\x00\x00",                                                  // size too small
+		"\x06\x00\x00\x00\x00\x00",                                          // trailing bytes
+		"\x06\x00\x00\x00\x0a\x00",                                          // missing terminator
+		"\x08\x00\x00\x00\x0aab\x00",                                        // missing terminator
+		"\x08\x00\x00\x00\x0aab\x01",                                        // unterminated key
+		"\x08\x00\x00\x00\x06a\x00\x00",                                     // unsupported type
+		"\x0d\x00\x00\x00\x02a\x00\x00\x00\x00\x00\x00",                     // string without a size
+		"\x0e\x00\x00\x00\x02a\x00\x01\x00\x00\x00a\x00",                    // unterminated string
+		"\x0e\x00\x00\x00\x05a\x00\xff\xff\xff\xff\x00\x00",                 // negative binary size
+		"\x10\x00\x00\x00\x05a\x00\x04\x00\x00\x00\x02\x01\x00\x00\x00\x00", // invalid old binary
+		"\x0c\x00\x00\x00\x03a\x00\x04\x00\x00\x00\x00",                     // invalid nested size
+		"\x0e\x00\x00\x00\x04a\x00\x05\x00\x00\x00\x01\x00",                 // invalid nested element
+		"\x0a\x00\x00\x00\x0ba\x00\x00\x00",                                 // invalid regex
+		"\x0b\x00\x00\x00\x0ba\x00b\x00c",                                   // unterminated regex
+		"\xff\xff\xff\x7f\x00",                                              // size beyond the input
+		"\xff\xff\xff\xff\x00",                                              // negative size
+		"\x0d\x00\x00\x00\x03a\x00\x10\x00\x00\x00\x00\x00",                 // nested size beyond the document
+		"\x0d\x00\x00\x00\x03a\x00\x05\x00\x00\x00\x01\x00",                 // unterminated nested document
+		"\x0e\x00\x00\x00\x02a\x00\xff\xff\xff\x7fa\x00\x00",                // string size beyond the input
+		"\x0e\x00\x00\x00\x02a\x00\x00\x00\x00\x00a\x00\x00",                // empty string size
+		"\x0f\x00\x00\x00\x05a\x00\xff\xff\xff\x7f\x00\x00\x00\x00",         // binary size beyond the input
+		"\x10\x00\x00\x00\x05a\x00\x02\x00\x00\x00\x02\x00\x00\x00\x00",     // old binary smaller than its size
+		"\x0e\x00\x00\x00\x05a\x00\x04\x00\x00\x00\x02\x00\x00",             // truncated old binary
+	} {
+		_, err := NewDecoder(iostream.NewReader(bytes.NewBufferString(input))).Decode()
+		assert.Error(t, err, "%q", input)
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	for _, tc := range []struct {
+		depth int
+		err   error
+	}{
+		{maxDepth, nil},
+		{maxDepth + 1, errDepth},
+		{4 * maxDepth, errDepth},
+	} {
+		doc := nested(tc.depth)
+		for _, src := range []io.Reader{bytes.NewBuffer(doc), bytes.NewReader(doc)} {
+			_, err := NewDecoder(iostream.NewReader(src)).Decode()
+			assert.Equal(t, tc.err, err, tc.depth)
+		}
+	}
+}
+
+// nested returns a document with the specified number of nested documents,
+// including itself, alternating between documents and arrays.
+func nested(depth int) []byte {
+	doc := []byte{0x05, 0x00, 0x00, 0x00, 0x00}
+	for i := 1; i < depth; i++ {
+		typ := byte(typeDocument)
+		if i%2 == 0 {
+			typ = typeArray
+		}
+
+		inner := append([]byte{typ, '0', 0x00}, doc...)
+		doc = make([]byte, 4, len(inner)+5)
+		binary.LittleEndian.PutUint32(doc, uint32(len(inner)+5))
+		doc = append(append(doc, inner...), 0x00)
+	}
+	return doc
+}
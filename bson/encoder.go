@@ -0,0 +1,304 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelindar/iostream"
+)
+
+// Encoder represents a BSON encoder.
+type Encoder struct {
+	out    *iostream.Writer
+	buffer bytes.Buffer
+	doc    *iostream.Writer
+}
+
+// NewEncoder creates a new BSON encoder over the writer.
+func NewEncoder(out *iostream.Writer) *Encoder {
+	e := &Encoder{
+		out: out,
+	}
+	e.doc = iostream.NewWriter(&e.buffer)
+	return e
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (e *Encoder) Offset() int64 {
+	return e.out.Offset()
+}
+
+// Encode encodes a document, which is either a D, an M or a map with string keys.
+// Since a document starts with its own size, it is built in memory before being
+// written to the underlying writer.
+func (e *Encoder) Encode(doc interface{}) error {
+	if typ, _ := typeOf(doc); typ != typeDocument {
+		return errUnsupported
+	}
+
+	e.buffer.Reset()
+	if err := e.writeValue(doc, typeDocument); err != nil {
+		return err
+	}
+
+	_, err := e.out.Write(e.buffer.Bytes())
+	return err
+}
+
+// writeDocument writes a document given a function which writes its elements,
+// and backpatches its size once the elements are written.
+func (e *Encoder) writeDocument(fn func() error) error {
+	start := e.buffer.Len()
+	if err := e.doc.WriteInt32(0); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	if err := e.doc.WriteUint8(0); err != nil {
+		return err
+	}
+
+	size := e.buffer.Len() - start
+	if size > math.MaxInt32 {
+		return errSize
+	}
+
+	binary.LittleEndian.PutUint32(e.buffer.Bytes()[start:], uint32(size))
+	return nil
+}
+
+// writeElement writes an element, composed of its type, key and value
+func (e *Encoder) writeElement(key string, v interface{}) error {
+	typ, ok := typeOf(v)
+	if !ok {
+		return errUnsupported
+	}
+
+	if err := e.doc.WriteUint8(typ); err != nil {
+		return err
+	}
+	if err := e.writeCString(key); err != nil {
+		return err
+	}
+	return e.writeValue(v, typ)
+}
+
+// writeValue writes the value of an element of the specified type
+func (e *Encoder) writeValue(v interface{}, typ byte) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		return e.doc.WriteBool(v)
+	case float32:
+		return e.doc.WriteFloat64(float64(v))
+	case float64:
+		return e.doc.WriteFloat64(v)
+	case string:
+		return e.writeString(v)
+	case ObjectID:
+		_, err := e.doc.Write(v[:])
+		return err
+	case time.Time:
+		return e.doc.WriteInt64(v.Unix()*1000 + int64(v.Nanosecond()/1e6))
+	case Timestamp:
+		return e.doc.WriteUint64(uint64(v.T)<<32 | uint64(v.I))
+	case Decimal128:
+		return e.doc.WriteUint128(v.H, v.L)
+	case Regex:
+		if err := e.writeCString(v.Pattern); err != nil {
+			return err
+		}
+		return e.writeCString(v.Options)
+	case []byte:
+		return e.writeBinary(Binary{Subtype: BinaryGeneric, Data: v})
+	case Binary:
+		return e.writeBinary(v)
+	case D:
+		return e.writeDocument(func() error {
+			for _, elem := range v {
+				if err := e.writeElement(elem.Key, elem.Value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case M:
+		return e.writeMap(v)
+	case map[string]interface{}:
+		return e.writeMap(v)
+	case A:
+		return e.writeArray(v)
+	case []interface{}:
+		return e.writeArray(v)
+	}
+
+	// Integers are either written as 32-bit or 64-bit integers
+	i, _ := toInt64(v)
+	if typ == typeInt32 {
+		return e.doc.WriteInt32(int32(i))
+	}
+	return e.doc.WriteInt64(i)
+}
+
+// writeMap writes a map as a document, with its keys sorted
+func (e *Encoder) writeMap(v map[string]interface{}) error {
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return e.writeDocument(func() error {
+		for _, key := range keys {
+			if err := e.writeElement(key, v[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeArray writes an array as a document with the indices as keys
+func (e *Encoder) writeArray(v []interface{}) error {
+	return e.writeDocument(func() error {
+		for i, item := range v {
+			if err := e.writeElement(strconv.Itoa(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeString writes a string prefixed with its size and followed by a null byte
+func (e *Encoder) writeString(v string) error {
+	if len(v) >= math.MaxInt32 {
+		return errSize
+	}
+
+	if err := e.doc.WriteInt32(int32(len(v) + 1)); err != nil {
+		return err
+	}
+
+	e.buffer.WriteString(v)
+	return e.doc.WriteUint8(0)
+}
+
+// writeCString writes a null-terminated string, which can not contain a null byte
+func (e *Encoder) writeCString(v string) error {
+	if strings.IndexByte(v, 0) >= 0 {
+		return errKey
+	}
+
+	e.buffer.WriteString(v)
+	return e.doc.WriteUint8(0)
+}
+
+// writeBinary writes binary data, along with its subtype. The old binary subtype
+// contains the size of the data a second time.
+func (e *Encoder) writeBinary(v Binary) error {
+	size := len(v.Data)
+	if v.Subtype == BinaryOld {
+		size += 4
+	}
+
+	if size > math.MaxInt32 {
+		return errSize
+	}
+
+	if err := e.doc.WriteInt32(int32(size)); err != nil {
+		return err
+	}
+	if err := e.doc.WriteUint8(v.Subtype); err != nil {
+		return err
+	}
+
+	if v.Subtype == BinaryOld {
+		if err := e.doc.WriteInt32(int32(len(v.Data))); err != nil {
+			return err
+		}
+	}
+
+	_, err := e.doc.Write(v.Data)
+	return err
+}
+
+// typeOf returns the element type of a value
+func typeOf(v interface{}) (byte, bool) {
+	switch i := v.(type) {
+	case nil:
+		return typeNull, true
+	case bool:
+		return typeBool, true
+	case float32, float64:
+		return typeDouble, true
+	case string:
+		return typeString, true
+	case ObjectID:
+		return typeObjectID, true
+	case time.Time:
+		return typeDateTime, true
+	case Timestamp:
+		return typeTimestamp, true
+	case Decimal128:
+		return typeDecimal128, true
+	case Regex:
+		return typeRegex, true
+	case []byte, Binary:
+		return typeBinary, true
+	case D, M, map[string]interface{}:
+		return typeDocument, true
+	case A, []interface{}:
+		return typeArray, true
+	case int8, int16, int32, uint8, uint16:
+		return typeInt32, true
+	case int:
+		if i >= math.MinInt32 && i <= math.MaxInt32 {
+			return typeInt32, true
+		}
+		return typeInt64, true
+	}
+
+	_, ok := toInt64(v)
+	return typeInt64, ok
+}
+
+// toInt64 converts an integer of any type to an int64
+func toInt64(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		return int64(v), v <= math.MaxInt64
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	default:
+		return 0, false
+	}
+}
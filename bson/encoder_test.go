@@ -0,0 +1,95 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package bson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+// Golden documents, the first two from the examples of the BSON specification
+var fixtures = []struct {
+	Name     string
+	Document interface{}
+	Buffer   string
+}{
+	{"hello", D{{"hello", "world"}},
+		"\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00world\x00\x00"},
+	{"array", D{{"BSON", A{"awesome", 5.05, 1986}}},
+		"\x31\x00\x00\x00\x04BSON\x00\x26\x00\x00\x00\x020\x00\x08\x00\x00\x00awesome\x00" +
+			"\x011\x00\x33\x33\x33\x33\x33\x33\x14\x40\x102\x00\xc2\x07\x00\x00\x00\x00"},
+	{"empty", D{}, "\x05\x00\x00\x00\x00"},
+	{"map", M{"b": true, "a": nil},
+		"\x0c\x00\x00\x00\x0aa\x00\x08b\x00\x01\x00"},
+	{"nested", D{{"d", map[string]interface{}{"x": int64(1)}}},
+		"\x18\x00\x00\x00\x03d\x00\x10\x00\x00\x00\x12x\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00"},
+	{"objectid", D{{"_id", ObjectID{0x50, 0x7f, 0x1f, 0x77, 0xbc, 0xf8, 0x6c, 0xd7, 0x99, 0x43, 0x90, 0x11}}},
+		"\x16\x00\x00\x00\x07_id\x00\x50\x7f\x1f\x77\xbc\xf8\x6c\xd7\x99\x43\x90\x11\x00"},
+	{"datetime", D{{"t", time.Unix(1, 5e8).UTC()}},
+		"\x10\x00\x00\x00\x09t\x00\xdc\x05\x00\x00\x00\x00\x00\x00\x00"},
+	{"binary", D{{"b", Binary{Subtype: BinaryUUID, Data: []byte{1, 2}}}},
+		"\x0f\x00\x00\x00\x05b\x00\x02\x00\x00\x00\x04\x01\x02\x00"},
+	{"binary-old", D{{"b", Binary{Subtype: BinaryOld, Data: []byte{1}}}},
+		"\x12\x00\x00\x00\x05b\x00\x05\x00\x00\x00\x02\x01\x00\x00\x00\x01\x00"},
+	{"regex", D{{"r", Regex{Pattern: "^a", Options: "i"}}},
+		"\x0d\x00\x00\x00\x0br\x00^a\x00i\x00\x00"},
+	{"timestamp", D{{"t", Timestamp{T: 2, I: 1}}},
+		"\x10\x00\x00\x00\x11t\x00\x01\x00\x00\x00\x02\x00\x00\x00\x00"},
+	{"decimal", D{{"d", Decimal128{H: 0x3040000000000000, L: 1}}},
+		"\x18\x00\x00\x00\x13d\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x40\x30\x00"},
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range fixtures {
+		buffer := bytes.NewBuffer(nil)
+		enc := NewEncoder(iostream.NewWriter(buffer))
+		assert.NoError(t, enc.Encode(tc.Document), tc.Name)
+		assert.Equal(t, []byte(tc.Buffer), buffer.Bytes(), tc.Name)
+		assert.Equal(t, int64(len(tc.Buffer)), enc.Offset(), tc.Name)
+	}
+}
+
+func TestEncodeTypes(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.Encode(D{
+		{"int8", int8(1)}, {"int16", int16(1)}, {"uint8", uint8(1)}, {"uint16", uint16(1)},
+		{"uint32", uint32(1)}, {"uint", uint(1)}, {"uint64", uint64(1)},
+		{"int", 1}, {"int-large", math.MaxInt32 + 1}, {"float32", float32(1)},
+		{"bytes", []byte{1}}, {"array", []interface{}{}},
+	}))
+
+	doc, err := NewDecoder(iostream.NewReader(buffer)).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, D{
+		{"int8", int32(1)}, {"int16", int32(1)}, {"uint8", int32(1)}, {"uint16", int32(1)},
+		{"uint32", int64(1)}, {"uint", int64(1)}, {"uint64", int64(1)},
+		{"int", int32(1)}, {"int-large", int64(math.MaxInt32 + 1)}, {"float32", float64(1)},
+		{"bytes", Binary{Data: []byte{1}}}, {"array", A{}},
+	}, doc)
+}
+
+func TestEncodeErrors(t *testing.T) {
+	enc := NewEncoder(iostream.NewWriter(bytes.NewBuffer(nil)))
+	for _, doc := range []interface{}{
+		"not a document",
+		A{1},
+		D{{"a\x00", 1}},
+		D{{"a", struct{}{}}},
+		D{{"a", uint64(math.MaxUint64)}},
+		D{{"a", Regex{Pattern: "\x00"}}},
+		D{{"a", Regex{Options: "\x00"}}},
+		M{"a": A{struct{}{}}},
+		map[string]interface{}{"a": D{{"b", struct{}{}}}},
+	} {
+		assert.Error(t, enc.Encode(doc), "%v", doc)
+	}
+
+	assert.Equal(t, int64(0), enc.Offset())
+}
@@ -0,0 +1,146 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package cbor implements an encoder and a decoder for the Concise Binary Object
+// Representation (CBOR) as specified by RFC 8949, on top of the iostream reader
+// and writer. The encoder can optionally produce the deterministic encoding
+// described in section 4.2 of the specification.
+package cbor
+
+import (
+	"errors"
+
+	"github.com/kelindar/iostream"
+)
+
+var (
+	errInfo        = errors.New("cbor: malformed additional information")
+	errType        = errors.New("cbor: unexpected major type")
+	errChunk       = errors.New("cbor: invalid chunk in an indefinite-length string")
+	errBreak       = errors.New("cbor: unexpected break")
+	errOverflow    = errors.New("cbor: value overflows the destination type")
+	errLength      = errors.New("cbor: length exceeds the maximum size")
+	errTime        = errors.New("cbor: invalid time value")
+	errKey         = errors.New("cbor: map key is not comparable")
+	errIndefinite  = errors.New("cbor: indefinite-length items are not deterministic")
+	errSimple      = errors.New("cbor: invalid simple value")
+	errUnsupported = errors.New("cbor: unsupported type")
+	errDepth       = errors.New("cbor: items are nested too deeply")
+)
+
+// Major types, as specified by section 3.1 of the specification
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorOther  = 7
+)
+
+// Additional information values with a special meaning
+const (
+	infoUint8      = 24
+	infoUint16     = 25
+	infoUint32     = 26
+	infoUint64     = 27
+	infoIndefinite = 31
+)
+
+// Simple values and floating point numbers of the major type 7
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	simpleFloat16   = 25
+	simpleFloat32   = 26
+	simpleFloat64   = 27
+	codeBreak       = 0xff
+)
+
+// Tag numbers with a built-in support
+const (
+	TagDateTime  = 0 // Standard date/time string
+	TagEpoch     = 1 // Epoch-based date/time
+	TagPosBignum = 2 // Unsigned bignum
+	TagNegBignum = 3 // Negative bignum
+)
+
+// Type represents the type of an encoded item
+type Type uint8
+
+// Types of the encoded items
+const (
+	InvalidType Type = iota
+	IntType
+	BytesType
+	StringType
+	ArrayType
+	MapType
+	TagType
+	BoolType
+	NilType
+	UndefinedType
+	SimpleType
+	FloatType
+	BreakType
+)
+
+// Tag represents a tagged data item which is not natively supported
+type Tag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// Simple represents a simple value which is neither a boolean nor null
+type Simple uint8
+
+// Undefined is the simple value "undefined"
+const Undefined Simple = simpleUndefined
+
+// typeOf returns the type of the item starting with the initial byte
+func typeOf(b byte) Type {
+	switch b >> 5 {
+	case majorUint, majorNegInt:
+		return IntType
+	case majorBytes:
+		return BytesType
+	case majorText:
+		return StringType
+	case majorArray:
+		return ArrayType
+	case majorMap:
+		return MapType
+	case majorTag:
+		return TagType
+	}
+
+	switch b & 0x1f {
+	case simpleFalse, simpleTrue:
+		return BoolType
+	case simpleNull:
+		return NilType
+	case simpleUndefined:
+		return UndefinedType
+	case simpleFloat16, simpleFloat32, simpleFloat64:
+		return FloatType
+	case infoIndefinite:
+		return BreakType
+	case 28, 29, 30:
+		return InvalidType
+	default:
+		return SimpleType
+	}
+}
+
+// --------------------------- Conversions ---------------------------
+
+// float16bits converts a float32 to the bits of a half-precision number, and
+// reports whether the conversion is exact. NaN values are not supported.
+func float16bits(f float32) (uint16, bool) {
+	h := iostream.Float16bits(f)
+	return h, iostream.Float16frombits(h) == f
+}
@@ -0,0 +1,691 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package cbor
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/kelindar/iostream"
+)
+
+// The maximum number of elements allocated upfront when decoding a collection,
+// so that a corrupt length does not cause a huge allocation.
+const maxPrealloc = 1024
+
+// The maximum nesting of arrays, maps and tags, so that a corrupt input does not
+// exhaust the stack.
+const maxDepth = 1024
+
+// Decoder represents a CBOR decoder.
+type Decoder struct {
+	src   *iostream.Reader
+	depth int // The nesting of the item being decoded
+}
+
+// NewDecoder creates a new CBOR decoder over the reader.
+func NewDecoder(src *iostream.Reader) *Decoder {
+	return &Decoder{
+		src: src,
+	}
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (d *Decoder) Offset() int64 {
+	return d.src.Offset()
+}
+
+// PeekType returns the type of the next item without consuming it.
+func (d *Decoder) PeekType() (Type, error) {
+	b, err := d.src.Peek(1)
+	if len(b) == 0 {
+		return InvalidType, err
+	}
+	return typeOf(b[0]), nil
+}
+
+// Decode decodes the next item into a Go value. Integers are decoded as int64,
+// unless they only fit into a uint64 or a *big.Int. Byte and text strings are
+// decoded as []byte and string, arrays as []interface{}, maps as
+// map[interface{}]interface{}, time tags as time.Time in UTC, bignums as *big.Int
+// and other tags as Tag.
+func (d *Decoder) Decode() (interface{}, error) {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	arg, indefinite, err := d.head(initial)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major := initial >> 5; major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return new(big.Int).Not(new(big.Int).SetUint64(arg)), nil
+		}
+		return int64(^arg), nil
+	case majorBytes:
+		b, err := d.bytes(major, arg, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case majorText:
+		b, err := d.bytes(major, arg, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case majorArray:
+		return d.array(arg, indefinite)
+	case majorMap:
+		return d.dictionary(arg, indefinite)
+	case majorTag:
+		return d.tag(arg)
+	default:
+		return d.simple(initial&0x1f, arg)
+	}
+}
+
+// DecodeNil decodes a null value
+func (d *Decoder) DecodeNil() error {
+	initial, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return err
+	case initial != majorOther<<5|simpleNull:
+		return errType
+	default:
+		return nil
+	}
+}
+
+// DecodeBool decodes a boolean value
+func (d *Decoder) DecodeBool() (bool, error) {
+	initial, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return false, err
+	case initial == majorOther<<5|simpleTrue:
+		return true, nil
+	case initial == majorOther<<5|simpleFalse:
+		return false, nil
+	default:
+		return false, errType
+	}
+}
+
+// DecodeUint decodes an unsigned integer
+func (d *Decoder) DecodeUint() (uint64, error) {
+	return d.definite(majorUint)
+}
+
+// DecodeInt decodes an integer, as long as it fits into an int64
+func (d *Decoder) DecodeInt() (int64, error) {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	arg, indefinite, err := d.head(initial)
+	switch {
+	case err != nil:
+		return 0, err
+	case indefinite || initial>>5 > majorNegInt:
+		return 0, errType
+	case arg > math.MaxInt64:
+		return 0, errOverflow
+	case initial>>5 == majorNegInt:
+		return int64(^arg), nil
+	default:
+		return int64(arg), nil
+	}
+}
+
+// DecodeBigInt decodes an integer of any size, including bignums
+func (d *Decoder) DecodeBigInt() (*big.Int, error) {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	arg, indefinite, err := d.head(initial)
+	switch {
+	case err != nil:
+		return nil, err
+	case indefinite:
+		return nil, errType
+	}
+
+	switch initial >> 5 {
+	case majorUint:
+		return new(big.Int).SetUint64(arg), nil
+	case majorNegInt:
+		return new(big.Int).Not(new(big.Int).SetUint64(arg)), nil
+	case majorTag:
+		if arg == TagPosBignum || arg == TagNegBignum {
+			return d.bignum(arg)
+		}
+	}
+	return nil, errType
+}
+
+// DecodeFloat64 decodes a floating point number of any precision
+func (d *Decoder) DecodeFloat64() (float64, error) {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	if typeOf(initial) != FloatType {
+		return 0, errType
+	}
+
+	arg, _, err := d.head(initial)
+	if err != nil {
+		return 0, err
+	}
+	return float(initial&0x1f, arg), nil
+}
+
+// DecodeBytes decodes a byte string, including an indefinite-length one
+func (d *Decoder) DecodeBytes() ([]byte, error) {
+	b, err := d.stringOf(majorBytes)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+// DecodeBytesNoCopy decodes a byte string without copying it when the reader is
+// backed by a buffer. The returned slice is only valid until the next read and
+// must not be modified.
+func (d *Decoder) DecodeBytesNoCopy() ([]byte, error) {
+	return d.stringOf(majorBytes)
+}
+
+// DecodeString decodes a text string, including an indefinite-length one
+func (d *Decoder) DecodeString() (string, error) {
+	b, err := d.stringOf(majorText)
+	return string(b), err
+}
+
+// DecodeArrayLen decodes the header of an array and returns its number of items,
+// or -1 if the array has an indefinite length and is terminated by a break.
+func (d *Decoder) DecodeArrayLen() (int, error) {
+	return d.collectionOf(majorArray)
+}
+
+// DecodeMapLen decodes the header of a map and returns its number of pairs, or
+// -1 if the map has an indefinite length and is terminated by a break.
+func (d *Decoder) DecodeMapLen() (int, error) {
+	return d.collectionOf(majorMap)
+}
+
+// DecodeBreak decodes the break which terminates an indefinite-length item
+func (d *Decoder) DecodeBreak() error {
+	initial, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return err
+	case initial != codeBreak:
+		return errType
+	default:
+		return nil
+	}
+}
+
+// DecodeTag decodes a tag number, which is followed by its content
+func (d *Decoder) DecodeTag() (uint64, error) {
+	return d.definite(majorTag)
+}
+
+// DecodeTime decodes a standard or an epoch-based date/time, in UTC
+func (d *Decoder) DecodeTime() (time.Time, error) {
+	tag, err := d.DecodeTag()
+	switch {
+	case err != nil:
+		return time.Time{}, err
+	case tag != TagDateTime && tag != TagEpoch:
+		return time.Time{}, errTime
+	}
+
+	content, err := d.Decode()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return toTime(tag, content)
+}
+
+// Skip skips the next item, including all of its content if it is an array, a
+// map or a tag.
+func (d *Decoder) Skip() error {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return err
+	}
+
+	arg, indefinite, err := d.head(initial)
+	if err != nil {
+		return err
+	}
+
+	switch major := initial >> 5; major {
+	case majorBytes, majorText:
+		if !indefinite {
+			return d.skip(arg)
+		}
+
+		return d.chunks(major, func(size int) error {
+			return d.src.SkipN(size)
+		})
+	case majorArray, majorMap:
+		if err := d.enter(); err != nil {
+			return err
+		}
+
+		defer d.leave()
+		return d.each(arg, indefinite, func() error {
+			if major == majorMap {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+			return d.Skip()
+		})
+	case majorTag:
+		if err := d.enter(); err != nil {
+			return err
+		}
+
+		defer d.leave()
+		return d.Skip()
+	case majorOther:
+		if indefinite {
+			return errBreak
+		}
+	}
+	return nil
+}
+
+// --------------------------- Heads ---------------------------
+
+// head reads the argument which follows the initial byte of an item, and reports
+// whether the item has an indefinite length.
+func (d *Decoder) head(initial byte) (uint64, bool, error) {
+	info := initial & 0x1f
+	switch {
+	case info < infoUint8:
+		return uint64(info), false, nil
+	case info <= infoUint64:
+		size := 1 << (info - infoUint8)
+		b, err := d.src.Slice(size)
+		if err != nil {
+			return 0, false, err
+		}
+
+		switch size {
+		case 1:
+			return uint64(b[0]), false, nil
+		case 2:
+			return uint64(binary.BigEndian.Uint16(b)), false, nil
+		case 4:
+			return uint64(binary.BigEndian.Uint32(b)), false, nil
+		default:
+			return binary.BigEndian.Uint64(b), false, nil
+		}
+	case info == infoIndefinite:
+		switch initial >> 5 {
+		case majorBytes, majorText, majorArray, majorMap, majorOther:
+			return 0, true, nil
+		}
+	}
+	return 0, false, errInfo
+}
+
+// definite reads the head of a definite-length item of the expected major type
+func (d *Decoder) definite(major byte) (uint64, error) {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	arg, indefinite, err := d.head(initial)
+	switch {
+	case err != nil:
+		return 0, err
+	case indefinite || initial>>5 != major:
+		return 0, errType
+	default:
+		return arg, nil
+	}
+}
+
+// collectionOf reads the head of an array or a map of the expected major type
+func (d *Decoder) collectionOf(major byte) (int, error) {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	arg, indefinite, err := d.head(initial)
+	switch {
+	case err != nil:
+		return 0, err
+	case initial>>5 != major:
+		return 0, errType
+	case indefinite:
+		return -1, nil
+	case arg > math.MaxInt32:
+		return 0, errLength
+	default:
+		return int(arg), nil
+	}
+}
+
+// stringOf reads a byte or a text string of the expected major type
+func (d *Decoder) stringOf(major byte) ([]byte, error) {
+	initial, err := d.src.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	arg, indefinite, err := d.head(initial)
+	switch {
+	case err != nil:
+		return nil, err
+	case initial>>5 != major:
+		return nil, errType
+	default:
+		return d.bytes(major, arg, indefinite)
+	}
+}
+
+// isBreak checks whether the next item is a break, and consumes it if it is
+func (d *Decoder) isBreak() (bool, error) {
+	b, err := d.src.Peek(1)
+	switch {
+	case len(b) == 0 && err == io.EOF:
+		return false, io.ErrUnexpectedEOF
+	case len(b) == 0:
+		return false, err
+	case b[0] != codeBreak:
+		return false, nil
+	default:
+		return true, d.src.SkipN(1)
+	}
+}
+
+// skip skips the specified number of bytes
+func (d *Decoder) skip(size uint64) error {
+	if size > math.MaxInt32 {
+		return errLength
+	}
+	return d.src.SkipN(int(size))
+}
+
+// --------------------------- Items ---------------------------
+
+// enter increments the nesting of items, up to the maximum depth
+func (d *Decoder) enter() error {
+	if d.depth >= maxDepth {
+		return errDepth
+	}
+
+	d.depth++
+	return nil
+}
+
+// leave decrements the nesting of items
+func (d *Decoder) leave() {
+	d.depth--
+}
+
+// bytes reads the contents of a byte or a text string. The returned slice is only
+// valid until the next read.
+func (d *Decoder) bytes(major byte, size uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		if size > math.MaxInt32 {
+			return nil, errLength
+		}
+		return d.src.Slice(int(size))
+	}
+
+	// Concatenate all of the chunks until the break
+	var out []byte
+	err := d.chunks(major, func(size int) error {
+		b, err := d.src.Slice(size)
+		out = append(out, b...)
+		return err
+	})
+	return out, err
+}
+
+// chunks reads the chunks of an indefinite-length string until the break
+func (d *Decoder) chunks(major byte, fn func(size int) error) error {
+	for {
+		switch done, err := d.isBreak(); {
+		case err != nil:
+			return err
+		case done:
+			return nil
+		}
+
+		initial, err := d.src.ReadUint8()
+		if err != nil {
+			return err
+		}
+
+		arg, indefinite, err := d.head(initial)
+		switch {
+		case err != nil:
+			return err
+		case indefinite || initial>>5 != major:
+			return errChunk
+		case arg > math.MaxInt32:
+			return errLength
+		}
+
+		if err := fn(int(arg)); err != nil {
+			return err
+		}
+	}
+}
+
+// each calls the function for every element of an array or a map, until the
+// specified count or until the break if the collection has an indefinite length.
+func (d *Decoder) each(count uint64, indefinite bool, fn func() error) error {
+	for i := uint64(0); indefinite || i < count; i++ {
+		if indefinite {
+			switch done, err := d.isBreak(); {
+			case err != nil:
+				return err
+			case done:
+				return nil
+			}
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// array reads the items of an array
+func (d *Decoder) array(size uint64, indefinite bool) ([]interface{}, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+
+	defer d.leave()
+	out := make([]interface{}, 0, prealloc(size))
+	err := d.each(size, indefinite, func() error {
+		v, err := d.Decode()
+		out = append(out, v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// dictionary reads the key and value pairs of a map
+func (d *Decoder) dictionary(size uint64, indefinite bool) (map[interface{}]interface{}, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+
+	defer d.leave()
+	out := make(map[interface{}]interface{}, prealloc(size))
+	err := d.each(size, indefinite, func() error {
+		key, err := d.Decode()
+		switch {
+		case err != nil:
+			return err
+		case !hashable(key):
+			return errKey
+		}
+
+		out[key], err = d.Decode()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// tag reads the content of a tag and converts it if the tag is supported
+func (d *Decoder) tag(number uint64) (interface{}, error) {
+	switch number {
+	case TagPosBignum, TagNegBignum:
+		return d.bignum(number)
+	}
+
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+
+	defer d.leave()
+	content, err := d.Decode()
+	switch {
+	case err != nil:
+		return nil, err
+	case number == TagDateTime || number == TagEpoch:
+		return toTime(number, content)
+	default:
+		return Tag{Number: number, Content: content}, nil
+	}
+}
+
+// bignum reads the content of a bignum tag
+func (d *Decoder) bignum(tag uint64) (*big.Int, error) {
+	b, err := d.stringOf(majorBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(big.Int).SetBytes(b)
+	if tag == TagNegBignum {
+		out.Not(out)
+	}
+	return out, nil
+}
+
+// simple converts the argument of a major type 7 item
+func (d *Decoder) simple(info byte, arg uint64) (interface{}, error) {
+	switch info {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull:
+		return nil, nil
+	case simpleFloat16, simpleFloat32, simpleFloat64:
+		return float(info, arg), nil
+	case infoIndefinite:
+		return nil, errBreak
+	case infoUint8:
+		if arg < 32 {
+			return nil, errSimple
+		}
+	}
+	return Simple(arg), nil
+}
+
+// --------------------------- Conversions ---------------------------
+
+// float converts the argument of a floating point number to a float64
+func float(info byte, arg uint64) float64 {
+	switch info {
+	case simpleFloat16:
+		return float64(iostream.Float16frombits(uint16(arg)))
+	case simpleFloat32:
+		return float64(math.Float32frombits(uint32(arg)))
+	default:
+		return math.Float64frombits(arg)
+	}
+}
+
+// toTime converts the content of a date/time tag
+func toTime(tag uint64, content interface{}) (time.Time, error) {
+	if tag == TagDateTime {
+		text, ok := content.(string)
+		if !ok {
+			return time.Time{}, errTime
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return time.Time{}, errTime
+		}
+		return t.UTC(), nil
+	}
+
+	switch v := content.(type) {
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) || math.Abs(v) > 1<<62 {
+			return time.Time{}, errTime
+		}
+
+		sec := math.Floor(v)
+		return time.Unix(int64(sec), int64(math.Round((v-sec)*1e9))).UTC(), nil
+	default:
+		return time.Time{}, errTime
+	}
+}
+
+// hashable returns whether the decoded value can be used as a map key
+func hashable(v interface{}) bool {
+	switch v := v.(type) {
+	case []byte, []interface{}, map[interface{}]interface{}:
+		return false
+	case Tag:
+		return hashable(v.Content)
+	default:
+		return true
+	}
+}
+
+// prealloc returns the capacity to allocate for a collection of the given size
+func prealloc(size uint64) int {
+	if size > maxPrealloc {
+		return maxPrealloc
+	}
+	return int(size)
+}
@@ -0,0 +1,309 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	for _, tc := range fixtures {
+		input, _ := hex.DecodeString(tc.Hex)
+		dec := newDecoder(input)
+		v, err := dec.Decode()
+		assert.NoError(t, err, tc.Hex)
+		assert.Equal(t, int64(len(input)), dec.Offset(), tc.Hex)
+
+		// Re-encode the decoded value, which must produce the same bytes, except
+		// for date/time strings which are re-encoded as epoch-based times
+		if _, ok := v.(time.Time); ok && tc.Hex[:2] == "c0" {
+			continue
+		}
+
+		buffer := bytes.NewBuffer(nil)
+		assert.NoError(t, NewDeterministicEncoder(iostream.NewWriter(buffer)).Encode(v), tc.Hex)
+		assert.Equal(t, tc.Hex, hex.EncodeToString(buffer.Bytes()))
+	}
+}
+
+func TestDecodeValues(t *testing.T) {
+	for _, tc := range []struct {
+		Hex   string
+		Value interface{}
+	}{
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"3b7fffffffffffffff", int64(math.MinInt64)},
+		{"3bffffffffffffffff", bigInt("-18446744073709551616")},
+		{"c249010000000000000000", bigInt("18446744073709551616")},
+		{"f93c00", 1.0},
+		{"f90001", 5.960464477539063e-8},
+		{"c074323031332d30332d32315432303a30343a30305a", time.Unix(1363896240, 0).UTC()},
+		{"c1fb41d452d9ec200000", time.Unix(1363896240, 500000000).UTC()},
+		{"c1f93c00", time.Unix(1, 0).UTC()},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"5fff", []byte{}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9fff", []interface{}{}},
+		{"9f018202039f0405ffff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"bf6346756ef563416d7421ff", map[interface{}]interface{}{"Fun": true, "Amt": int64(-2)}},
+		{"a1c1016161", map[interface{}]interface{}{time.Unix(1, 0).UTC(): "a"}},
+		{"f7", Undefined},
+	} {
+		input, _ := hex.DecodeString(tc.Hex)
+		v, err := newDecoder(input).Decode()
+		assert.NoError(t, err, tc.Hex)
+		assert.Equal(t, tc.Value, v, tc.Hex)
+	}
+}
+
+func TestDecodeTyped(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.EncodeNil())
+	assert.NoError(t, enc.EncodeBool(true))
+	assert.NoError(t, enc.EncodeBool(false))
+	assert.NoError(t, enc.EncodeUint(math.MaxUint64))
+	assert.NoError(t, enc.EncodeInt(math.MinInt64))
+	assert.NoError(t, enc.EncodeInt(42))
+	assert.NoError(t, enc.EncodeBigInt(bigInt("-18446744073709551617")))
+	assert.NoError(t, enc.EncodeBigInt(big.NewInt(-5)))
+	assert.NoError(t, enc.EncodeFloat32(1.5))
+	assert.NoError(t, enc.EncodeString("hello"))
+	assert.NoError(t, enc.EncodeBytes([]byte("world")))
+	assert.NoError(t, enc.EncodeBytes([]byte("view")))
+	assert.NoError(t, enc.EncodeArrayLen(3))
+	assert.NoError(t, enc.EncodeIndefiniteMap())
+	assert.NoError(t, enc.EncodeBreak())
+	assert.NoError(t, enc.EncodeTag(55799))
+	assert.NoError(t, enc.EncodeTime(time.Unix(1, 250000000)))
+
+	for _, src := range []io.Reader{
+		bytes.NewBuffer(buffer.Bytes()),
+		bytes.NewReader(buffer.Bytes()),
+	} {
+		dec := NewDecoder(iostream.NewReader(src))
+		typ, err := dec.PeekType()
+		assert.NoError(t, err)
+		assert.Equal(t, NilType, typ)
+		assert.NoError(t, dec.DecodeNil())
+
+		b, err := dec.DecodeBool()
+		assert.NoError(t, err)
+		assert.True(t, b)
+
+		b, err = dec.DecodeBool()
+		assert.NoError(t, err)
+		assert.False(t, b)
+
+		u, err := dec.DecodeUint()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(math.MaxUint64), u)
+
+		i, err := dec.DecodeInt()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(math.MinInt64), i)
+
+		i, err = dec.DecodeInt()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), i)
+
+		n, err := dec.DecodeBigInt()
+		assert.NoError(t, err)
+		assert.Equal(t, bigInt("-18446744073709551617"), n)
+
+		n, err = dec.DecodeBigInt()
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(-5), n)
+
+		f, err := dec.DecodeFloat64()
+		assert.NoError(t, err)
+		assert.Equal(t, 1.5, f)
+
+		s, err := dec.DecodeString()
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", s)
+
+		bin, err := dec.DecodeBytes()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("world"), bin)
+
+		bin, err = dec.DecodeBytesNoCopy()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("view"), bin)
+
+		size, err := dec.DecodeArrayLen()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, size)
+
+		size, err = dec.DecodeMapLen()
+		assert.NoError(t, err)
+		assert.Equal(t, -1, size)
+
+		typ, err = dec.PeekType()
+		assert.NoError(t, err)
+		assert.Equal(t, BreakType, typ)
+		assert.NoError(t, dec.DecodeBreak())
+
+		tag, err := dec.DecodeTag()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(55799), tag)
+
+		ts, err := dec.DecodeTime()
+		assert.NoError(t, err)
+		assert.Equal(t, time.Unix(1, 250000000).UTC(), ts)
+
+		_, err = dec.PeekType()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestDecodeBigIntTypes(t *testing.T) {
+	for _, tc := range []struct {
+		Hex   string
+		Value *big.Int
+	}{
+		{"1864", big.NewInt(100)},
+		{"3863", big.NewInt(-100)},
+		{"c249010000000000000000", bigInt("18446744073709551616")},
+	} {
+		input, _ := hex.DecodeString(tc.Hex)
+		v, err := newDecoder(input).DecodeBigInt()
+		assert.NoError(t, err)
+		assert.Equal(t, tc.Value, v)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	inputs := []string{"5f42010243030405ff", "7f657374726561646d696e67ff", "9f018202039f0405ffff", "bf61610161629f0203ffff"}
+	for _, tc := range fixtures {
+		inputs = append(inputs, tc.Hex)
+	}
+
+	for _, v := range inputs {
+		input, _ := hex.DecodeString(v + "182a")
+		dec := newDecoder(input)
+		assert.NoError(t, dec.Skip(), v)
+
+		i, err := dec.DecodeInt()
+		assert.NoError(t, err, v)
+		assert.Equal(t, int64(42), i, v)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	decode := map[string]func(*Decoder) error{
+		"Decode":            func(d *Decoder) error { _, err := d.Decode(); return err },
+		"DecodeNil":         func(d *Decoder) error { return d.DecodeNil() },
+		"DecodeBool":        func(d *Decoder) error { _, err := d.DecodeBool(); return err },
+		"DecodeUint":        func(d *Decoder) error { _, err := d.DecodeUint(); return err },
+		"DecodeInt":         func(d *Decoder) error { _, err := d.DecodeInt(); return err },
+		"DecodeBigInt":      func(d *Decoder) error { _, err := d.DecodeBigInt(); return err },
+		"DecodeFloat64":     func(d *Decoder) error { _, err := d.DecodeFloat64(); return err },
+		"DecodeBytes":       func(d *Decoder) error { _, err := d.DecodeBytes(); return err },
+		"DecodeBytesNoCopy": func(d *Decoder) error { _, err := d.DecodeBytesNoCopy(); return err },
+		"DecodeString":      func(d *Decoder) error { _, err := d.DecodeString(); return err },
+		"DecodeArrayLen":    func(d *Decoder) error { _, err := d.DecodeArrayLen(); return err },
+		"DecodeMapLen":      func(d *Decoder) error { _, err := d.DecodeMapLen(); return err },
+		"DecodeBreak":       func(d *Decoder) error { return d.DecodeBreak() },
+		"DecodeTag":         func(d *Decoder) error { _, err := d.DecodeTag(); return err },
+		"DecodeTime":        func(d *Decoder) error { _, err := d.DecodeTime(); return err },
+		"Skip":              func(d *Decoder) error { return d.Skip() },
+	}
+
+	// Empty and malformed inputs must fail for every method
+	for name, fn := range decode {
+		assert.Error(t, fn(newDecoder(nil)), name)
+		assert.Error(t, fn(newDecoder([]byte{0x1c})), name)
+	}
+
+	// Truncated inputs must fail
+	for _, tc := range fixtures {
+		input, _ := hex.DecodeString(tc.Hex)
+		for i := 1; i < len(input); i++ {
+			_, err := newDecoder(input[:i]).Decode()
+			assert.Error(t, err, tc.Hex)
+			assert.Error(t, newDecoder(input[:i]).Skip(), tc.Hex)
+		}
+	}
+
+	// Items of a different type must fail
+	for name, fn := range decode {
+		if name != "Decode" && name != "Skip" && name != "DecodeNil" {
+			assert.Error(t, fn(newDecoder([]byte{0xf6})), name)
+		}
+	}
+	assert.Error(t, decode["DecodeNil"](newDecoder([]byte{0xf5})))
+
+	// Specific errors
+	for _, tc := range []struct {
+		name string
+		hex  string
+	}{
+		{"Decode", "ff"},           // unexpected break
+		{"Decode", "1f"},           // indefinite integer
+		{"Decode", "f818"},         // reserved simple value
+		{"Decode", "5f6161ff"},     // text chunk in a byte string
+		{"Decode", "5f5f4101ffff"}, // nested indefinite chunk
+		{"Decode", "5f"},           // missing break
+		{"Decode", "9f01"},         // missing break
+		{"Decode", "a1820102f6"},   // array as a map key
+		{"Decode", "a1c1f5f6"},     // invalid epoch time
+		{"Decode", "c0f6"},         // invalid date/time string
+		{"Decode", "c06161"},       // invalid date/time string
+		{"Decode", "c1f97c00"},     // infinite epoch time
+		{"Decode", "c2f6"},         // invalid bignum
+		{"Decode", "9bffffffffffffffff"},
+		{"Decode", "5bffffffffffffffff"},
+		{"Decode", "5f5bffffffffffffffffff"},
+		{"Skip", "5bffffffffffffffff"},
+		{"Skip", "5f6161ff"},
+		{"Skip", "9f01"},
+		{"Skip", "ff"},
+		{"DecodeInt", "1bffffffffffffffff"},
+		{"DecodeInt", "3bffffffffffffffff"},
+		{"DecodeBigInt", "c101"},
+		{"DecodeBigInt", "5f"},
+		{"DecodeTime", "c201"},
+		{"DecodeTime", "c1"},
+		{"DecodeArrayLen", "9bffffffffffffffff"},
+		{"DecodeString", "41"},
+	} {
+		input, _ := hex.DecodeString(tc.hex)
+		assert.Error(t, decode[tc.name](newDecoder(input)), "%s %s", tc.name, tc.hex)
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	for _, item := range [][]byte{
+		{0x81},       // array of one item
+		{0x9f},       // indefinite-length array
+		{0xa1, 0x00}, // map with a single key
+		{0xc6},       // unassigned tag
+	} {
+		deep := bytes.Repeat(item, 2*maxDepth)
+		_, err := newDecoder(deep).Decode()
+		assert.Equal(t, errDepth, err, "%x", item)
+		assert.Equal(t, errDepth, newDecoder(deep).Skip(), "%x", item)
+	}
+
+	// Nesting up to the limit is fine
+	dec := newDecoder(append(bytes.Repeat([]byte{0x81}, maxDepth), 0xf6))
+	_, err := dec.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, dec.depth)
+}
+
+func newDecoder(b []byte) *Decoder {
+	return NewDecoder(iostream.NewReader(bytes.NewBuffer(b)))
+}
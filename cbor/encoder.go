@@ -0,0 +1,407 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/kelindar/iostream/internal/convert"
+)
+
+// Encoder represents a CBOR encoder.
+type Encoder struct {
+	out           *iostream.Writer
+	scratch       [9]byte
+	deterministic bool
+}
+
+// NewEncoder creates a new CBOR encoder over the writer. Integers and lengths
+// always use their shortest form, while floating point numbers keep the size of
+// their Go type.
+func NewEncoder(out *iostream.Writer) *Encoder {
+	return &Encoder{
+		out: out,
+	}
+}
+
+// NewDeterministicEncoder creates a new CBOR encoder which follows the core
+// deterministic encoding requirements. Floating point numbers use the shortest
+// form which preserves their value, map keys are sorted by their encoded bytes
+// and indefinite-length items are rejected.
+func NewDeterministicEncoder(out *iostream.Writer) *Encoder {
+	return &Encoder{
+		out:           out,
+		deterministic: true,
+	}
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (e *Encoder) Offset() int64 {
+	return e.out.Offset()
+}
+
+// Encode encodes a Go value. It supports nil, booleans, integers, floats, strings,
+// byte slices, time.Time, *big.Int, Tag, Simple, as well as slices of interface{}
+// and maps with string or interface{} keys that contain any of the supported types.
+func (e *Encoder) Encode(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return e.EncodeNil()
+	case bool:
+		return e.EncodeBool(v)
+	case int:
+		return e.EncodeInt(int64(v))
+	case int8:
+		return e.EncodeInt(int64(v))
+	case int16:
+		return e.EncodeInt(int64(v))
+	case int32:
+		return e.EncodeInt(int64(v))
+	case int64:
+		return e.EncodeInt(v)
+	case uint:
+		return e.EncodeUint(uint64(v))
+	case uint8:
+		return e.EncodeUint(uint64(v))
+	case uint16:
+		return e.EncodeUint(uint64(v))
+	case uint32:
+		return e.EncodeUint(uint64(v))
+	case uint64:
+		return e.EncodeUint(v)
+	case float32:
+		return e.EncodeFloat32(v)
+	case float64:
+		return e.EncodeFloat64(v)
+	case string:
+		return e.EncodeString(v)
+	case []byte:
+		return e.EncodeBytes(v)
+	case time.Time:
+		return e.EncodeTime(v)
+	case *big.Int:
+		return e.EncodeBigInt(v)
+	case Simple:
+		return e.EncodeSimple(v)
+	case Tag:
+		if err := e.EncodeTag(v.Number); err != nil {
+			return err
+		}
+		return e.Encode(v.Content)
+	case []interface{}:
+		if err := e.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		return e.encodeMap(len(v), func(fn func(k, v interface{}) error) error {
+			for key, item := range v {
+				if err := fn(key, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case map[interface{}]interface{}:
+		return e.encodeMap(len(v), func(fn func(k, v interface{}) error) error {
+			for key, item := range v {
+				if err := fn(key, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	default:
+		return errUnsupported
+	}
+}
+
+// EncodeNil encodes a null value
+func (e *Encoder) EncodeNil() error {
+	return e.out.WriteUint8(majorOther<<5 | simpleNull)
+}
+
+// EncodeBool encodes a boolean value
+func (e *Encoder) EncodeBool(v bool) error {
+	if v {
+		return e.out.WriteUint8(majorOther<<5 | simpleTrue)
+	}
+	return e.out.WriteUint8(majorOther<<5 | simpleFalse)
+}
+
+// EncodeSimple encodes a simple value. The values from 24 to 31 are reserved
+// and can not be encoded.
+func (e *Encoder) EncodeSimple(v Simple) error {
+	switch {
+	case v < infoUint8:
+		return e.out.WriteUint8(majorOther<<5 | uint8(v))
+	case v < 32:
+		return errSimple
+	default:
+		return e.writeHead(majorOther, uint64(v))
+	}
+}
+
+// EncodeUint encodes an unsigned integer
+func (e *Encoder) EncodeUint(v uint64) error {
+	return e.writeHead(majorUint, v)
+}
+
+// EncodeInt encodes a signed integer
+func (e *Encoder) EncodeInt(v int64) error {
+	if v >= 0 {
+		return e.writeHead(majorUint, uint64(v))
+	}
+	return e.writeHead(majorNegInt, uint64(^v))
+}
+
+// EncodeBigInt encodes an arbitrary-precision integer. It is encoded as a plain
+// integer if it fits into 64 bits, or as a bignum otherwise. A nil integer is
+// encoded as null.
+func (e *Encoder) EncodeBigInt(v *big.Int) error {
+	if v == nil {
+		return e.EncodeNil()
+	}
+
+	tag := uint64(TagPosBignum)
+	if v.Sign() < 0 {
+		tag = TagNegBignum
+		v = new(big.Int).Not(v) // -1 - v
+	}
+
+	switch {
+	case v.IsUint64() && tag == TagPosBignum:
+		return e.writeHead(majorUint, v.Uint64())
+	case v.IsUint64():
+		return e.writeHead(majorNegInt, v.Uint64())
+	}
+
+	if err := e.EncodeTag(tag); err != nil {
+		return err
+	}
+	return e.EncodeBytes(v.Bytes())
+}
+
+// EncodeFloat32 encodes a single-precision floating point number
+func (e *Encoder) EncodeFloat32(v float32) error {
+	if e.deterministic {
+		return e.EncodeFloat64(float64(v))
+	}
+
+	e.scratch[0] = majorOther<<5 | simpleFloat32
+	binary.BigEndian.PutUint32(e.scratch[1:], math.Float32bits(v))
+	return e.write(e.scratch[:5])
+}
+
+// EncodeFloat64 encodes a double-precision floating point number. When the
+// encoder is deterministic, the shortest form which preserves the value is used.
+func (e *Encoder) EncodeFloat64(v float64) error {
+	if e.deterministic {
+		switch f := float32(v); {
+		case v != v:
+			return e.writeFloat16(0x7e00)
+		case float64(f) != v:
+		default:
+			if h, ok := float16bits(f); ok {
+				return e.writeFloat16(h)
+			}
+
+			e.scratch[0] = majorOther<<5 | simpleFloat32
+			binary.BigEndian.PutUint32(e.scratch[1:], math.Float32bits(f))
+			return e.write(e.scratch[:5])
+		}
+	}
+
+	e.scratch[0] = majorOther<<5 | simpleFloat64
+	binary.BigEndian.PutUint64(e.scratch[1:], math.Float64bits(v))
+	return e.write(e.scratch[:9])
+}
+
+// EncodeBytes encodes a byte string
+func (e *Encoder) EncodeBytes(v []byte) error {
+	if err := e.writeHead(majorBytes, uint64(len(v))); err != nil {
+		return err
+	}
+	return e.write(v)
+}
+
+// EncodeString encodes a UTF-8 text string
+func (e *Encoder) EncodeString(v string) error {
+	if err := e.writeHead(majorText, uint64(len(v))); err != nil {
+		return err
+	}
+	return e.write(convert.ToBytes(v))
+}
+
+// EncodeArrayLen encodes the header of an array, which must be followed by the
+// specified number of items.
+func (e *Encoder) EncodeArrayLen(n int) error {
+	return e.writeHead(majorArray, uint64(n))
+}
+
+// EncodeMapLen encodes the header of a map, which must be followed by the
+// specified number of key and value pairs. When the encoder is deterministic,
+// the keys must be written in the order of their encoded bytes by the caller.
+func (e *Encoder) EncodeMapLen(n int) error {
+	return e.writeHead(majorMap, uint64(n))
+}
+
+// EncodeTag encodes a tag number, which must be followed by its content.
+func (e *Encoder) EncodeTag(v uint64) error {
+	return e.writeHead(majorTag, v)
+}
+
+// EncodeTime encodes a time as an epoch-based date/time. It is encoded as an
+// integer if the time has no fractional seconds, or as a floating point number
+// otherwise, which is rounded to the precision of a float64.
+func (e *Encoder) EncodeTime(v time.Time) error {
+	if err := e.EncodeTag(TagEpoch); err != nil {
+		return err
+	}
+
+	if nsec := v.Nanosecond(); nsec != 0 {
+		return e.EncodeFloat64(float64(v.Unix()) + float64(nsec)/1e9)
+	}
+	return e.EncodeInt(v.Unix())
+}
+
+// --------------------------- Indefinite Length ---------------------------
+
+// EncodeIndefiniteBytes starts a byte string of an indefinite length. It must be
+// followed by definite-length byte strings and terminated with a break.
+func (e *Encoder) EncodeIndefiniteBytes() error {
+	return e.writeIndefinite(majorBytes)
+}
+
+// EncodeIndefiniteString starts a text string of an indefinite length. It must be
+// followed by definite-length text strings and terminated with a break.
+func (e *Encoder) EncodeIndefiniteString() error {
+	return e.writeIndefinite(majorText)
+}
+
+// EncodeIndefiniteArray starts an array of an indefinite length, which must be
+// terminated with a break.
+func (e *Encoder) EncodeIndefiniteArray() error {
+	return e.writeIndefinite(majorArray)
+}
+
+// EncodeIndefiniteMap starts a map of an indefinite length, which must be
+// terminated with a break.
+func (e *Encoder) EncodeIndefiniteMap() error {
+	return e.writeIndefinite(majorMap)
+}
+
+// EncodeBreak terminates an indefinite-length item
+func (e *Encoder) EncodeBreak() error {
+	return e.writeIndefinite(majorOther)
+}
+
+// --------------------------- Headers ---------------------------
+
+// write writes the contents of p into the underlying writer
+func (e *Encoder) write(p []byte) error {
+	_, err := e.out.Write(p)
+	return err
+}
+
+// writeHead writes the initial byte of an item along with its argument, using
+// the shortest form which can hold the argument.
+func (e *Encoder) writeHead(major byte, arg uint64) error {
+	major <<= 5
+	switch {
+	case arg < infoUint8:
+		return e.out.WriteUint8(major | byte(arg))
+	case arg <= math.MaxUint8:
+		e.scratch[0], e.scratch[1] = major|infoUint8, byte(arg)
+		return e.write(e.scratch[:2])
+	case arg <= math.MaxUint16:
+		e.scratch[0] = major | infoUint16
+		binary.BigEndian.PutUint16(e.scratch[1:], uint16(arg))
+		return e.write(e.scratch[:3])
+	case arg <= math.MaxUint32:
+		e.scratch[0] = major | infoUint32
+		binary.BigEndian.PutUint32(e.scratch[1:], uint32(arg))
+		return e.write(e.scratch[:5])
+	default:
+		e.scratch[0] = major | infoUint64
+		binary.BigEndian.PutUint64(e.scratch[1:], arg)
+		return e.write(e.scratch[:9])
+	}
+}
+
+// writeIndefinite writes the initial byte of an indefinite-length item
+func (e *Encoder) writeIndefinite(major byte) error {
+	if e.deterministic {
+		return errIndefinite
+	}
+	return e.out.WriteUint8(major<<5 | infoIndefinite)
+}
+
+// writeFloat16 writes a half-precision floating point number
+func (e *Encoder) writeFloat16(v uint16) error {
+	e.scratch[0] = majorOther<<5 | simpleFloat16
+	binary.BigEndian.PutUint16(e.scratch[1:], v)
+	return e.write(e.scratch[:3])
+}
+
+// encodeMap encodes a map, given a function which iterates over its pairs. When
+// the encoder is deterministic, the keys are sorted by their encoded bytes.
+func (e *Encoder) encodeMap(size int, each func(fn func(k, v interface{}) error) error) error {
+	if err := e.EncodeMapLen(size); err != nil {
+		return err
+	}
+
+	if !e.deterministic {
+		return each(func(k, v interface{}) error {
+			if err := e.Encode(k); err != nil {
+				return err
+			}
+			return e.Encode(v)
+		})
+	}
+
+	// Encode every key separately so we can sort them
+	type entry struct {
+		key   []byte
+		value interface{}
+	}
+
+	entries := make([]entry, 0, size)
+	if err := each(func(k, v interface{}) error {
+		buffer := bytes.NewBuffer(nil)
+		if err := NewDeterministicEncoder(iostream.NewWriter(buffer)).Encode(k); err != nil {
+			return err
+		}
+
+		entries = append(entries, entry{key: buffer.Bytes(), value: v})
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	for _, entry := range entries {
+		if err := e.write(entry.key); err != nil {
+			return err
+		}
+		if err := e.Encode(entry.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
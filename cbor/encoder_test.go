@@ -0,0 +1,227 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+// Examples of encoded items, from the appendix A of RFC 8949
+var fixtures = []struct {
+	Value interface{}
+	Hex   string
+}{
+	{0, "00"},
+	{1, "01"},
+	{10, "0a"},
+	{23, "17"},
+	{24, "1818"},
+	{25, "1819"},
+	{100, "1864"},
+	{1000, "1903e8"},
+	{1000000, "1a000f4240"},
+	{1000000000000, "1b000000e8d4a51000"},
+	{uint64(18446744073709551615), "1bffffffffffffffff"},
+	{bigInt("18446744073709551616"), "c249010000000000000000"},
+	{bigInt("-18446744073709551616"), "3bffffffffffffffff"},
+	{bigInt("-18446744073709551617"), "c349010000000000000000"},
+	{-1, "20"},
+	{-10, "29"},
+	{-100, "3863"},
+	{-1000, "3903e7"},
+	{0.0, "f90000"},
+	{math.Copysign(0, -1), "f98000"},
+	{1.0, "f93c00"},
+	{1.1, "fb3ff199999999999a"},
+	{1.5, "f93e00"},
+	{65504.0, "f97bff"},
+	{100000.0, "fa47c35000"},
+	{3.4028234663852886e+38, "fa7f7fffff"},
+	{1.0e+300, "fb7e37e43c8800759c"},
+	{5.960464477539063e-8, "f90001"},
+	{0.00006103515625, "f90400"},
+	{-4.0, "f9c400"},
+	{-4.1, "fbc010666666666666"},
+	{math.Inf(1), "f97c00"},
+	{math.NaN(), "f97e00"},
+	{math.Inf(-1), "f9fc00"},
+	{false, "f4"},
+	{true, "f5"},
+	{nil, "f6"},
+	{Undefined, "f7"},
+	{Simple(16), "f0"},
+	{Simple(255), "f8ff"},
+	{Tag{Number: 0, Content: "2013-03-21T20:04:00Z"}, "c074323031332d30332d32315432303a30343a30305a"},
+	{time.Unix(1363896240, 0), "c11a514b67b0"},
+	{time.Unix(1363896240, 500000000), "c1fb41d452d9ec200000"},
+	{Tag{Number: 23, Content: []byte{1, 2, 3, 4}}, "d74401020304"},
+	{Tag{Number: 24, Content: []byte{0x64, 0x49, 0x45, 0x54, 0x46}}, "d818456449455446"},
+	{Tag{Number: 32, Content: "http://www.example.com"}, "d82076687474703a2f2f7777772e6578616d706c652e636f6d"},
+	{[]byte{}, "40"},
+	{[]byte{1, 2, 3, 4}, "4401020304"},
+	{"", "60"},
+	{"a", "6161"},
+	{"IETF", "6449455446"},
+	{"\"\\", "62225c"},
+	{"ü", "62c3bc"},
+	{"水", "63e6b0b4"},
+	{[]interface{}{}, "80"},
+	{[]interface{}{1, 2, 3}, "83010203"},
+	{[]interface{}{1, []interface{}{2, 3}, []interface{}{4, 5}}, "8301820203820405"},
+	{series(1, 25), "98190102030405060708090a0b0c0d0e0f101112131415161718181819"},
+	{map[interface{}]interface{}{}, "a0"},
+	{map[interface{}]interface{}{1: 2, 3: 4}, "a201020304"},
+	{map[string]interface{}{"a": 1, "b": []interface{}{2, 3}}, "a26161016162820203"},
+	{[]interface{}{"a", map[string]interface{}{"b": "c"}}, "826161a161626163"},
+	{map[string]interface{}{"a": "A", "b": "B", "c": "C", "d": "D", "e": "E"}, "a56161614161626142616361436164614461656145"},
+}
+
+func TestEncodeDeterministic(t *testing.T) {
+	for _, tc := range fixtures {
+		buffer := bytes.NewBuffer(nil)
+		enc := NewDeterministicEncoder(iostream.NewWriter(buffer))
+		assert.NoError(t, enc.Encode(tc.Value), tc.Hex)
+		assert.Equal(t, tc.Hex, hex.EncodeToString(buffer.Bytes()))
+		assert.Equal(t, int64(buffer.Len()), enc.Offset())
+	}
+}
+
+func TestEncodeSortedKeys(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewDeterministicEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.Encode(map[interface{}]interface{}{
+		"aa": 0, "b": 0, 100: 0, -1: 0, 10: 0, false: 0,
+	}))
+
+	// Keys sorted by their encoded bytes: 10, 100, -1, "b", "aa", false
+	assert.Equal(t, "a60a00186400200061620062616100f400", hex.EncodeToString(buffer.Bytes()))
+}
+
+func TestEncodeFloats(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.EncodeFloat32(1.0))
+	assert.NoError(t, enc.EncodeFloat64(1.0))
+	assert.Equal(t, "fa3f800000fb3ff0000000000000", hex.EncodeToString(buffer.Bytes()))
+
+	buffer.Reset()
+	enc = NewDeterministicEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.EncodeFloat32(1.0))
+	assert.NoError(t, enc.EncodeFloat32(100000.0))
+	assert.Equal(t, "f93c00fa47c35000", hex.EncodeToString(buffer.Bytes()))
+}
+
+func TestEncodeIndefinite(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+
+	// (_ h'0102', h'030405')
+	assert.NoError(t, enc.EncodeIndefiniteBytes())
+	assert.NoError(t, enc.EncodeBytes([]byte{1, 2}))
+	assert.NoError(t, enc.EncodeBytes([]byte{3, 4, 5}))
+	assert.NoError(t, enc.EncodeBreak())
+
+	// (_ "strea", "ming")
+	assert.NoError(t, enc.EncodeIndefiniteString())
+	assert.NoError(t, enc.EncodeString("strea"))
+	assert.NoError(t, enc.EncodeString("ming"))
+	assert.NoError(t, enc.EncodeBreak())
+
+	// [_ 1, [2, 3], [_ 4, 5]]
+	assert.NoError(t, enc.EncodeIndefiniteArray())
+	assert.NoError(t, enc.Encode(1))
+	assert.NoError(t, enc.Encode([]interface{}{2, 3}))
+	assert.NoError(t, enc.EncodeIndefiniteArray())
+	assert.NoError(t, enc.Encode(4))
+	assert.NoError(t, enc.Encode(5))
+	assert.NoError(t, enc.EncodeBreak())
+	assert.NoError(t, enc.EncodeBreak())
+
+	// {_ "a": 1}
+	assert.NoError(t, enc.EncodeIndefiniteMap())
+	assert.NoError(t, enc.Encode("a"))
+	assert.NoError(t, enc.Encode(1))
+	assert.NoError(t, enc.EncodeBreak())
+
+	assert.Equal(t, "5f42010243030405ff"+"7f657374726561646d696e67ff"+"9f018202039f0405ffff"+"bf616101ff",
+		hex.EncodeToString(buffer.Bytes()))
+}
+
+func TestEncodeErrors(t *testing.T) {
+	enc := NewDeterministicEncoder(iostream.NewWriter(bytes.NewBuffer(nil)))
+	assert.Error(t, enc.EncodeIndefiniteBytes())
+	assert.Error(t, enc.EncodeIndefiniteString())
+	assert.Error(t, enc.EncodeIndefiniteArray())
+	assert.Error(t, enc.EncodeIndefiniteMap())
+	assert.Error(t, enc.EncodeBreak())
+	assert.Error(t, enc.EncodeSimple(24))
+	assert.Error(t, enc.Encode(struct{}{}))
+	assert.Error(t, enc.Encode([]interface{}{struct{}{}}))
+	assert.Error(t, enc.Encode(Tag{Number: 1, Content: struct{}{}}))
+	assert.Error(t, enc.Encode(map[string]interface{}{"a": struct{}{}}))
+	assert.Error(t, enc.Encode(map[interface{}]interface{}{struct{}{}: 1}))
+
+	enc = NewEncoder(iostream.NewWriter(bytes.NewBuffer(nil)))
+	assert.Error(t, enc.Encode(map[string]interface{}{"a": struct{}{}}))
+	assert.Error(t, enc.Encode(map[interface{}]interface{}{struct{}{}: 1}))
+}
+
+func TestEncodeTypes(t *testing.T) {
+	for _, v := range []interface{}{
+		int(1), int8(1), int16(1), int32(1), int64(1),
+		uint(1), uint8(1), uint16(1), uint32(1), uint64(1),
+		big.NewInt(1),
+	} {
+		buffer := bytes.NewBuffer(nil)
+		assert.NoError(t, NewEncoder(iostream.NewWriter(buffer)).Encode(v))
+		assert.Equal(t, []byte{0x01}, buffer.Bytes())
+	}
+}
+
+func TestEncodeNilBigInt(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.EncodeBigInt(nil))
+	assert.NoError(t, enc.Encode((*big.Int)(nil)))
+	assert.Equal(t, []byte{0xf6, 0xf6}, buffer.Bytes())
+}
+
+func TestFloat16(t *testing.T) {
+	for i := 0; i < 1<<16; i++ {
+		v := float64(iostream.Float16frombits(uint16(i)))
+		if math.IsNaN(v) {
+			continue
+		}
+
+		h, ok := float16bits(float32(v))
+		assert.True(t, ok)
+		assert.Equal(t, uint16(i), h)
+	}
+
+	for _, v := range []float32{1e-8, 65520, 1.0009765, 3e-5 + 1e-9} {
+		_, ok := float16bits(v)
+		assert.False(t, ok, v)
+	}
+}
+
+func bigInt(v string) *big.Int {
+	out, _ := new(big.Int).SetString(v, 10)
+	return out
+}
+
+func series(from, to int) []interface{} {
+	var out []interface{}
+	for i := from; i <= to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
@@ -0,0 +1,170 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelindar/iostream"
+)
+
+const (
+	maxItems  = 8  // The maximum number of slice elements to print
+	maxString = 64 // The maximum number of characters of a string to print
+)
+
+// entry represents a decoded value along with its location in the input
+type entry struct {
+	Label  string      // The layout field and its repetition
+	Offset int64       // The offset of the value in the input
+	Size   int         // The number of bytes of the encoded value
+	Value  interface{} // The decoded value
+	Err    error       // The error which stopped the decoding
+}
+
+// decode decodes the input according to the layout. It stops at the first error,
+// in which case the last entry carries it.
+func decode(data []byte, layout []field) []entry {
+	r := iostream.NewReader(bytes.NewBuffer(data))
+	out := make([]entry, 0, len(layout))
+	for i, f := range layout {
+		for n := 0; f.Repeat == untilEnd || n < f.Repeat; n++ {
+			offset := r.Offset()
+			if f.Repeat == untilEnd && offset >= int64(len(data)) {
+				break
+			}
+
+			label := fmt.Sprintf("#%d %s", i, f.Type)
+			if f.Repeat != 1 {
+				label += fmt.Sprintf("[%d]", n)
+			}
+
+			value, err := f.decode(r)
+			if err != nil {
+				return append(out, entry{Label: label, Offset: offset, Err: err})
+			}
+
+			out = append(out, entry{
+				Label:  label,
+				Offset: offset,
+				Size:   int(r.Offset() - offset),
+				Value:  value,
+			})
+		}
+	}
+	return out
+}
+
+// --------------------------- Printer ---------------------------
+
+// printer prints an annotated hex dump
+type printer struct {
+	out   io.Writer
+	width int // The number of bytes per line
+	lines int // The maximum number of lines per value, or zero for no limit
+}
+
+// Print prints the decoded entries followed by the bytes which were not decoded,
+// and returns the decoding error, if any.
+func (p *printer) Print(data []byte, entries []entry) error {
+	fmt.Fprintf(p.out, "%-8s  %-*s  %-24s %s\n", "offset", p.width*3-1, "bytes", "field", "value")
+
+	end := int64(0)
+	for _, e := range entries {
+		if e.Err != nil {
+			context := data[e.Offset:]
+			if len(context) > p.width {
+				context = context[:p.width]
+			}
+
+			p.row(e.Offset, context, e.Label, "error: "+e.Err.Error())
+			fmt.Fprintf(p.out, "decoding failed at offset %d (0x%x), %d bytes left\n",
+				e.Offset, e.Offset, int64(len(data))-e.Offset)
+			return fmt.Errorf("%s at offset %d: %w", e.Label, e.Offset, e.Err)
+		}
+
+		p.row(e.Offset, data[e.Offset:e.Offset+int64(e.Size)], e.Label, format(e.Value))
+		end = e.Offset + int64(e.Size)
+	}
+
+	if rest := data[end:]; len(rest) > 0 {
+		p.row(end, rest, "(trailing)", fmt.Sprintf("%d bytes not described by the layout", len(rest)))
+	}
+	return nil
+}
+
+// row prints the bytes of a single value, wrapped over several lines
+func (p *printer) row(offset int64, b []byte, label, value string) {
+	for line := 0; line == 0 || len(b) > 0; line++ {
+		if p.lines > 0 && line == p.lines {
+			fmt.Fprintf(p.out, "%-8s  ... %d more bytes\n", "", len(b))
+			return
+		}
+
+		chunk := b
+		if len(chunk) > p.width {
+			chunk = chunk[:p.width]
+		}
+
+		text := fmt.Sprintf("%08x  %-*s  %-24s %s", offset, p.width*3-1, hexOf(chunk), label, value)
+		fmt.Fprintln(p.out, strings.TrimRight(text, " "))
+		offset += int64(len(chunk))
+		b = b[len(chunk):]
+		label, value = "", ""
+	}
+}
+
+// hexOf returns the bytes as space-separated hexadecimal pairs
+func hexOf(b []byte) string {
+	var sb strings.Builder
+	for i := range b {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(hex.EncodeToString(b[i : i+1]))
+	}
+	return sb.String()
+}
+
+// format returns a short, human-readable representation of a decoded value
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if len(v) > maxString {
+			return strconv.Quote(v[:maxString]) + "..."
+		}
+		return strconv.Quote(v)
+	case []byte:
+		if len(v) > maxString {
+			return fmt.Sprintf("len=%d %x...", len(v), v[:maxString])
+		}
+		return fmt.Sprintf("len=%d %x", len(v), v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return fmt.Sprint(v)
+	}
+
+	items := make([]string, 0, maxItems+1)
+	for i := 0; i < rv.Len() && i < maxItems; i++ {
+		items = append(items, format(rv.Index(i).Interface()))
+	}
+
+	if rv.Len() > maxItems {
+		items = append(items, "...")
+	}
+	return fmt.Sprintf("len=%d [%s]", rv.Len(), strings.Join(items, " "))
+}
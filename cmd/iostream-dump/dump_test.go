@@ -0,0 +1,186 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSnapshot writes a small snapshot used by the tests
+func newSnapshot() []byte {
+	buffer := bytes.NewBuffer(nil)
+	w := iostream.NewWriter(buffer)
+	w.WriteUvarint(300)
+	w.WriteString("hello")
+	w.WriteUint32s([]uint32{1, 2, 3})
+	w.WriteUint32s(make([]uint32, 20))
+	return buffer.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	layout, err := parseLayout("uvarint string uint32s[..]")
+	assert.NoError(t, err)
+
+	entries := decode(newSnapshot(), layout)
+	assert.Equal(t, []entry{
+		{Label: "#0 uvarint", Offset: 0, Size: 2, Value: uint64(300)},
+		{Label: "#1 string", Offset: 2, Size: 6, Value: "hello"},
+		{Label: "#2 uint32s[0]", Offset: 8, Size: 13, Value: []uint32{1, 2, 3}},
+		{Label: "#2 uint32s[1]", Offset: 21, Size: 81, Value: make([]uint32, 20)},
+	}, entries)
+}
+
+func TestDecodeError(t *testing.T) {
+	layout, err := parseLayout("uvarint string uint32s[2] string")
+	assert.NoError(t, err)
+
+	entries := decode(append(newSnapshot(), 5, 'a'), layout)
+	assert.Len(t, entries, 5)
+	assert.Equal(t, "#3 string", entries[4].Label)
+	assert.Equal(t, int64(102), entries[4].Offset)
+	assert.Error(t, entries[4].Err)
+}
+
+func TestDecodeCorruptLength(t *testing.T) {
+	for _, name := range typeNames() {
+		layout, err := parseLayout(name)
+		assert.NoError(t, err)
+
+		for _, input := range [][]byte{
+			{0xff, 0xff, 0xff, 0xff, 0x0f},
+			{0x80, 0x80, 0x80, 0x80, 0x80, 0x01},
+			{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+			{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+			{0x01, 0xff, 0xff, 0xff, 0xff, 0x0f},
+			{0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		} {
+			input = append(input, make([]byte, 16)...)
+			assert.NotPanics(t, func() { decode(input, layout) }, "%s %x", name, input)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	data := newSnapshot()
+	layout, _ := parseLayout("uvarint string uint32s[..]")
+
+	out := bytes.NewBuffer(nil)
+	dump := &printer{out: out, width: 8, lines: 2}
+	assert.NoError(t, dump.Print(data, decode(data, layout)))
+	assert.Equal(t, strings.Join([]string{
+		"offset    bytes                    field                    value",
+		"00000000  ac 02                    #0 uvarint               300",
+		"00000002  05 68 65 6c 6c 6f        #1 string                \"hello\"",
+		"00000008  03 01 00 00 00 02 00 00  #2 uint32s[0]            len=3 [1 2 3]",
+		"00000010  00 03 00 00 00",
+		"00000015  14 00 00 00 00 00 00 00  #2 uint32s[1]            len=20 [0 0 0 0 0 0 0 0 ...]",
+		"0000001d  00 00 00 00 00 00 00 00",
+		"          ... 65 more bytes",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestPrintError(t *testing.T) {
+	data := append(newSnapshot(), 0xff)
+	layout, _ := parseLayout("uvarint string uint32s uint32s uint16")
+
+	out := bytes.NewBuffer(nil)
+	dump := &printer{out: out, width: 16}
+	err := dump.Print(data, decode(data, layout))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "#4 uint16 at offset 102")
+	assert.Contains(t, out.String(), "00000066  ff")
+	assert.Contains(t, out.String(), "decoding failed at offset 102 (0x66), 1 bytes left")
+}
+
+func TestPrintTrailing(t *testing.T) {
+	data := newSnapshot()
+	layout, _ := parseLayout("uvarint")
+
+	out := bytes.NewBuffer(nil)
+	dump := &printer{out: out, width: 16, lines: 1}
+	assert.NoError(t, dump.Print(data, decode(data, layout)))
+	assert.Contains(t, out.String(), "00000002  05 68 65 6c 6c 6f 03 01 00 00 00 02 00 00 00 03  (trailing)")
+	assert.Contains(t, out.String(), "100 bytes not described by the layout")
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		expect string
+	}{
+		{uint64(1), "1"},
+		{true, "true"},
+		{"a\n", `"a\n"`},
+		{strings.Repeat("a", 70), `"` + strings.Repeat("a", 64) + `"...`},
+		{[]byte{1, 0xff}, "len=2 01ff"},
+		{make([]byte, 70), "len=70 " + strings.Repeat("00", 64) + "..."},
+		{time.Unix(1, 5).UTC(), "1970-01-01T00:00:01.000000005Z"},
+		{time.Second, "1s"},
+		{big.NewInt(-5), "-5"},
+		{[]string{"a", "b"}, `len=2 ["a" "b"]`},
+		{[]float32{}, "len=0 []"},
+		{[]bool{true, false}, "len=2 [true false]"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expect, format(tc.value))
+	}
+}
+
+func TestRun(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "snapshot.bin")
+	assert.NoError(t, os.WriteFile(file, newSnapshot(), 0644))
+
+	stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	assert.Equal(t, 0, run([]string{file, "uvarint", "string", "uint32s[..]"}, nil, stdout, stderr))
+	assert.Contains(t, stdout.String(), `#1 string                "hello"`)
+	assert.Empty(t, stderr.String())
+
+	stdout.Reset()
+	stdin := bytes.NewBuffer(newSnapshot()[:5])
+	assert.Equal(t, 1, run([]string{"-width", "4", "-", "uvarint string"}, stdin, stdout, stderr))
+	assert.Contains(t, stdout.String(), "decoding failed at offset 2 (0x2), 3 bytes left")
+	assert.Contains(t, stderr.String(), "#1 string at offset 2")
+}
+
+func TestRunCorruptLength(t *testing.T) {
+	input := make([]byte, 5<<10)
+	copy(input, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01}) // 1<<36 elements
+	file := filepath.Join(t.TempDir(), "corrupt.bin")
+	assert.NoError(t, os.WriteFile(file, input, 0644))
+
+	for _, layout := range []string{"rleint64s", "int64s", "uvarints", "strings"} {
+		stdout, stderr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		assert.Equal(t, 1, run([]string{file, layout}, nil, stdout, stderr), layout)
+		assert.Contains(t, stdout.String(), "decoding failed at offset 0", layout)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"file"},
+		{"-width", "0", "file", "uvarint"},
+		{"-unknown", "file", "uvarint"},
+		{"file", "unknown"},
+	} {
+		stderr := bytes.NewBuffer(nil)
+		assert.Equal(t, 2, run(args, nil, bytes.NewBuffer(nil), stderr), "%v", args)
+		assert.NotEmpty(t, stderr.String())
+	}
+
+	stderr := bytes.NewBuffer(nil)
+	assert.Equal(t, 1, run([]string{filepath.Join(t.TempDir(), "missing"), "uvarint"}, nil, nil, stderr))
+	assert.NotEmpty(t, stderr.String())
+}
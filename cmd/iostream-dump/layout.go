@@ -0,0 +1,161 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kelindar/iostream"
+)
+
+// untilEnd is the repeat count of a field which repeats until the end of input
+const untilEnd = -1
+
+// decodeFunc decodes a single value of a field
+type decodeFunc func(r *iostream.Reader) (interface{}, error)
+
+// decoders maps the type names of a layout to their reader methods
+var decoders = map[string]decodeFunc{
+	"uvarint":           func(r *iostream.Reader) (interface{}, error) { return r.ReadUvarint() },
+	"varint":            func(r *iostream.Reader) (interface{}, error) { return r.ReadVarint() },
+	"uint8":             func(r *iostream.Reader) (interface{}, error) { return r.ReadUint8() },
+	"uint16":            func(r *iostream.Reader) (interface{}, error) { return r.ReadUint16() },
+	"uint32":            func(r *iostream.Reader) (interface{}, error) { return r.ReadUint32() },
+	"uint64":            func(r *iostream.Reader) (interface{}, error) { return r.ReadUint64() },
+	"uint":              func(r *iostream.Reader) (interface{}, error) { return r.ReadUint() },
+	"uint128":           readUint128,
+	"int8":              func(r *iostream.Reader) (interface{}, error) { return r.ReadInt8() },
+	"int16":             func(r *iostream.Reader) (interface{}, error) { return r.ReadInt16() },
+	"int32":             func(r *iostream.Reader) (interface{}, error) { return r.ReadInt32() },
+	"int64":             func(r *iostream.Reader) (interface{}, error) { return r.ReadInt64() },
+	"int":               func(r *iostream.Reader) (interface{}, error) { return r.ReadInt() },
+	"float16":           func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat16() },
+	"bfloat16":          func(r *iostream.Reader) (interface{}, error) { return r.ReadBFloat16() },
+	"float32":           func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat32() },
+	"float64":           func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat64() },
+	"bool":              func(r *iostream.Reader) (interface{}, error) { return r.ReadBool() },
+	"string":            func(r *iostream.Reader) (interface{}, error) { return r.ReadString() },
+	"bytes":             func(r *iostream.Reader) (interface{}, error) { return r.ReadBytes() },
+	"time":              func(r *iostream.Reader) (interface{}, error) { return r.ReadTime() },
+	"duration":          func(r *iostream.Reader) (interface{}, error) { return r.ReadDuration() },
+	"bigint":            func(r *iostream.Reader) (interface{}, error) { return r.ReadBigInt() },
+	"bigfloat":          func(r *iostream.Reader) (interface{}, error) { return r.ReadBigFloat() },
+	"bigrat":            func(r *iostream.Reader) (interface{}, error) { return r.ReadBigRat() },
+	"uvarints":          func(r *iostream.Reader) (interface{}, error) { return r.ReadUvarints() },
+	"varints":           func(r *iostream.Reader) (interface{}, error) { return r.ReadVarints() },
+	"deltauvarints":     func(r *iostream.Reader) (interface{}, error) { return r.ReadDeltaUvarints() },
+	"deltavarints":      func(r *iostream.Reader) (interface{}, error) { return r.ReadDeltaVarints() },
+	"uint8s":            func(r *iostream.Reader) (interface{}, error) { return r.ReadUint8s() },
+	"uint16s":           func(r *iostream.Reader) (interface{}, error) { return r.ReadUint16s() },
+	"uint32s":           func(r *iostream.Reader) (interface{}, error) { return r.ReadUint32s() },
+	"uint64s":           func(r *iostream.Reader) (interface{}, error) { return r.ReadUint64s() },
+	"uints":             func(r *iostream.Reader) (interface{}, error) { return r.ReadUints() },
+	"packeduint32s":     func(r *iostream.Reader) (interface{}, error) { return r.ReadPackedUint32s() },
+	"int8s":             func(r *iostream.Reader) (interface{}, error) { return r.ReadInt8s() },
+	"int16s":            func(r *iostream.Reader) (interface{}, error) { return r.ReadInt16s() },
+	"int32s":            func(r *iostream.Reader) (interface{}, error) { return r.ReadInt32s() },
+	"int64s":            func(r *iostream.Reader) (interface{}, error) { return r.ReadInt64s() },
+	"ints":              func(r *iostream.Reader) (interface{}, error) { return r.ReadInts() },
+	"float16s":          func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat16s() },
+	"bfloat16s":         func(r *iostream.Reader) (interface{}, error) { return r.ReadBFloat16s() },
+	"float32s":          func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat32s() },
+	"float64s":          func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat64s() },
+	"gorillafloat64s":   func(r *iostream.Reader) (interface{}, error) { return r.ReadGorillaFloat64s() },
+	"gorillatimestamps": func(r *iostream.Reader) (interface{}, error) { return r.ReadGorillaTimestamps() },
+	"rleint64s":         func(r *iostream.Reader) (interface{}, error) { return r.ReadRLEInt64s() },
+	"rleuint8s":         func(r *iostream.Reader) (interface{}, error) { return r.ReadRLEUint8s() },
+	"autoint64s":        func(r *iostream.Reader) (interface{}, error) { return r.ReadAutoInt64s() },
+	"autouint8s":        func(r *iostream.Reader) (interface{}, error) { return r.ReadAutoUint8s() },
+	"bools":             func(r *iostream.Reader) (interface{}, error) { return r.ReadBools() },
+	"bitmap":            func(r *iostream.Reader) (interface{}, error) { return r.ReadBitmap() },
+	"strings":           func(r *iostream.Reader) (interface{}, error) { return r.ReadStrings() },
+	"sortedstrings":     func(r *iostream.Reader) (interface{}, error) { return r.ReadSortedStrings() },
+	"times":             func(r *iostream.Reader) (interface{}, error) { return r.ReadTimes() },
+	"durations":         func(r *iostream.Reader) (interface{}, error) { return r.ReadDurations() },
+}
+
+// readUint128 reads a 128-bit integer and returns it as a hexadecimal string
+func readUint128(r *iostream.Reader) (interface{}, error) {
+	hi, lo, err := r.ReadUint128()
+	return fmt.Sprintf("0x%016x%016x", hi, lo), err
+}
+
+// typeNames returns the sorted list of supported type names
+func typeNames() []string {
+	names := make([]string, 0, len(decoders))
+	for name := range decoders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// --------------------------- Layout ---------------------------
+
+// field represents a single entry of a layout, such as "uint32s[..]"
+type field struct {
+	Type   string     // The name of the type
+	Repeat int        // The number of repetitions, or untilEnd
+	decode decodeFunc // The decoder of a single value
+}
+
+// String returns the layout representation of the field
+func (f field) String() string {
+	switch f.Repeat {
+	case 1:
+		return f.Type
+	case untilEnd:
+		return f.Type + "[..]"
+	default:
+		return fmt.Sprintf("%s[%d]", f.Type, f.Repeat)
+	}
+}
+
+// parseLayout parses a whitespace-separated list of types, each optionally followed
+// by a repeat count in brackets: "[N]" repeats it N times and "[..]" repeats it
+// until the end of the input.
+func parseLayout(text string) ([]field, error) {
+	tokens := strings.Fields(text)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("layout is empty")
+	}
+
+	layout := make([]field, 0, len(tokens))
+	for _, token := range tokens {
+		name, repeat := token, 1
+		if i := strings.IndexByte(token, '['); i >= 0 {
+			if !strings.HasSuffix(token, "]") {
+				return nil, fmt.Errorf("layout: missing ']' in %q", token)
+			}
+
+			switch count := token[i+1 : len(token)-1]; count {
+			case "..":
+				repeat = untilEnd
+			default:
+				n, err := strconv.Atoi(count)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("layout: invalid repeat count in %q", token)
+				}
+				repeat = n
+			}
+			name = token[:i]
+		}
+
+		decode, ok := decoders[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("layout: unknown type %q", name)
+		}
+
+		layout = append(layout, field{
+			Type:   strings.ToLower(name),
+			Repeat: repeat,
+			decode: decode,
+		})
+	}
+	return layout, nil
+}
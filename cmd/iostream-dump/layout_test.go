@@ -0,0 +1,52 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLayout(t *testing.T) {
+	layout, err := parseLayout(" uvarint  String\tuint32s[..] bool[3] int8[0]")
+	assert.NoError(t, err)
+	assert.Len(t, layout, 5)
+
+	var names []string
+	for _, f := range layout {
+		assert.NotNil(t, f.decode)
+		names = append(names, f.String())
+	}
+	assert.Equal(t, []string{"uvarint", "string", "uint32s[..]", "bool[3]", "int8[0]"}, names)
+	assert.Equal(t, untilEnd, layout[2].Repeat)
+	assert.Equal(t, 3, layout[3].Repeat)
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	for _, text := range []string{
+		"", "  ", "unknown", "uint32[", "uint32[2", "uint32[x]", "uint32[-1]", "uint32[.]", "[2]",
+	} {
+		_, err := parseLayout(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestTypeNames(t *testing.T) {
+	names := typeNames()
+	assert.Len(t, names, len(decoders))
+	assert.Contains(t, names, "uvarint")
+	assert.IsIncreasing(t, names)
+}
+
+func TestDecoders(t *testing.T) {
+	for _, name := range typeNames() {
+		layout, err := parseLayout(name)
+		assert.NoError(t, err)
+
+		entries := decode(make([]byte, 64), layout)
+		assert.Len(t, entries, 1, name)
+		assert.NotPanics(t, func() { format(entries[0].Value) }, name)
+	}
+}
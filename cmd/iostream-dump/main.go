@@ -0,0 +1,76 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Command iostream-dump prints an annotated hex dump of a binary file written with
+// iostream, given a layout of the values it contains. For example:
+//
+//	iostream-dump snapshot.bin "uvarint string uint32s[..]"
+//
+// Each type of the layout is the name of a Reader method without its "Read"
+// prefix, in lower case, and can be followed by "[N]" to repeat it N times or by
+// "[..]" to repeat it until the end of the file. Every value is printed with its
+// offset, its bytes and its decoded value. If decoding fails, the dump stops at
+// the offending value and the command exits with a non-zero status.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command and returns its exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("iostream-dump", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	width := flags.Int("width", 16, "number of bytes per line")
+	lines := flags.Int("lines", 4, "maximum number of lines per value, 0 for no limit")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: iostream-dump [flags] <file|-> <layout...>")
+		fmt.Fprintln(stderr, "\nflags:")
+		flags.PrintDefaults()
+		fmt.Fprintf(stderr, "\ntypes:\n  %s\n", strings.Join(typeNames(), " "))
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() < 2 || *width <= 0 {
+		flags.Usage()
+		return 2
+	}
+
+	layout, err := parseLayout(strings.Join(flags.Args()[1:], " "))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	data, err := readInput(flags.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	dump := &printer{out: stdout, width: *width, lines: *lines}
+	if err := dump.Print(data, decode(data, layout)); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// readInput reads the whole file, or the standard input if the name is "-"
+func readInput(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(name)
+}
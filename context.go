@@ -0,0 +1,140 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// deadliner represents a stream which supports read deadlines, such as net.Conn
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// NewReaderContext creates a stream reader bound to the context. Once the context
+// is done, reads fail with an error wrapping the context error. If the stream
+// supports read deadlines, such as net.Conn, the deadline of the context is set on
+// the stream and a pending read is interrupted when the context is cancelled.
+// Otherwise, the context is only checked before reading from the stream.
+//
+// Interrupting a read moves the read deadline of the stream into the past, and the
+// deadline is cleared again before the interrupted read returns, so the stream
+// can be reused afterwards. The returned release function stops watching the
+// context and should be called once the reader is no longer needed. It also
+// clears the deadline which was set from the context.
+func NewReaderContext(ctx context.Context, src io.Reader) (*Reader, func()) {
+	r := newContextReader(ctx, src)
+	return &Reader{
+		src: newSource(r),
+	}, r.release
+}
+
+// --------------------------- Context Reader ---------------------------
+
+// contextReader represents an io.Reader which is bound to a context
+type contextReader struct {
+	ctx      context.Context
+	src      io.Reader
+	lock     sync.Mutex    // Held while reading, so the watcher can wait for a read
+	once     sync.Once     // Guards the closing of the released channel
+	released chan struct{} // Closed once the reader is released
+	stopped  chan struct{} // Closed once the watcher has returned
+}
+
+// newContextReader creates a new reader bound to the context. If the stream
+// supports deadlines and the context can be cancelled, a single watcher is started
+// which interrupts the pending read, if any, once the context is done.
+func newContextReader(ctx context.Context, src io.Reader) *contextReader {
+	r := &contextReader{
+		ctx:      ctx,
+		src:      src,
+		released: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	conn, ok := src.(deadliner)
+	if !ok || ctx.Done() == nil {
+		close(r.stopped)
+		return r
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+
+	go r.watch(conn)
+	return r
+}
+
+// watch waits for the context to be done and interrupts the pending read by
+// moving the read deadline of the stream in the past. Once the interrupted read
+// has returned, the deadline is cleared so that the stream can be reused.
+func (r *contextReader) watch(conn deadliner) {
+	defer close(r.stopped)
+	select {
+	case <-r.released:
+		if _, ok := r.ctx.Deadline(); ok {
+			_ = conn.SetReadDeadline(time.Time{})
+		}
+		return
+	case <-r.ctx.Done():
+	}
+
+	_ = conn.SetReadDeadline(time.Unix(1, 0))
+	r.lock.Lock()
+	_ = conn.SetReadDeadline(time.Time{})
+	r.lock.Unlock()
+}
+
+// release stops watching the context and waits for the watcher to return.
+func (r *contextReader) release() {
+	r.once.Do(func() {
+		close(r.released)
+	})
+	<-r.stopped
+}
+
+// Read implements the io.Reader interface. The context is checked while holding
+// the lock, so that a cancellation either happens before the read starts or
+// interrupts it.
+func (r *contextReader) Read(p []byte) (int, error) {
+	r.lock.Lock()
+	if err := r.ctx.Err(); err != nil {
+		r.lock.Unlock()
+		return 0, r.interrupted(err)
+	}
+
+	n, err := r.src.Read(p)
+	r.lock.Unlock()
+	return n, r.wrap(err)
+}
+
+// wrap replaces the error with the context error if the read has failed because
+// the context is done. The deadline is checked separately, since the stream can
+// time out slightly before the context does.
+func (r *contextReader) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := r.ctx.Err(); ctxErr != nil {
+		return r.interrupted(ctxErr)
+	}
+
+	if deadline, ok := r.ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return r.interrupted(context.DeadlineExceeded)
+	}
+	return err
+}
+
+// interrupted waits for the watcher to clear the deadline of the stream, and
+// wraps the context error.
+func (r *contextReader) interrupted(err error) error {
+	<-r.stopped
+	return fmt.Errorf("iostream: read interrupted: %w", err)
+}
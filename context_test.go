@@ -0,0 +1,136 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderContext(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteString("hello"))
+
+	r, release := NewReaderContext(context.Background(), newNetworkSource(buffer.Bytes()))
+	defer release()
+
+	s, err := r.ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", s)
+
+	_, err = r.ReadString()
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, context.Canceled))
+}
+
+func TestReaderContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r, release := NewReaderContext(ctx, newNetworkSource([]byte{0x1}))
+	defer release()
+
+	_, err := r.ReadUint8()
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestReaderContextConnCancel(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, release := NewReaderContext(ctx, client)
+	defer release()
+
+	go func() {
+		NewWriter(server).WriteString("hi")
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	s, err := r.ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", s)
+
+	// This blocks until the context is cancelled
+	_, err = r.ReadString()
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// The deadline is cleared once the read is interrupted
+	go NewWriter(server).WriteString("again")
+	s, err = NewReader(client).ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "again", s)
+}
+
+func TestReaderContextConnDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	r, release := NewReaderContext(ctx, client)
+	defer release()
+
+	_, err := r.ReadString()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestReaderContextConnCancelIdle(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, release := NewReaderContext(ctx, client)
+	defer release()
+
+	// Cancel while no read is pending, the next read then fails right away
+	cancel()
+	_, err := r.ReadString()
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	go NewWriter(server).WriteString("again")
+	s, err := NewReader(client).ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "again", s)
+}
+
+func TestReaderContextRelease(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	r, release := NewReaderContext(ctx, client)
+	go NewWriter(server).WriteString("hi")
+	s, err := r.ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", s)
+
+	// Once released, the context is no longer watched and its deadline is cleared
+	release()
+	release()
+	cancel()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		NewWriter(server).WriteString("again")
+	}()
+
+	s, err = NewReader(client).ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "again", s)
+}
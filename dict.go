@@ -0,0 +1,157 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"errors"
+	"io"
+)
+
+var errDictionary = errors.New("iostream: reference to an unknown dictionary entry")
+
+// Tags written in front of every dictionary-encoded string. Any tag above these
+// refers to a dictionary entry.
+const (
+	dictRestart = 0 // The dictionary is cleared
+	dictLiteral = 1 // A new string follows and is added to the dictionary
+	dictEntries = 2 // The first dictionary entry
+)
+
+// --------------------------- Dictionary Writer ---------------------------
+
+// DictWriter represents a stateful string writer which writes every distinct
+// string only once and refers to it by its position in a dictionary afterwards.
+type DictWriter struct {
+	out   *Writer
+	dict  map[string]uint64
+	limit int
+}
+
+// NewDictWriter creates a new dictionary writer which holds at most the specified
+// number of strings. Once the dictionary is full, it is restarted. If the limit is
+// zero or negative, the dictionary is unbounded.
+func NewDictWriter(out io.Writer, limit int) *DictWriter {
+	return &DictWriter{
+		out:   NewWriter(out),
+		dict:  make(map[string]uint64),
+		limit: limit,
+	}
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (w *DictWriter) Offset() int64 {
+	return w.out.Offset()
+}
+
+// WriteString writes a string, or a reference to it if it was already written
+// since the last restart.
+func (w *DictWriter) WriteString(v string) error {
+	if id, ok := w.dict[v]; ok {
+		return w.out.WriteUvarint(dictEntries + id)
+	}
+
+	if w.limit > 0 && len(w.dict) >= w.limit {
+		if err := w.Restart(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.out.WriteUvarint(dictLiteral); err != nil {
+		return err
+	}
+
+	w.dict[v] = uint64(len(w.dict))
+	return w.out.WriteString(v)
+}
+
+// WriteStrings writes an array of strings
+func (w *DictWriter) WriteStrings(v []string) error {
+	if err := w.out.WriteUvarint(uint64(len(v))); err != nil {
+		return err
+	}
+
+	for _, s := range v {
+		if err := w.WriteString(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restart writes a reset point, clearing the dictionary on both sides. This can
+// be used to start a section of the stream which is decodable on its own.
+func (w *DictWriter) Restart() error {
+	for k := range w.dict {
+		delete(w.dict, k)
+	}
+	return w.out.WriteUvarint(dictRestart)
+}
+
+// --------------------------- Dictionary Reader ---------------------------
+
+// DictReader represents a stateful string reader for strings written with
+// a dictionary writer. Strings are interned, so reading a string which was
+// already read does not allocate.
+type DictReader struct {
+	src  *Reader
+	dict []string
+}
+
+// NewDictReader creates a new dictionary reader.
+func NewDictReader(src io.Reader) *DictReader {
+	return &DictReader{
+		src: NewReader(src),
+	}
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (r *DictReader) Offset() int64 {
+	return r.src.Offset()
+}
+
+// ReadString reads a string
+func (r *DictReader) ReadString() (string, error) {
+	for {
+		tag, err := r.src.ReadUvarint()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case tag == dictRestart:
+			r.dict = r.dict[:0]
+		case tag == dictLiteral:
+			v, err := r.src.ReadString()
+			if err != nil {
+				return "", err
+			}
+
+			r.dict = append(r.dict, v)
+			return v, nil
+		case tag-dictEntries < uint64(len(r.dict)):
+			return r.dict[tag-dictEntries], nil
+		default:
+			return "", errDictionary
+		}
+	}
+}
+
+// ReadStrings reads an array of strings
+func (r *DictReader) ReadStrings() ([]string, error) {
+	length, capacity, err := r.src.readLength(1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
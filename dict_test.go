@@ -0,0 +1,115 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictWriter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewDictWriter(buffer, 0)
+	assert.NoError(t, w.WriteStrings([]string{"a", "bc", "a", "a", "bc"}))
+	assert.NoError(t, w.Restart())
+	assert.NoError(t, w.WriteString("bc"))
+	assert.Equal(t, []byte{
+		0x5,           // length
+		0x1, 0x1, 'a', // literal "a"
+		0x1, 0x2, 'b', 'c', // literal "bc"
+		0x2, 0x2, 0x3, // references
+		0x0,                // restart
+		0x1, 0x2, 'b', 'c', // literal "bc"
+	}, buffer.Bytes())
+	assert.Equal(t, int64(buffer.Len()), w.Offset())
+
+	r := NewDictReader(buffer)
+	out, err := r.ReadStrings()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "bc", "a", "a", "bc"}, out)
+
+	s, err := r.ReadString()
+	assert.NoError(t, err)
+	assert.Equal(t, "bc", s)
+	assert.Equal(t, w.Offset(), r.Offset())
+}
+
+func TestDictLimit(t *testing.T) {
+	input := make([]string, 1000)
+	for i := range input {
+		input[i] = fmt.Sprintf("key-%d", i%37)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	w := NewDictWriter(buffer, 10)
+	assert.NoError(t, w.WriteStrings(input))
+
+	r := NewDictReader(buffer)
+	out, err := r.ReadStrings()
+	assert.NoError(t, err)
+	assert.Equal(t, input, out)
+	assert.LessOrEqual(t, len(r.dict), 10)
+}
+
+func TestDictInterned(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewDictWriter(buffer, 0)
+	assert.NoError(t, w.WriteStrings([]string{"hello", "hello"}))
+
+	out, err := NewDictReader(buffer).ReadStrings()
+	assert.NoError(t, err)
+	assert.Equal(t, dataOf(out[0]), dataOf(out[1]))
+}
+
+func TestDictReaderErrors(t *testing.T) {
+	inputs := [][]byte{
+		{},              // empty
+		{0x0},           // restart only
+		{0x1, 0x5, 'a'}, // short literal
+		{0x2},           // unknown entry
+		{0x1, 0x1, 'a', 0x3},
+	}
+
+	for _, input := range inputs {
+		r := NewDictReader(bytes.NewBuffer(input))
+		_, err := r.ReadString()
+		if err == nil {
+			_, err = r.ReadString()
+		}
+		assert.Error(t, err)
+	}
+
+	_, err := NewDictReader(bytes.NewBuffer(nil)).ReadStrings()
+	assert.Error(t, err)
+
+	_, err = NewDictReader(bytes.NewBuffer([]byte{0x1})).ReadStrings()
+	assert.Error(t, err)
+
+	input := []byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x1, 0x1, 'a'}
+	_, err = NewDictReader(bytes.NewBuffer(input)).ReadStrings()
+	assert.Equal(t, errSize, err)
+
+	_, err = NewDictReader(newNetworkSource(input)).ReadStrings()
+	assert.Error(t, err)
+}
+
+func TestDictWriterErrors(t *testing.T) {
+	assert.Error(t, NewDictWriter(newLimitWriter(0), 0).WriteString("a"))
+	assert.Error(t, NewDictWriter(newLimitWriter(1), 0).WriteString("a"))
+	assert.Error(t, NewDictWriter(newLimitWriter(0), 0).WriteStrings([]string{"a"}))
+	assert.Error(t, NewDictWriter(newLimitWriter(1), 0).WriteStrings([]string{"a"}))
+
+	w := NewDictWriter(newLimitWriter(3), 1)
+	assert.NoError(t, w.WriteString("a"))
+	assert.Error(t, w.WriteString("b"))
+}
+
+func dataOf(v string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&v)).Data
+}
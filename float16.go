@@ -0,0 +1,93 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"math"
+)
+
+// --------------------------- IEEE 754 Half Precision ---------------------------
+
+// Float16bits converts a 32-bit floating point number to an IEEE 754 half-precision
+// number, rounding to nearest even. Values too large are converted to infinity and
+// values too small to zero, while NaNs are kept as quiet NaNs.
+func Float16bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	// Infinity and NaN, make sure the NaN does not turn into an infinity
+	if exp == 0xff {
+		if mant == 0 {
+			return sign | 0x7c00
+		}
+		return sign | 0x7e00 | uint16(mant>>13)
+	}
+
+	switch e := exp - 127 + 15; {
+	case e >= 0x1f: // Overflow
+		return sign | 0x7c00
+	case e < -10: // Underflow
+		return sign
+	case e <= 0: // Subnormal, the carry may turn it into a normal number
+		mant |= 0x800000
+		shift := uint(14 - e)
+		return sign | uint16(roundEven(mant>>shift, mant&(1<<shift-1), 1<<(shift-1)))
+	default: // Normal, the carry may turn it into an infinity
+		return sign | uint16(roundEven(uint32(e)<<10|mant>>13, mant&0x1fff, 0x1000))
+	}
+}
+
+// Float16frombits converts an IEEE 754 half-precision number to a 32-bit floating
+// point number. This conversion is exact.
+func Float16frombits(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f: // Infinity and NaN
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0 && mant == 0: // Zero
+		return math.Float32frombits(sign)
+	case exp == 0: // Subnormal, needs to be normalized
+		exp = 127 - 15 + 1
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		return math.Float32frombits(sign | exp<<23 | (mant&0x3ff)<<13)
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	}
+}
+
+// --------------------------- Brain Floating Point ---------------------------
+
+// BFloat16bits converts a 32-bit floating point number to a bfloat16 number,
+// rounding to nearest even. NaNs are kept as quiet NaNs.
+func BFloat16bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	if b&0x7fffffff > 0x7f800000 {
+		return uint16(b>>16) | 0x40
+	}
+
+	return uint16(roundEven(b>>16, b&0xffff, 0x8000))
+}
+
+// BFloat16frombits converts a bfloat16 number to a 32-bit floating point number.
+// This conversion is exact.
+func BFloat16frombits(h uint16) float32 {
+	return math.Float32frombits(uint32(h) << 16)
+}
+
+// roundEven rounds the truncated value to nearest even, given the remainder that
+// was truncated and the half-way point of that remainder.
+func roundEven(v, rem, half uint32) uint32 {
+	if rem > half || (rem == half && v&1 == 1) {
+		v++
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat16RoundTrip(t *testing.T) {
+	for i := 0; i <= math.MaxUint16; i++ {
+		h := uint16(i)
+		f := Float16frombits(h)
+		if f != f {
+			assert.True(t, isNaN16(Float16bits(f)))
+			continue
+		}
+
+		assert.Equal(t, h, Float16bits(f))
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	tests := []struct {
+		input  float32
+		output uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{65520, 0x7c00},    // rounds to infinity
+		{1e10, 0x7c00},     // overflow
+		{5.9604645e-08, 1}, // smallest subnormal
+		{2.9802322e-08, 0}, // half of smallest subnormal, rounds to even
+		{2.9802326e-08, 1}, // just above half of smallest subnormal
+		{1e-10, 0},         // underflow
+		{6.097555e-05, 0x03ff},
+		{1.0009766, 0x3c01},
+		{1.0004883, 0x3c00}, // tie, rounds to even
+		{1.0014648, 0x3c02}, // tie, rounds to even
+		{float32(math.Inf(1)), 0x7c00},
+		{float32(math.Inf(-1)), 0xfc00},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.output, Float16bits(tc.input), "%v", tc.input)
+	}
+
+	assert.True(t, isNaN16(Float16bits(float32(math.NaN()))))
+	assert.True(t, isNaN16(Float16bits(math.Float32frombits(0x7f800001))))
+}
+
+func TestBFloat16(t *testing.T) {
+	tests := []struct {
+		input  float32
+		output uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3f80},
+		{-2, 0xc000},
+		{math.Float32frombits(0x3f808000), 0x3f80}, // tie, rounds to even
+		{math.Float32frombits(0x3f818000), 0x3f82}, // tie, rounds to even
+		{math.Float32frombits(0x3f808001), 0x3f81},
+		{math.MaxFloat32, 0x7f80}, // rounds to infinity
+		{float32(math.Inf(-1)), 0xff80},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.output, BFloat16bits(tc.input), "%v", tc.input)
+		assert.Equal(t, tc.output, BFloat16bits(BFloat16frombits(tc.output)))
+	}
+
+	nan := BFloat16frombits(BFloat16bits(math.Float32frombits(0x7f800001)))
+	assert.True(t, nan != nan)
+}
+
+func isNaN16(h uint16) bool {
+	return h&0x7c00 == 0x7c00 && h&0x3ff != 0
+}
+
+func TestReadFloat16sInvalidLength(t *testing.T) {
+	input := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}
+	_, err := NewReader(bytes.NewBuffer(input)).ReadFloat16s()
+	assert.Error(t, err)
+	_, err = NewReader(bytes.NewBuffer(input)).ReadBFloat16s()
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package convert provides the conversions shared by the packages of this module.
+package convert
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// ToString converts byte slice to a string without allocating.
+func ToString(b *[]byte) string {
+	return *(*string)(unsafe.Pointer(b))
+}
+
+// ToBytes converts a string to a byte slice without allocating. The returned
+// slice must not be modified.
+func ToBytes(v string) (b []byte) {
+	strHeader := (*reflect.StringHeader)(unsafe.Pointer(&v))
+	byteHeader := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	byteHeader.Data = strHeader.Data
+
+	l := len(v)
+	byteHeader.Len = l
+	byteHeader.Cap = l
+	return
+}
@@ -0,0 +1,22 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertString(t *testing.T) {
+	v := "hi there"
+
+	b := ToBytes(v)
+	assert.NotEmpty(t, b)
+	assert.Equal(t, v, string(b))
+
+	o := ToString(&b)
+	assert.NotEmpty(t, b)
+	assert.Equal(t, v, o)
+}
@@ -0,0 +1,613 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package msgpack
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/kelindar/iostream"
+)
+
+// The maximum number of elements allocated upfront when decoding a collection,
+// so that a corrupt length does not cause a huge allocation.
+const maxPrealloc = 1024
+
+// The maximum nesting of arrays and maps, so that a corrupt input does not
+// exhaust the stack.
+const maxDepth = 1024
+
+// Decoder represents a MessagePack decoder.
+type Decoder struct {
+	src   *iostream.Reader
+	depth int // The nesting of the collection being decoded
+}
+
+// NewDecoder creates a new MessagePack decoder over the reader.
+func NewDecoder(src *iostream.Reader) *Decoder {
+	return &Decoder{
+		src: src,
+	}
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (d *Decoder) Offset() int64 {
+	return d.src.Offset()
+}
+
+// PeekType returns the type of the next value without consuming it.
+func (d *Decoder) PeekType() (Type, error) {
+	b, err := d.src.Peek(1)
+	if len(b) == 0 {
+		return InvalidType, err
+	}
+	return typeOf(b[0]), nil
+}
+
+// Decode decodes the next value into a Go value. Integers are decoded as int64,
+// unless they only fit into a uint64. Arrays are decoded as []interface{}, maps
+// as map[string]interface{} and timestamps as time.Time in UTC.
+func (d *Decoder) Decode() (interface{}, error) {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch typeOf(code) {
+	case NilType:
+		return nil, nil
+	case BoolType:
+		return code == codeTrue, nil
+	case IntType:
+		v, negative, err := d.integer(code)
+		switch {
+		case err != nil:
+			return nil, err
+		case !negative && v > math.MaxInt64:
+			return v, nil
+		default:
+			return int64(v), nil
+		}
+	case FloatType:
+		if code == codeFloat32 {
+			return d.float32()
+		}
+		return d.float64()
+	case StrType:
+		b, err := d.bytes(code)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case BinType:
+		b, err := d.bytes(code)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case ArrayType:
+		return d.array(code)
+	case MapType:
+		return d.dictionary(code)
+	case ExtType:
+		typ, size, err := d.extHeader(code)
+		switch {
+		case err != nil:
+			return nil, err
+		case typ == TimestampType:
+			return d.timestamp(size)
+		}
+
+		b, err := d.src.Slice(size)
+		if err != nil {
+			return nil, err
+		}
+		return Ext{Type: typ, Data: append([]byte{}, b...)}, nil
+	default:
+		return nil, errCode
+	}
+}
+
+// DecodeNil decodes a nil value
+func (d *Decoder) DecodeNil() error {
+	code, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return err
+	case code != codeNil:
+		return errCode
+	default:
+		return nil
+	}
+}
+
+// DecodeBool decodes a boolean value
+func (d *Decoder) DecodeBool() (bool, error) {
+	code, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return false, err
+	case code == codeTrue:
+		return true, nil
+	case code == codeFalse:
+		return false, nil
+	default:
+		return false, errCode
+	}
+}
+
+// DecodeInt decodes an integer of any format, as long as it fits into an int64
+func (d *Decoder) DecodeInt() (int64, error) {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	v, negative, err := d.integer(code)
+	switch {
+	case err != nil:
+		return 0, err
+	case !negative && v > math.MaxInt64:
+		return 0, errOverflow
+	default:
+		return int64(v), nil
+	}
+}
+
+// DecodeUint decodes an integer of any format, as long as it is not negative
+func (d *Decoder) DecodeUint() (uint64, error) {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+
+	v, negative, err := d.integer(code)
+	switch {
+	case err != nil:
+		return 0, err
+	case negative:
+		return 0, errOverflow
+	default:
+		return v, nil
+	}
+}
+
+// DecodeFloat32 decodes a single-precision floating point number
+func (d *Decoder) DecodeFloat32() (float32, error) {
+	code, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return 0, err
+	case code != codeFloat32:
+		return 0, errCode
+	default:
+		return d.float32()
+	}
+}
+
+// DecodeFloat64 decodes a floating point number of either precision
+func (d *Decoder) DecodeFloat64() (float64, error) {
+	code, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return 0, err
+	case code == codeFloat32:
+		v, err := d.float32()
+		return float64(v), err
+	case code == codeFloat64:
+		return d.float64()
+	default:
+		return 0, errCode
+	}
+}
+
+// DecodeString decodes a string
+func (d *Decoder) DecodeString() (string, error) {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return "", err
+	}
+
+	if typeOf(code) != StrType {
+		return "", errCode
+	}
+
+	b, err := d.bytes(code)
+	return string(b), err
+}
+
+// DecodeBytes decodes a byte array, or the bytes of a string
+func (d *Decoder) DecodeBytes() ([]byte, error) {
+	b, err := d.DecodeBytesNoCopy()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+// DecodeBytesNoCopy decodes a byte array, or the bytes of a string, without
+// copying them when the reader is backed by a buffer. The returned slice is only
+// valid until the next read and must not be modified.
+func (d *Decoder) DecodeBytesNoCopy() ([]byte, error) {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := typeOf(code); t != BinType && t != StrType {
+		return nil, errCode
+	}
+	return d.bytes(code)
+}
+
+// DecodeArrayLen decodes the header of an array and returns its number of elements
+func (d *Decoder) DecodeArrayLen() (int, error) {
+	code, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return 0, err
+	case typeOf(code) != ArrayType:
+		return 0, errCode
+	default:
+		return d.length(code)
+	}
+}
+
+// DecodeMapLen decodes the header of a map and returns its number of key and
+// value pairs.
+func (d *Decoder) DecodeMapLen() (int, error) {
+	code, err := d.src.ReadUint8()
+	switch {
+	case err != nil:
+		return 0, err
+	case typeOf(code) != MapType:
+		return 0, errCode
+	default:
+		return d.length(code)
+	}
+}
+
+// DecodeExt decodes an extension value and returns its type and data
+func (d *Decoder) DecodeExt() (int8, []byte, error) {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	typ, size, err := d.extHeader(code)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	b, err := d.src.Slice(size)
+	if err != nil {
+		return 0, nil, err
+	}
+	return typ, append([]byte{}, b...), nil
+}
+
+// DecodeTime decodes a time encoded with the timestamp extension, in UTC
+func (d *Decoder) DecodeTime() (time.Time, error) {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	typ, size, err := d.extHeader(code)
+	switch {
+	case err != nil:
+		return time.Time{}, err
+	case typ != TimestampType:
+		return time.Time{}, errTime
+	default:
+		return d.timestamp(size)
+	}
+}
+
+// Skip skips the next value, including all of its elements if it is an array
+// or a map.
+func (d *Decoder) Skip() error {
+	code, err := d.src.ReadUint8()
+	if err != nil {
+		return err
+	}
+
+	switch typeOf(code) {
+	case NilType, BoolType:
+		return nil
+	case IntType, FloatType:
+		return d.src.SkipN(payloadSize(code))
+	case StrType, BinType:
+		size, err := d.length(code)
+		if err != nil {
+			return err
+		}
+		return d.src.SkipN(size)
+	case ArrayType, MapType:
+		size, err := d.length(code)
+		if err != nil {
+			return err
+		}
+
+		if typeOf(code) == MapType {
+			size *= 2
+		}
+
+		if err := d.enter(); err != nil {
+			return err
+		}
+
+		defer d.leave()
+		for i := 0; i < size; i++ {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ExtType:
+		_, size, err := d.extHeader(code)
+		if err != nil {
+			return err
+		}
+		return d.src.SkipN(size)
+	default:
+		return errCode
+	}
+}
+
+// --------------------------- Payloads ---------------------------
+
+// payloadSize returns the size of the fixed-size payload following the code
+func payloadSize(code byte) int {
+	switch code {
+	case codeUint8, codeInt8:
+		return 1
+	case codeUint16, codeInt16:
+		return 2
+	case codeUint32, codeInt32, codeFloat32:
+		return 4
+	case codeUint64, codeInt64, codeFloat64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// uint reads a big-endian unsigned integer of the specified size in bytes
+func (d *Decoder) uint(size int) (uint64, error) {
+	b, err := d.src.Slice(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// integer reads the payload of an integer and returns its value, which needs
+// to be reinterpreted as an int64 if it is negative.
+func (d *Decoder) integer(code byte) (uint64, bool, error) {
+	switch {
+	case code <= 0x7f:
+		return uint64(code), false, nil
+	case code >= codeNegFix:
+		return uint64(int64(int8(code))), true, nil
+	case typeOf(code) != IntType:
+		return 0, false, errCode
+	}
+
+	v, err := d.uint(payloadSize(code))
+	switch code {
+	case codeUint8, codeUint16, codeUint32, codeUint64:
+		return v, false, err
+	case codeInt8:
+		v = uint64(int64(int8(v)))
+	case codeInt16:
+		v = uint64(int64(int16(v)))
+	case codeInt32:
+		v = uint64(int64(int32(v)))
+	}
+	return v, int64(v) < 0, err
+}
+
+// float32 reads the payload of a single-precision floating point number
+func (d *Decoder) float32() (float32, error) {
+	v, err := d.uint(4)
+	return math.Float32frombits(uint32(v)), err
+}
+
+// float64 reads the payload of a double-precision floating point number
+func (d *Decoder) float64() (float64, error) {
+	v, err := d.uint(8)
+	return math.Float64frombits(v), err
+}
+
+// length reads the length of a string, byte array, array or map
+func (d *Decoder) length(code byte) (int, error) {
+	var size int
+	switch {
+	case code >= codeFixMap && code < codeFixStr:
+		return int(code & 0x0f), nil
+	case code >= codeFixStr && code < codeNil:
+		return int(code & 0x1f), nil
+	case code == codeStr8 || code == codeBin8:
+		size = 1
+	case code == codeStr16 || code == codeBin16 || code == codeArray16 || code == codeMap16:
+		size = 2
+	case code == codeStr32 || code == codeBin32 || code == codeArray32 || code == codeMap32:
+		size = 4
+	default:
+		return 0, errCode
+	}
+
+	v, err := d.uint(size)
+	if err == nil && v > math.MaxInt32 {
+		err = errLength
+	}
+	return int(v), err
+}
+
+// bytes reads the contents of a string or a byte array. The returned slice is
+// only valid until the next read.
+func (d *Decoder) bytes(code byte) ([]byte, error) {
+	size, err := d.length(code)
+	if err != nil {
+		return nil, err
+	}
+	return d.src.Slice(size)
+}
+
+// array reads the elements of an array
+func (d *Decoder) array(code byte) ([]interface{}, error) {
+	size, err := d.length(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+
+	defer d.leave()
+	out := make([]interface{}, 0, prealloc(size))
+	for i := 0; i < size; i++ {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// dictionary reads the key and value pairs of a map with string keys
+func (d *Decoder) dictionary(code byte) (map[string]interface{}, error) {
+	size, err := d.length(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+
+	defer d.leave()
+	out := make(map[string]interface{}, prealloc(size))
+	for i := 0; i < size; i++ {
+		k, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := k.(string)
+		if !ok {
+			return nil, errMapKey
+		}
+
+		if out[key], err = d.Decode(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// enter increments the nesting of collections, up to the maximum depth
+func (d *Decoder) enter() error {
+	if d.depth >= maxDepth {
+		return errDepth
+	}
+
+	d.depth++
+	return nil
+}
+
+// leave decrements the nesting of collections
+func (d *Decoder) leave() {
+	d.depth--
+}
+
+// extHeader reads the header of an extension value and returns its type and size
+func (d *Decoder) extHeader(code byte) (int8, int, error) {
+	var size int
+	var err error
+	switch code {
+	case codeFixExt1:
+		size = 1
+	case codeFixExt2:
+		size = 2
+	case codeFixExt4:
+		size = 4
+	case codeFixExt8:
+		size = 8
+	case codeFixExt16:
+		size = 16
+	case codeExt8, codeExt16, codeExt32:
+		var n uint64
+		n, err = d.uint(1 << (code - codeExt8))
+		if n > math.MaxInt32 {
+			return 0, 0, errLength
+		}
+		size = int(n)
+	default:
+		return 0, 0, errCode
+	}
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	typ, err := d.src.ReadUint8()
+	return int8(typ), size, err
+}
+
+// timestamp reads the payload of the timestamp extension
+func (d *Decoder) timestamp(size int) (time.Time, error) {
+	var sec int64
+	var nsec uint64
+	switch size {
+	case 4:
+		v, err := d.uint(4)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec = int64(v)
+	case 8:
+		v, err := d.uint(8)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, nsec = int64(v&(1<<34-1)), v>>34
+	case 12:
+		b, err := d.src.Slice(12)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec = int64(binary.BigEndian.Uint64(b[4:]))
+		nsec = uint64(binary.BigEndian.Uint32(b))
+	default:
+		return time.Time{}, errTime
+	}
+
+	if nsec >= 1e9 {
+		return time.Time{}, errTime
+	}
+	return time.Unix(sec, int64(nsec)).UTC(), nil
+}
+
+// prealloc returns the capacity to allocate for a collection of the given size
+func prealloc(size int) int {
+	if size > maxPrealloc {
+		return maxPrealloc
+	}
+	return size
+}
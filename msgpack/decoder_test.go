@@ -0,0 +1,278 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package msgpack
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	expect := map[string]interface{}{
+		"fixint":      int64(127),
+		"uint64":      int64(1 << 32),
+		"int16":       int64(-129),
+		"float32":     float32(1),
+		"bin8":        []byte{1},
+		"fixarray":    []interface{}{int64(1), "a"},
+		"array16":     make([]interface{}, 16),
+		"fixmap":      map[string]interface{}{"a": int64(1)},
+		"fixext1":     Ext{Type: 5, Data: []byte{1}},
+		"timestamp32": time.Unix(1, 0).UTC(),
+		"timestamp64": time.Unix(1, 1).UTC(),
+		"timestamp96": time.Unix(-1, 1).UTC(),
+	}
+
+	for name, tc := range fixtures {
+		want, ok := expect[name]
+		if !ok {
+			want = tc.Value
+		}
+
+		// Integers of every size are decoded as int64
+		if _, isInt := want.(int64); !isInt && typeOf(tc.Buffer[0]) == IntType {
+			want = toInt64(want)
+		}
+
+		dec := newDecoder(tc.Buffer)
+		v, err := dec.Decode()
+		if name == "fixmap-any" {
+			assert.Error(t, err, name)
+			continue
+		}
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, want, v, name)
+		assert.Equal(t, int64(len(tc.Buffer)), dec.Offset(), name)
+	}
+}
+
+func TestDecodeMaxUint(t *testing.T) {
+	v, err := newDecoder([]byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), v)
+}
+
+func TestDecodeTyped(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.EncodeNil())
+	assert.NoError(t, enc.EncodeBool(true))
+	assert.NoError(t, enc.EncodeInt(math.MinInt64))
+	assert.NoError(t, enc.EncodeUint(math.MaxUint64))
+	assert.NoError(t, enc.EncodeFloat32(1.5))
+	assert.NoError(t, enc.EncodeFloat32(2.5))
+	assert.NoError(t, enc.EncodeFloat64(math.Inf(-1)))
+	assert.NoError(t, enc.EncodeString("hello"))
+	assert.NoError(t, enc.EncodeBytes([]byte("world")))
+	assert.NoError(t, enc.EncodeString("view"))
+	assert.NoError(t, enc.EncodeArrayLen(2))
+	assert.NoError(t, enc.EncodeMapLen(20))
+	assert.NoError(t, enc.EncodeExt(7, []byte{1, 2, 3, 4, 5}))
+	assert.NoError(t, enc.EncodeTime(time.Unix(1<<40, 999999999)))
+
+	for _, src := range []io.Reader{
+		bytes.NewBuffer(buffer.Bytes()),
+		bytes.NewReader(buffer.Bytes()),
+	} {
+		dec := NewDecoder(iostream.NewReader(src))
+		typ, err := dec.PeekType()
+		assert.NoError(t, err)
+		assert.Equal(t, NilType, typ)
+		assert.NoError(t, dec.DecodeNil())
+
+		b, err := dec.DecodeBool()
+		assert.NoError(t, err)
+		assert.True(t, b)
+
+		i, err := dec.DecodeInt()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(math.MinInt64), i)
+
+		u, err := dec.DecodeUint()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(math.MaxUint64), u)
+
+		f32, err := dec.DecodeFloat32()
+		assert.NoError(t, err)
+		assert.Equal(t, float32(1.5), f32)
+
+		f64, err := dec.DecodeFloat64()
+		assert.NoError(t, err)
+		assert.Equal(t, 2.5, f64)
+
+		f64, err = dec.DecodeFloat64()
+		assert.NoError(t, err)
+		assert.True(t, math.IsInf(f64, -1))
+
+		s, err := dec.DecodeString()
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", s)
+
+		bin, err := dec.DecodeBytes()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("world"), bin)
+
+		bin, err = dec.DecodeBytesNoCopy()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("view"), bin)
+
+		n, err := dec.DecodeArrayLen()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		n, err = dec.DecodeMapLen()
+		assert.NoError(t, err)
+		assert.Equal(t, 20, n)
+
+		typ, err = dec.PeekType()
+		assert.NoError(t, err)
+		assert.Equal(t, ExtType, typ)
+
+		ext, data, err := dec.DecodeExt()
+		assert.NoError(t, err)
+		assert.Equal(t, int8(7), ext)
+		assert.Equal(t, []byte{1, 2, 3, 4, 5}, data)
+
+		ts, err := dec.DecodeTime()
+		assert.NoError(t, err)
+		assert.Equal(t, time.Unix(1<<40, 999999999).UTC(), ts)
+
+		_, err = dec.PeekType()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestDecodeNoCopy(t *testing.T) {
+	input := []byte{0xa3, 0x61, 0x62, 0x63}
+	b, err := newDecoder(input).DecodeBytesNoCopy()
+	assert.NoError(t, err)
+	assert.Equal(t, &input[1], &b[0])
+}
+
+func TestSkip(t *testing.T) {
+	for name, tc := range fixtures {
+		input := append(append([]byte{}, tc.Buffer...), 0x2a)
+		dec := newDecoder(input)
+		assert.NoError(t, dec.Skip(), name)
+
+		v, err := dec.DecodeInt()
+		assert.NoError(t, err, name)
+		assert.Equal(t, int64(42), v, name)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	decode := map[string]func(*Decoder) error{
+		"Decode":            func(d *Decoder) error { _, err := d.Decode(); return err },
+		"DecodeNil":         func(d *Decoder) error { return d.DecodeNil() },
+		"DecodeBool":        func(d *Decoder) error { _, err := d.DecodeBool(); return err },
+		"DecodeInt":         func(d *Decoder) error { _, err := d.DecodeInt(); return err },
+		"DecodeUint":        func(d *Decoder) error { _, err := d.DecodeUint(); return err },
+		"DecodeFloat32":     func(d *Decoder) error { _, err := d.DecodeFloat32(); return err },
+		"DecodeFloat64":     func(d *Decoder) error { _, err := d.DecodeFloat64(); return err },
+		"DecodeString":      func(d *Decoder) error { _, err := d.DecodeString(); return err },
+		"DecodeBytes":       func(d *Decoder) error { _, err := d.DecodeBytes(); return err },
+		"DecodeBytesNoCopy": func(d *Decoder) error { _, err := d.DecodeBytesNoCopy(); return err },
+		"DecodeArrayLen":    func(d *Decoder) error { _, err := d.DecodeArrayLen(); return err },
+		"DecodeMapLen":      func(d *Decoder) error { _, err := d.DecodeMapLen(); return err },
+		"DecodeExt":         func(d *Decoder) error { _, _, err := d.DecodeExt(); return err },
+		"DecodeTime":        func(d *Decoder) error { _, err := d.DecodeTime(); return err },
+		"Skip":              func(d *Decoder) error { return d.Skip() },
+	}
+
+	// Empty and truncated inputs must fail for every method
+	for name, fn := range decode {
+		assert.Error(t, fn(newDecoder(nil)), name)
+		assert.Error(t, fn(newDecoder([]byte{0xc1})), name)
+	}
+
+	for name, tc := range fixtures {
+		for i := 1; i < len(tc.Buffer); i++ {
+			_, err := newDecoder(tc.Buffer[:i]).Decode()
+			assert.Error(t, err, name)
+			assert.Error(t, newDecoder(tc.Buffer[:i]).Skip(), name)
+		}
+	}
+
+	// Values of a different type must fail
+	for name, fn := range decode {
+		if name != "Decode" && name != "Skip" && name != "DecodeNil" {
+			assert.Error(t, fn(newDecoder([]byte{0xc0})), name)
+		}
+	}
+	assert.Error(t, decode["DecodeNil"](newDecoder([]byte{0xc2})))
+
+	// Specific errors
+	for _, tc := range []struct {
+		name  string
+		input []byte
+	}{
+		{"DecodeInt", []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"DecodeUint", []byte{0xff}},
+		{"DecodeUint", []byte{0xd0, 0x80}},
+		{"DecodeFloat32", []byte{0xcb, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"DecodeString", []byte{0xc4, 0x00}},
+		{"DecodeTime", []byte{0xd4, 0x01, 0x00}},
+		{"DecodeTime", []byte{0xd4, 0xff, 0x00}},
+		{"DecodeTime", []byte{0xd7, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00}},
+		{"Decode", []byte{0x81, 0x01, 0x01}},
+		{"Decode", []byte{0x91, 0xc1}},
+		{"Decode", []byte{0x81, 0xa1, 0x61, 0xc1}},
+		{"Decode", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}},
+		{"Decode", []byte{0xc9, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"Skip", []byte{0x91, 0xc1}},
+	} {
+		assert.Error(t, decode[tc.name](newDecoder(tc.input)), "%s %v", tc.name, tc.input)
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	deep := bytes.Repeat([]byte{0x91}, 2*maxDepth)
+	_, err := newDecoder(deep).Decode()
+	assert.Equal(t, errDepth, err)
+	assert.Equal(t, errDepth, newDecoder(deep).Skip())
+
+	deep = bytes.Repeat([]byte{0x81, 0xa1, 0x61}, 2*maxDepth)
+	_, err = newDecoder(deep).Decode()
+	assert.Equal(t, errDepth, err)
+	assert.Equal(t, errDepth, newDecoder(deep).Skip())
+
+	// Nesting up to the limit is fine
+	dec := newDecoder(append(bytes.Repeat([]byte{0x91}, maxDepth), 0xc0))
+	_, err = dec.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, dec.depth)
+}
+
+func toInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	default:
+		return v.(int64)
+	}
+}
+
+func newDecoder(b []byte) *Decoder {
+	return NewDecoder(iostream.NewReader(bytes.NewBuffer(b)))
+}
@@ -0,0 +1,307 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package msgpack
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/kelindar/iostream/internal/convert"
+)
+
+// Encoder represents a MessagePack encoder.
+type Encoder struct {
+	out     *iostream.Writer
+	scratch [9]byte  // Format code and its payload
+	stamp   [12]byte // Payload of the timestamp extension
+}
+
+// NewEncoder creates a new MessagePack encoder over the writer.
+func NewEncoder(out *iostream.Writer) *Encoder {
+	return &Encoder{
+		out: out,
+	}
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (e *Encoder) Offset() int64 {
+	return e.out.Offset()
+}
+
+// Encode encodes a Go value. It supports nil, booleans, integers, floats, strings,
+// byte slices, time.Time, Ext, as well as slices of interface{} and maps with
+// string or interface{} keys that contain any of the supported types.
+func (e *Encoder) Encode(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return e.EncodeNil()
+	case bool:
+		return e.EncodeBool(v)
+	case int:
+		return e.EncodeInt(int64(v))
+	case int8:
+		return e.EncodeInt(int64(v))
+	case int16:
+		return e.EncodeInt(int64(v))
+	case int32:
+		return e.EncodeInt(int64(v))
+	case int64:
+		return e.EncodeInt(v)
+	case uint:
+		return e.EncodeUint(uint64(v))
+	case uint8:
+		return e.EncodeUint(uint64(v))
+	case uint16:
+		return e.EncodeUint(uint64(v))
+	case uint32:
+		return e.EncodeUint(uint64(v))
+	case uint64:
+		return e.EncodeUint(v)
+	case float32:
+		return e.EncodeFloat32(v)
+	case float64:
+		return e.EncodeFloat64(v)
+	case string:
+		return e.EncodeString(v)
+	case []byte:
+		return e.EncodeBytes(v)
+	case time.Time:
+		return e.EncodeTime(v)
+	case Ext:
+		return e.EncodeExt(v.Type, v.Data)
+	case []interface{}:
+		if err := e.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		if err := e.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for key, item := range v {
+			if err := e.EncodeString(key); err != nil {
+				return err
+			}
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[interface{}]interface{}:
+		if err := e.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for key, item := range v {
+			if err := e.Encode(key); err != nil {
+				return err
+			}
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errType
+	}
+}
+
+// EncodeNil encodes a nil value
+func (e *Encoder) EncodeNil() error {
+	return e.out.WriteUint8(codeNil)
+}
+
+// EncodeBool encodes a boolean value
+func (e *Encoder) EncodeBool(v bool) error {
+	if v {
+		return e.out.WriteUint8(codeTrue)
+	}
+	return e.out.WriteUint8(codeFalse)
+}
+
+// EncodeInt encodes a signed integer using the smallest format which can hold it
+func (e *Encoder) EncodeInt(v int64) error {
+	switch {
+	case v >= 0:
+		return e.EncodeUint(uint64(v))
+	case v >= -32:
+		return e.out.WriteUint8(uint8(v))
+	case v >= math.MinInt8:
+		return e.writeCode8(codeInt8, uint8(v))
+	case v >= math.MinInt16:
+		return e.writeCode16(codeInt16, uint16(v))
+	case v >= math.MinInt32:
+		return e.writeCode32(codeInt32, uint32(v))
+	default:
+		return e.writeCode64(codeInt64, uint64(v))
+	}
+}
+
+// EncodeUint encodes an unsigned integer using the smallest format which can hold it
+func (e *Encoder) EncodeUint(v uint64) error {
+	switch {
+	case v <= 0x7f:
+		return e.out.WriteUint8(uint8(v))
+	case v <= math.MaxUint8:
+		return e.writeCode8(codeUint8, uint8(v))
+	case v <= math.MaxUint16:
+		return e.writeCode16(codeUint16, uint16(v))
+	case v <= math.MaxUint32:
+		return e.writeCode32(codeUint32, uint32(v))
+	default:
+		return e.writeCode64(codeUint64, v)
+	}
+}
+
+// EncodeFloat32 encodes a single-precision floating point number
+func (e *Encoder) EncodeFloat32(v float32) error {
+	return e.writeCode32(codeFloat32, math.Float32bits(v))
+}
+
+// EncodeFloat64 encodes a double-precision floating point number
+func (e *Encoder) EncodeFloat64(v float64) error {
+	return e.writeCode64(codeFloat64, math.Float64bits(v))
+}
+
+// EncodeString encodes a UTF-8 string
+func (e *Encoder) EncodeString(v string) error {
+	if err := e.writeSize(len(v), codeFixStr, 31, codeStr8, codeStr16, codeStr32); err != nil {
+		return err
+	}
+
+	_, err := e.out.Write(convert.ToBytes(v))
+	return err
+}
+
+// EncodeBytes encodes a byte array
+func (e *Encoder) EncodeBytes(v []byte) error {
+	if err := e.writeSize(len(v), 0, -1, codeBin8, codeBin16, codeBin32); err != nil {
+		return err
+	}
+
+	_, err := e.out.Write(v)
+	return err
+}
+
+// EncodeArrayLen encodes the header of an array, which must be followed by
+// the specified number of values.
+func (e *Encoder) EncodeArrayLen(n int) error {
+	return e.writeSize(n, codeFixArray, 15, 0, codeArray16, codeArray32)
+}
+
+// EncodeMapLen encodes the header of a map, which must be followed by the
+// specified number of key and value pairs.
+func (e *Encoder) EncodeMapLen(n int) error {
+	return e.writeSize(n, codeFixMap, 15, 0, codeMap16, codeMap32)
+}
+
+// EncodeExt encodes an extension value of the application-specific type
+func (e *Encoder) EncodeExt(typ int8, data []byte) error {
+	if err := e.writeExtHeader(typ, len(data)); err != nil {
+		return err
+	}
+
+	_, err := e.out.Write(data)
+	return err
+}
+
+// EncodeTime encodes a time using the timestamp extension. It uses the smallest
+// of the 32, 64 and 96-bit formats which can hold the time. The location of the
+// time is not preserved.
+func (e *Encoder) EncodeTime(v time.Time) error {
+	sec, nsec := v.Unix(), uint32(v.Nanosecond())
+	switch {
+	case sec >= 0 && sec <= math.MaxUint32 && nsec == 0:
+		binary.BigEndian.PutUint32(e.stamp[:4], uint32(sec))
+		return e.EncodeExt(TimestampType, e.stamp[:4])
+	case sec >= 0 && sec < 1<<34:
+		binary.BigEndian.PutUint64(e.stamp[:8], uint64(nsec)<<34|uint64(sec))
+		return e.EncodeExt(TimestampType, e.stamp[:8])
+	default:
+		binary.BigEndian.PutUint32(e.stamp[:4], nsec)
+		binary.BigEndian.PutUint64(e.stamp[4:12], uint64(sec))
+		return e.EncodeExt(TimestampType, e.stamp[:12])
+	}
+}
+
+// --------------------------- Headers ---------------------------
+
+// writeSize writes the header of a sized value. The fixed format is used up to
+// the maximum, and the 8-bit format is only used if its code is specified.
+func (e *Encoder) writeSize(size int, fix byte, max int, code8, code16, code32 byte) error {
+	switch {
+	case size <= max:
+		return e.out.WriteUint8(fix | byte(size))
+	case size <= math.MaxUint8 && code8 != 0:
+		return e.writeCode8(code8, uint8(size))
+	case size <= math.MaxUint16:
+		return e.writeCode16(code16, uint16(size))
+	case uint64(size) <= math.MaxUint32:
+		return e.writeCode32(code32, uint32(size))
+	default:
+		return errLength
+	}
+}
+
+// writeExtHeader writes the header of an extension value
+func (e *Encoder) writeExtHeader(typ int8, size int) error {
+	var err error
+	switch size {
+	case 1:
+		err = e.out.WriteUint8(codeFixExt1)
+	case 2:
+		err = e.out.WriteUint8(codeFixExt2)
+	case 4:
+		err = e.out.WriteUint8(codeFixExt4)
+	case 8:
+		err = e.out.WriteUint8(codeFixExt8)
+	case 16:
+		err = e.out.WriteUint8(codeFixExt16)
+	default:
+		err = e.writeSize(size, 0, -1, codeExt8, codeExt16, codeExt32)
+	}
+
+	if err != nil {
+		return err
+	}
+	return e.out.WriteUint8(uint8(typ))
+}
+
+// writeCode8 writes a format code followed by an 8-bit payload
+func (e *Encoder) writeCode8(code byte, v uint8) error {
+	e.scratch[0] = code
+	e.scratch[1] = v
+	_, err := e.out.Write(e.scratch[:2])
+	return err
+}
+
+// writeCode16 writes a format code followed by a big-endian 16-bit payload
+func (e *Encoder) writeCode16(code byte, v uint16) error {
+	e.scratch[0] = code
+	binary.BigEndian.PutUint16(e.scratch[1:], v)
+	_, err := e.out.Write(e.scratch[:3])
+	return err
+}
+
+// writeCode32 writes a format code followed by a big-endian 32-bit payload
+func (e *Encoder) writeCode32(code byte, v uint32) error {
+	e.scratch[0] = code
+	binary.BigEndian.PutUint32(e.scratch[1:], v)
+	_, err := e.out.Write(e.scratch[:5])
+	return err
+}
+
+// writeCode64 writes a format code followed by a big-endian 64-bit payload
+func (e *Encoder) writeCode64(code byte, v uint64) error {
+	e.scratch[0] = code
+	binary.BigEndian.PutUint64(e.scratch[1:], v)
+	_, err := e.out.Write(e.scratch[:9])
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package msgpack
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+// Golden values, encoded according to the MessagePack specification
+var fixtures = map[string]struct {
+	Value  interface{}
+	Buffer []byte
+}{
+	"nil":         {nil, []byte{0xc0}},
+	"false":       {false, []byte{0xc2}},
+	"true":        {true, []byte{0xc3}},
+	"fixint":      {127, []byte{0x7f}},
+	"uint8":       {uint8(128), []byte{0xcc, 0x80}},
+	"uint16":      {uint16(256), []byte{0xcd, 0x01, 0x00}},
+	"uint32":      {uint32(65536), []byte{0xce, 0x00, 0x01, 0x00, 0x00}},
+	"uint64":      {uint64(1 << 32), []byte{0xcf, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+	"negfixint":   {-32, []byte{0xe0}},
+	"int8":        {int8(-33), []byte{0xd0, 0xdf}},
+	"int16":       {int16(-129), []byte{0xd1, 0xff, 0x7f}},
+	"int32":       {int32(-32769), []byte{0xd2, 0xff, 0xff, 0x7f, 0xff}},
+	"int64":       {int64(math.MinInt32 - 1), []byte{0xd3, 0xff, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff}},
+	"float32":     {float32(1), []byte{0xca, 0x3f, 0x80, 0x00, 0x00}},
+	"float64":     {float64(1), []byte{0xcb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+	"fixstr":      {"a", []byte{0xa1, 0x61}},
+	"str8":        {strings.Repeat("a", 32), append([]byte{0xd9, 0x20}, strings.Repeat("a", 32)...)},
+	"str16":       {strings.Repeat("a", 256), append([]byte{0xda, 0x01, 0x00}, strings.Repeat("a", 256)...)},
+	"bin8":        {[]byte{1}, []byte{0xc4, 0x01, 0x01}},
+	"bin16":       {make([]byte, 256), append([]byte{0xc5, 0x01, 0x00}, make([]byte, 256)...)},
+	"fixarray":    {[]interface{}{1, "a"}, []byte{0x92, 0x01, 0xa1, 0x61}},
+	"array16":     {make([]interface{}, 16), append([]byte{0xdc, 0x00, 0x10}, bytes.Repeat([]byte{0xc0}, 16)...)},
+	"fixmap":      {map[string]interface{}{"a": 1}, []byte{0x81, 0xa1, 0x61, 0x01}},
+	"fixmap-any":  {map[interface{}]interface{}{1: true}, []byte{0x81, 0x01, 0xc3}},
+	"fixext1":     {Ext{Type: 5, Data: []byte{1}}, []byte{0xd4, 0x05, 0x01}},
+	"fixext16":    {Ext{Type: 5, Data: make([]byte, 16)}, append([]byte{0xd8, 0x05}, make([]byte, 16)...)},
+	"ext8":        {Ext{Type: 5, Data: []byte{1, 2, 3}}, []byte{0xc7, 0x03, 0x05, 0x01, 0x02, 0x03}},
+	"timestamp32": {time.Unix(1, 0), []byte{0xd6, 0xff, 0x00, 0x00, 0x00, 0x01}},
+	"timestamp64": {time.Unix(1, 1), []byte{0xd7, 0xff, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01}},
+	"timestamp96": {time.Unix(-1, 1), []byte{0xc7, 0x0c, 0xff, 0x00, 0x00, 0x00, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+}
+
+func TestEncode(t *testing.T) {
+	for name, tc := range fixtures {
+		buffer := bytes.NewBuffer(nil)
+		enc := NewEncoder(iostream.NewWriter(buffer))
+		assert.NoError(t, enc.Encode(tc.Value), name)
+		assert.Equal(t, tc.Buffer, buffer.Bytes(), name)
+		assert.Equal(t, int64(len(tc.Buffer)), enc.Offset(), name)
+	}
+}
+
+func TestEncodeTypes(t *testing.T) {
+	for _, v := range []interface{}{
+		int(1), int8(1), int16(1), int32(1), int64(1),
+		uint(1), uint8(1), uint16(1), uint32(1), uint64(1),
+	} {
+		buffer := bytes.NewBuffer(nil)
+		assert.NoError(t, NewEncoder(iostream.NewWriter(buffer)).Encode(v))
+		assert.Equal(t, []byte{0x01}, buffer.Bytes())
+	}
+}
+
+func TestEncodeLarge(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(iostream.NewWriter(buffer))
+	assert.NoError(t, enc.EncodeString(strings.Repeat("a", 65536)))
+	assert.NoError(t, enc.EncodeBytes(make([]byte, 65536)))
+	assert.NoError(t, enc.EncodeArrayLen(65536))
+	assert.NoError(t, enc.EncodeMapLen(65536))
+	assert.NoError(t, enc.EncodeExt(1, make([]byte, 256)))
+	assert.NoError(t, enc.EncodeExt(1, make([]byte, 65536)))
+
+	b := buffer.Bytes()
+	assert.Equal(t, []byte{0xdb, 0x00, 0x01, 0x00, 0x00}, b[:5])
+	b = b[65541:]
+	assert.Equal(t, []byte{0xc6, 0x00, 0x01, 0x00, 0x00}, b[:5])
+	b = b[65541:]
+	assert.Equal(t, []byte{0xdd, 0x00, 0x01, 0x00, 0x00, 0xdf, 0x00, 0x01, 0x00, 0x00}, b[:10])
+	b = b[10:]
+	assert.Equal(t, []byte{0xc8, 0x01, 0x00, 0x01}, b[:4])
+	b = b[260:]
+	assert.Equal(t, []byte{0xc9, 0x00, 0x01, 0x00, 0x00, 0x01}, b[:6])
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	enc := NewEncoder(iostream.NewWriter(bytes.NewBuffer(nil)))
+	assert.Error(t, enc.Encode(struct{}{}))
+	assert.Error(t, enc.Encode([]interface{}{struct{}{}}))
+	assert.Error(t, enc.Encode(map[string]interface{}{"a": struct{}{}}))
+	assert.Error(t, enc.Encode(map[interface{}]interface{}{struct{}{}: 1}))
+}
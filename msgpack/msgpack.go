@@ -0,0 +1,123 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package msgpack implements an encoder and a decoder for the MessagePack format,
+// on top of the iostream reader and writer. Values can either be written one by
+// one using the typed methods, or encoded from and decoded into plain Go values.
+package msgpack
+
+import (
+	"errors"
+)
+
+var (
+	errCode     = errors.New("msgpack: unexpected format code")
+	errOverflow = errors.New("msgpack: value overflows the destination type")
+	errLength   = errors.New("msgpack: length exceeds the maximum size")
+	errMapKey   = errors.New("msgpack: map key is not a string")
+	errTime     = errors.New("msgpack: invalid timestamp extension")
+	errType     = errors.New("msgpack: unsupported type")
+	errDepth    = errors.New("msgpack: values are nested too deeply")
+)
+
+// Format codes, as specified by the MessagePack specification
+const (
+	codeFixMap   = 0x80
+	codeFixArray = 0x90
+	codeFixStr   = 0xa0
+	codeNil      = 0xc0
+	codeFalse    = 0xc2
+	codeTrue     = 0xc3
+	codeBin8     = 0xc4
+	codeBin16    = 0xc5
+	codeBin32    = 0xc6
+	codeExt8     = 0xc7
+	codeExt16    = 0xc8
+	codeExt32    = 0xc9
+	codeFloat32  = 0xca
+	codeFloat64  = 0xcb
+	codeUint8    = 0xcc
+	codeUint16   = 0xcd
+	codeUint32   = 0xce
+	codeUint64   = 0xcf
+	codeInt8     = 0xd0
+	codeInt16    = 0xd1
+	codeInt32    = 0xd2
+	codeInt64    = 0xd3
+	codeFixExt1  = 0xd4
+	codeFixExt2  = 0xd5
+	codeFixExt4  = 0xd6
+	codeFixExt8  = 0xd7
+	codeFixExt16 = 0xd8
+	codeStr8     = 0xd9
+	codeStr16    = 0xda
+	codeStr32    = 0xdb
+	codeArray16  = 0xdc
+	codeArray32  = 0xdd
+	codeMap16    = 0xde
+	codeMap32    = 0xdf
+	codeNegFix   = 0xe0
+)
+
+// TimestampType is the extension type reserved for timestamps
+const TimestampType int8 = -1
+
+// Type represents the type of an encoded value
+type Type uint8
+
+// Types of the encoded values
+const (
+	InvalidType Type = iota
+	NilType
+	BoolType
+	IntType
+	FloatType
+	StrType
+	BinType
+	ArrayType
+	MapType
+	ExtType
+)
+
+// Ext represents an application-specific extension value
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// typeOf returns the type of the value starting with the format code
+func typeOf(code byte) Type {
+	switch {
+	case code <= 0x7f || code >= codeNegFix:
+		return IntType
+	case code < codeFixArray:
+		return MapType
+	case code < codeFixStr:
+		return ArrayType
+	case code < codeNil:
+		return StrType
+	}
+
+	switch code {
+	case codeNil:
+		return NilType
+	case codeFalse, codeTrue:
+		return BoolType
+	case codeBin8, codeBin16, codeBin32:
+		return BinType
+	case codeExt8, codeExt16, codeExt32, codeFixExt1, codeFixExt2, codeFixExt4, codeFixExt8, codeFixExt16:
+		return ExtType
+	case codeFloat32, codeFloat64:
+		return FloatType
+	case codeUint8, codeUint16, codeUint32, codeUint64, codeInt8, codeInt16, codeInt32, codeInt64:
+		return IntType
+	case codeStr8, codeStr16, codeStr32:
+		return StrType
+	case codeArray16, codeArray32:
+		return ArrayType
+	case codeMap16, codeMap32:
+		return MapType
+	default:
+		return InvalidType
+	}
+}
@@ -0,0 +1,527 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package protowire implements a reader and a writer for the Protocol Buffers
+// wire format, on top of the iostream reader and writer. It does not depend on
+// the protobuf runtime and does not use any schema, fields are written and read
+// one by one.
+package protowire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/kelindar/iostream"
+)
+
+var (
+	errNumber = errors.New("protowire: invalid field number")
+	errType   = errors.New("protowire: field has a different wire type")
+	errGroup  = errors.New("protowire: mismatched end group")
+	errDepth  = errors.New("protowire: groups are nested too deeply")
+)
+
+// maxDepth is the maximum nesting of groups which can be skipped
+const maxDepth = 1024
+
+// Number represents a field number
+type Number int32
+
+// Valid field numbers, as specified by the protobuf language guide
+const (
+	MinValidNumber Number = 1
+	MaxValidNumber Number = 1<<29 - 1
+)
+
+// Type represents a wire type
+type Type int8
+
+// Wire types, as specified by the protobuf encoding guide
+const (
+	VarintType     Type = 0
+	Fixed64Type    Type = 1
+	BytesType      Type = 2
+	StartGroupType Type = 3
+	EndGroupType   Type = 4
+	Fixed32Type    Type = 5
+)
+
+// --------------------------- Writer ---------------------------
+
+// Writer represents a writer of protobuf-encoded messages.
+type Writer struct {
+	out *iostream.Writer
+}
+
+// NewWriter creates a new protobuf writer.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{
+		out: iostream.NewWriter(out),
+	}
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (w *Writer) Offset() int64 {
+	return w.out.Offset()
+}
+
+// WriteTag writes the tag of a field, composed of its number and wire type
+func (w *Writer) WriteTag(num Number, typ Type) error {
+	if num < MinValidNumber || num > MaxValidNumber {
+		return errNumber
+	}
+	return w.out.WriteUvarint(uint64(num)<<3 | uint64(typ&7))
+}
+
+// WriteVarint writes a varint field, used for uint32, uint64 and enum fields
+func (w *Writer) WriteVarint(num Number, v uint64) error {
+	if err := w.WriteTag(num, VarintType); err != nil {
+		return err
+	}
+	return w.out.WriteUvarint(v)
+}
+
+// WriteInt64 writes an int32 or int64 field, negative values always take 10 bytes
+func (w *Writer) WriteInt64(num Number, v int64) error {
+	return w.WriteVarint(num, uint64(v))
+}
+
+// WriteSint64 writes a zigzag-encoded sint32 or sint64 field
+func (w *Writer) WriteSint64(num Number, v int64) error {
+	if err := w.WriteTag(num, VarintType); err != nil {
+		return err
+	}
+	return w.out.WriteVarint(v)
+}
+
+// WriteBool writes a bool field
+func (w *Writer) WriteBool(num Number, v bool) error {
+	if v {
+		return w.WriteVarint(num, 1)
+	}
+	return w.WriteVarint(num, 0)
+}
+
+// WriteFixed32 writes a fixed32 or sfixed32 field
+func (w *Writer) WriteFixed32(num Number, v uint32) error {
+	if err := w.WriteTag(num, Fixed32Type); err != nil {
+		return err
+	}
+	return w.out.WriteUint32(v)
+}
+
+// WriteFixed64 writes a fixed64 or sfixed64 field
+func (w *Writer) WriteFixed64(num Number, v uint64) error {
+	if err := w.WriteTag(num, Fixed64Type); err != nil {
+		return err
+	}
+	return w.out.WriteUint64(v)
+}
+
+// WriteFloat writes a float field
+func (w *Writer) WriteFloat(num Number, v float32) error {
+	return w.WriteFixed32(num, math.Float32bits(v))
+}
+
+// WriteDouble writes a double field
+func (w *Writer) WriteDouble(num Number, v float64) error {
+	return w.WriteFixed64(num, math.Float64bits(v))
+}
+
+// WriteBytes writes a bytes field
+func (w *Writer) WriteBytes(num Number, v []byte) error {
+	if err := w.WriteTag(num, BytesType); err != nil {
+		return err
+	}
+	return w.out.WriteBytes(v)
+}
+
+// WriteString writes a string field
+func (w *Writer) WriteString(num Number, v string) error {
+	if err := w.WriteTag(num, BytesType); err != nil {
+		return err
+	}
+	return w.out.WriteString(v)
+}
+
+// WriteMessage writes an embedded message field. Since the message is prefixed
+// with its size, it is first written into a buffer by the provided function.
+func (w *Writer) WriteMessage(num Number, fn func(w *Writer) error) error {
+	buffer := bytes.NewBuffer(nil)
+	if err := fn(NewWriter(buffer)); err != nil {
+		return err
+	}
+
+	return w.WriteBytes(num, buffer.Bytes())
+}
+
+// WritePackedVarints writes a packed repeated field of varints
+func (w *Writer) WritePackedVarints(num Number, v []uint64) error {
+	size := 0
+	for _, x := range v {
+		size += sizeVarint(x)
+	}
+
+	if err := w.writePacked(num, size); err != nil {
+		return err
+	}
+
+	for _, x := range v {
+		if err := w.out.WriteUvarint(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePackedFixed32 writes a packed repeated field of fixed32s
+func (w *Writer) WritePackedFixed32(num Number, v []uint32) error {
+	if err := w.writePacked(num, 4*len(v)); err != nil {
+		return err
+	}
+
+	for _, x := range v {
+		if err := w.out.WriteUint32(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePackedFixed64 writes a packed repeated field of fixed64s
+func (w *Writer) WritePackedFixed64(num Number, v []uint64) error {
+	if err := w.writePacked(num, 8*len(v)); err != nil {
+		return err
+	}
+
+	for _, x := range v {
+		if err := w.out.WriteUint64(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePacked writes the tag and the size of a packed repeated field
+func (w *Writer) writePacked(num Number, size int) error {
+	if err := w.WriteTag(num, BytesType); err != nil {
+		return err
+	}
+	return w.out.WriteUvarint(uint64(size))
+}
+
+// sizeVarint returns the size of the varint-encoded value
+func sizeVarint(v uint64) int {
+	return (bits.Len64(v|1) + 6) / 7
+}
+
+// --------------------------- Reader ---------------------------
+
+// Reader represents a reader of protobuf-encoded messages, which iterates over
+// the fields of a message.
+//
+//	for r.Next() {
+//		switch r.Number() {
+//		case 1:
+//			v, err = r.ReadVarint()
+//		default:
+//			err = r.Skip()
+//		}
+//	}
+type Reader struct {
+	src *iostream.Reader
+	num Number
+	typ Type
+	err error
+}
+
+// NewReader creates a new protobuf reader. The message ends at the end of the
+// stream.
+func NewReader(src io.Reader) *Reader {
+	return &Reader{
+		src: iostream.NewReader(src),
+	}
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (r *Reader) Offset() int64 {
+	return r.src.Offset()
+}
+
+// Next reads the tag of the next field. It returns false at the end of the
+// message or if an error has occurred.
+func (r *Reader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	// The end of the stream is the end of the message
+	if b, err := r.src.Peek(1); len(b) == 0 {
+		if err != io.EOF {
+			r.err = err
+		}
+		return false
+	}
+
+	num, typ, err := r.readTag()
+	if err != nil {
+		r.err = err
+		return false
+	}
+
+	r.num, r.typ = num, typ
+	return true
+}
+
+// readTag reads the tag of a field
+func (r *Reader) readTag() (Number, Type, error) {
+	tag, err := r.src.ReadUvarint()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	num := Number(tag >> 3)
+	if tag>>3 > uint64(MaxValidNumber) || num < MinValidNumber {
+		return 0, 0, errNumber
+	}
+
+	return num, Type(tag & 7), nil
+}
+
+// Number returns the number of the current field
+func (r *Reader) Number() Number {
+	return r.num
+}
+
+// Type returns the wire type of the current field
+func (r *Reader) Type() Type {
+	return r.typ
+}
+
+// Err returns the first error encountered while reading the tags
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// expect checks whether the current field has the expected wire type
+func (r *Reader) expect(typ Type) error {
+	if r.typ != typ {
+		return errType
+	}
+	return nil
+}
+
+// ReadVarint reads the value of a varint field
+func (r *Reader) ReadVarint() (uint64, error) {
+	if err := r.expect(VarintType); err != nil {
+		return 0, err
+	}
+	return r.src.ReadUvarint()
+}
+
+// ReadInt64 reads the value of an int32 or int64 field
+func (r *Reader) ReadInt64() (int64, error) {
+	v, err := r.ReadVarint()
+	return int64(v), err
+}
+
+// ReadSint64 reads the value of a zigzag-encoded sint32 or sint64 field
+func (r *Reader) ReadSint64() (int64, error) {
+	if err := r.expect(VarintType); err != nil {
+		return 0, err
+	}
+	return r.src.ReadVarint()
+}
+
+// ReadBool reads the value of a bool field
+func (r *Reader) ReadBool() (bool, error) {
+	v, err := r.ReadVarint()
+	return v != 0, err
+}
+
+// ReadFixed32 reads the value of a fixed32 or sfixed32 field
+func (r *Reader) ReadFixed32() (uint32, error) {
+	if err := r.expect(Fixed32Type); err != nil {
+		return 0, err
+	}
+	return r.src.ReadUint32()
+}
+
+// ReadFixed64 reads the value of a fixed64 or sfixed64 field
+func (r *Reader) ReadFixed64() (uint64, error) {
+	if err := r.expect(Fixed64Type); err != nil {
+		return 0, err
+	}
+	return r.src.ReadUint64()
+}
+
+// ReadFloat reads the value of a float field
+func (r *Reader) ReadFloat() (float32, error) {
+	v, err := r.ReadFixed32()
+	return math.Float32frombits(v), err
+}
+
+// ReadDouble reads the value of a double field
+func (r *Reader) ReadDouble() (float64, error) {
+	v, err := r.ReadFixed64()
+	return math.Float64frombits(v), err
+}
+
+// ReadBytes reads the value of a bytes field
+func (r *Reader) ReadBytes() ([]byte, error) {
+	if err := r.expect(BytesType); err != nil {
+		return nil, err
+	}
+	return r.src.ReadBytes()
+}
+
+// ReadString reads the value of a string field
+func (r *Reader) ReadString() (string, error) {
+	if err := r.expect(BytesType); err != nil {
+		return "", err
+	}
+	return r.src.ReadString()
+}
+
+// ReadMessage reads the value of an embedded message field and returns a reader
+// over the fields of that message.
+func (r *Reader) ReadMessage() (*Reader, error) {
+	b, err := r.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReader(bytes.NewBuffer(b)), nil
+}
+
+// ReadPackedVarints reads the values of a repeated varint field. Both packed and
+// non-packed encodings are accepted, as required by the specification, so the
+// values of every occurrence of the field need to be appended together.
+func (r *Reader) ReadPackedVarints() ([]uint64, error) {
+	if r.typ == VarintType {
+		v, err := r.ReadVarint()
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{v}, nil
+	}
+
+	packed, err := r.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]uint64, 0, 8)
+	for {
+		if b, _ := packed.src.Peek(1); len(b) == 0 {
+			return out, nil
+		}
+
+		v, err := packed.src.ReadUvarint()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+}
+
+// ReadPackedFixed32 reads the values of a repeated fixed32 field. Both packed and
+// non-packed encodings are accepted.
+func (r *Reader) ReadPackedFixed32() ([]uint32, error) {
+	if r.typ == Fixed32Type {
+		v, err := r.ReadFixed32()
+		if err != nil {
+			return nil, err
+		}
+		return []uint32{v}, nil
+	}
+
+	b, err := r.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b)%4 != 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	out := make([]uint32, len(b)/4)
+	src := iostream.NewReader(bytes.NewBuffer(b))
+	for i := range out {
+		out[i], _ = src.ReadUint32()
+	}
+	return out, nil
+}
+
+// ReadPackedFixed64 reads the values of a repeated fixed64 field. Both packed and
+// non-packed encodings are accepted.
+func (r *Reader) ReadPackedFixed64() ([]uint64, error) {
+	if r.typ == Fixed64Type {
+		v, err := r.ReadFixed64()
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{v}, nil
+	}
+
+	b, err := r.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b)%8 != 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	out := make([]uint64, len(b)/8)
+	src := iostream.NewReader(bytes.NewBuffer(b))
+	for i := range out {
+		out[i], _ = src.ReadUint64()
+	}
+	return out, nil
+}
+
+// Skip skips the value of the current field, whichever its wire type is. Groups
+// are skipped up to their matching end group.
+func (r *Reader) Skip() error {
+	return r.skip(r.num, r.typ, 0)
+}
+
+// skip skips a value of the specified wire type, nested at the specified depth
+func (r *Reader) skip(num Number, typ Type, depth int) error {
+	switch typ {
+	case VarintType:
+		return r.src.SkipUvarint()
+	case Fixed32Type:
+		return r.src.SkipN(4)
+	case Fixed64Type:
+		return r.src.SkipN(8)
+	case BytesType:
+		return r.src.SkipBytes()
+	case StartGroupType:
+		if depth >= maxDepth {
+			return errDepth
+		}
+
+		for {
+			n, t, err := r.readTag()
+			switch {
+			case err != nil:
+				return err
+			case t == EndGroupType && n == num:
+				return nil
+			case t == EndGroupType:
+				return errGroup
+			}
+
+			if err := r.skip(n, t, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return errType
+	}
+}
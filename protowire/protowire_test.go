@@ -0,0 +1,293 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package protowire
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Golden messages, from the examples of the protobuf encoding guide
+var fixtures = map[string]struct {
+	Encode func(*Writer) error
+	Buffer []byte
+}{
+	"varint": {
+		Encode: func(w *Writer) error { return w.WriteVarint(1, 150) },
+		Buffer: []byte{0x08, 0x96, 0x01},
+	},
+	"string": {
+		Encode: func(w *Writer) error { return w.WriteString(2, "testing") },
+		Buffer: []byte{0x12, 0x07, 0x74, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x67},
+	},
+	"message": {
+		Encode: func(w *Writer) error {
+			return w.WriteMessage(3, func(w *Writer) error {
+				return w.WriteVarint(1, 150)
+			})
+		},
+		Buffer: []byte{0x1a, 0x03, 0x08, 0x96, 0x01},
+	},
+	"packed": {
+		Encode: func(w *Writer) error { return w.WritePackedVarints(4, []uint64{3, 270, 86942}) },
+		Buffer: []byte{0x22, 0x06, 0x03, 0x8e, 0x02, 0x9e, 0xa7, 0x05},
+	},
+	"int64-negative": {
+		Encode: func(w *Writer) error { return w.WriteInt64(1, -2) },
+		Buffer: []byte{0x08, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+	},
+	"sint64-negative": {
+		Encode: func(w *Writer) error { return w.WriteSint64(1, -2) },
+		Buffer: []byte{0x08, 0x03},
+	},
+	"bool": {
+		Encode: func(w *Writer) error { return w.WriteBool(1, true) },
+		Buffer: []byte{0x08, 0x01},
+	},
+	"fixed32": {
+		Encode: func(w *Writer) error { return w.WriteFixed32(5, 1) },
+		Buffer: []byte{0x2d, 0x01, 0x00, 0x00, 0x00},
+	},
+	"double": {
+		Encode: func(w *Writer) error { return w.WriteDouble(1, 1.0) },
+		Buffer: []byte{0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f},
+	},
+	"float": {
+		Encode: func(w *Writer) error { return w.WriteFloat(1, 1.0) },
+		Buffer: []byte{0x0d, 0x00, 0x00, 0x80, 0x3f},
+	},
+	"bytes": {
+		Encode: func(w *Writer) error { return w.WriteBytes(1, []byte{0xff}) },
+		Buffer: []byte{0x0a, 0x01, 0xff},
+	},
+	"packed-fixed32": {
+		Encode: func(w *Writer) error { return w.WritePackedFixed32(1, []uint32{1, 2}) },
+		Buffer: []byte{0x0a, 0x08, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+	},
+	"packed-fixed64": {
+		Encode: func(w *Writer) error { return w.WritePackedFixed64(1, []uint64{1}) },
+		Buffer: []byte{0x0a, 0x08, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	},
+	"large-number": {
+		Encode: func(w *Writer) error { return w.WriteVarint(MaxValidNumber, 1) },
+		Buffer: []byte{0xf8, 0xff, 0xff, 0xff, 0x0f, 0x01},
+	},
+}
+
+func TestWrite(t *testing.T) {
+	for name, tc := range fixtures {
+		buffer := bytes.NewBuffer(nil)
+		w := NewWriter(buffer)
+		assert.NoError(t, tc.Encode(w), name)
+		assert.Equal(t, tc.Buffer, buffer.Bytes(), name)
+		assert.Equal(t, int64(len(tc.Buffer)), w.Offset(), name)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	w := NewWriter(bytes.NewBuffer(nil))
+	assert.Error(t, w.WriteTag(0, VarintType))
+	assert.Error(t, w.WriteTag(MaxValidNumber+1, VarintType))
+	assert.Error(t, w.WriteMessage(1, func(w *Writer) error {
+		return w.WriteVarint(0, 1)
+	}))
+}
+
+func TestRead(t *testing.T) {
+	r := NewReader(bytes.NewBuffer(concat(
+		fixtures["varint"].Buffer,
+		fixtures["string"].Buffer,
+		fixtures["message"].Buffer,
+		fixtures["packed"].Buffer,
+		fixtures["int64-negative"].Buffer,
+		[]byte{0x20, 0x05}, // non-packed element of field 4
+	)))
+
+	var a []uint64
+	var b string
+	var c uint64
+	var d []uint64
+	for r.Next() {
+		switch r.Number() {
+		case 1:
+			v, err := r.ReadVarint()
+			assert.NoError(t, err)
+			a = append(a, v)
+		case 2:
+			v, err := r.ReadString()
+			assert.NoError(t, err)
+			b = v
+		case 3:
+			m, err := r.ReadMessage()
+			assert.NoError(t, err)
+			assert.True(t, m.Next())
+			c, err = m.ReadVarint()
+			assert.NoError(t, err)
+			assert.False(t, m.Next())
+			assert.NoError(t, m.Err())
+		case 4:
+			v, err := r.ReadPackedVarints()
+			assert.NoError(t, err)
+			d = append(d, v...)
+		}
+	}
+
+	assert.NoError(t, r.Err())
+	assert.Equal(t, []uint64{150, 0xfffffffffffffffe}, a)
+	assert.Equal(t, "testing", b)
+	assert.Equal(t, uint64(150), c)
+	assert.Equal(t, []uint64{3, 270, 86942, 5}, d)
+}
+
+func TestReadTypes(t *testing.T) {
+	read := func(name string, fn func(r *Reader) (interface{}, error)) interface{} {
+		r := NewReader(bytes.NewBuffer(fixtures[name].Buffer))
+		assert.True(t, r.Next(), name)
+		v, err := fn(r)
+		assert.NoError(t, err, name)
+		assert.False(t, r.Next(), name)
+		return v
+	}
+
+	assert.Equal(t, int64(-2), read("int64-negative", func(r *Reader) (interface{}, error) { return r.ReadInt64() }))
+	assert.Equal(t, int64(-2), read("sint64-negative", func(r *Reader) (interface{}, error) { return r.ReadSint64() }))
+	assert.Equal(t, true, read("bool", func(r *Reader) (interface{}, error) { return r.ReadBool() }))
+	assert.Equal(t, uint32(1), read("fixed32", func(r *Reader) (interface{}, error) { return r.ReadFixed32() }))
+	assert.Equal(t, 1.0, read("double", func(r *Reader) (interface{}, error) { return r.ReadDouble() }))
+	assert.Equal(t, float32(1.0), read("float", func(r *Reader) (interface{}, error) { return r.ReadFloat() }))
+	assert.Equal(t, []byte{0xff}, read("bytes", func(r *Reader) (interface{}, error) { return r.ReadBytes() }))
+	assert.Equal(t, []uint32{1, 2}, read("packed-fixed32", func(r *Reader) (interface{}, error) { return r.ReadPackedFixed32() }))
+	assert.Equal(t, []uint64{1}, read("packed-fixed64", func(r *Reader) (interface{}, error) { return r.ReadPackedFixed64() }))
+	assert.Equal(t, []uint32{1}, read("fixed32", func(r *Reader) (interface{}, error) { return r.ReadPackedFixed32() }))
+	assert.Equal(t, []uint64{1}, read("packed-fixed64", func(r *Reader) (interface{}, error) { return r.ReadPackedFixed64() }))
+	assert.Equal(t, Number(MaxValidNumber), read("large-number", func(r *Reader) (interface{}, error) { return r.Number(), nil }))
+}
+
+func TestSkip(t *testing.T) {
+	input := concat(
+		[]byte{0x0b},                       // start group 1
+		fixtures["varint"].Buffer,          // field 1, inside of the group
+		[]byte{0x13, 0x14, 0x0c},           // nested group 2, end group 1
+		fixtures["string"].Buffer,          //
+		fixtures["fixed32"].Buffer,         //
+		fixtures["double"].Buffer,          //
+		fixtures["varint"].Buffer,          //
+		fixtures["sint64-negative"].Buffer, //
+	)
+
+	r := NewReader(bytes.NewBuffer(input))
+	count := 0
+	for r.Next() {
+		assert.NoError(t, r.Skip())
+		count++
+	}
+	assert.NoError(t, r.Err())
+	assert.Equal(t, 6, count)
+	assert.Equal(t, int64(len(input)), r.Offset())
+}
+
+func TestSkipDepth(t *testing.T) {
+	r := NewReader(bytes.NewBuffer(bytes.Repeat([]byte{0x0b}, 2*maxDepth)))
+	assert.True(t, r.Next())
+	assert.Equal(t, errDepth, r.Skip())
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, input := range [][]byte{
+		{0x08},                               // missing value
+		{0x00, 0x01},                         // field number zero
+		{0x80},                               // truncated tag
+		{0x0b, 0x14},                         // mismatched end group
+		{0x0c},                               // unexpected end group
+		{0x0b, 0x08},                         // truncated group
+		{0x0e, 0x01},                         // invalid wire type
+		{0xf8, 0xff, 0xff, 0xff, 0x1f, 0x00}, // field number too large
+	} {
+		r := NewReader(bytes.NewBuffer(input))
+		var err error
+		for r.Next() && err == nil {
+			err = r.Skip()
+		}
+		assert.True(t, err != nil || r.Err() != nil, "%v", input)
+	}
+
+	// Wrong wire types
+	r := NewReader(bytes.NewBuffer([]byte{0x0e}))
+	assert.True(t, r.Next())
+	_, err := r.ReadVarint()
+	assert.Error(t, err)
+	_, err = r.ReadSint64()
+	assert.Error(t, err)
+	_, err = r.ReadFixed32()
+	assert.Error(t, err)
+	_, err = r.ReadFixed64()
+	assert.Error(t, err)
+	_, err = r.ReadBytes()
+	assert.Error(t, err)
+	_, err = r.ReadString()
+	assert.Error(t, err)
+	_, err = r.ReadMessage()
+	assert.Error(t, err)
+	_, err = r.ReadPackedVarints()
+	assert.Error(t, err)
+	_, err = r.ReadPackedFixed32()
+	assert.Error(t, err)
+	_, err = r.ReadPackedFixed64()
+	assert.Error(t, err)
+
+	// Malformed packed fields
+	for _, fn := range []func(r *Reader) error{
+		func(r *Reader) error { _, err := r.ReadPackedVarints(); return err },
+		func(r *Reader) error { _, err := r.ReadPackedFixed32(); return err },
+		func(r *Reader) error { _, err := r.ReadPackedFixed64(); return err },
+	} {
+		r := NewReader(bytes.NewBuffer([]byte{0x0a, 0x01, 0x80}))
+		assert.True(t, r.Next())
+		assert.Error(t, fn(r))
+	}
+
+	// Truncated scalar values of repeated fields
+	for _, input := range [][]byte{{0x08, 0x80}, {0x0d, 0x01}, {0x09, 0x01}} {
+		for _, fn := range []func(r *Reader) (interface{}, error){
+			func(r *Reader) (interface{}, error) { return r.ReadPackedVarints() },
+			func(r *Reader) (interface{}, error) { return r.ReadPackedFixed32() },
+			func(r *Reader) (interface{}, error) { return r.ReadPackedFixed64() },
+		} {
+			r := NewReader(bytes.NewBuffer(input))
+			assert.True(t, r.Next())
+			out, err := fn(r)
+			assert.Error(t, err)
+			assert.Nil(t, out)
+		}
+	}
+}
+
+func TestReadCorruptSize(t *testing.T) {
+	for _, input := range [][]byte{
+		{0x0a, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x01},
+		{0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x01},
+		{0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x01},
+	} {
+		for _, fn := range []func(r *Reader) (interface{}, error){
+			func(r *Reader) (interface{}, error) { return r.ReadBytes() },
+			func(r *Reader) (interface{}, error) { return r.ReadString() },
+			func(r *Reader) (interface{}, error) { return r.ReadMessage() },
+		} {
+			for _, src := range []io.Reader{bytes.NewBuffer(input), iotest.OneByteReader(bytes.NewReader(input))} {
+				r := NewReader(src)
+				assert.True(t, r.Next())
+				_, err := fn(r)
+				assert.Error(t, err)
+			}
+		}
+	}
+}
+
+func concat(buffers ...[]byte) []byte {
+	return bytes.Join(buffers, nil)
+}
@@ -5,8 +5,26 @@ package iostream
 
 import (
 	"encoding"
+	"encoding/binary"
+	"errors"
 	"io"
 	"math"
+	"math/big"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/kelindar/iostream/internal/convert"
+)
+
+var (
+	errDenominator = errors.New("iostream: rational number with a zero denominator")
+	errSign        = errors.New("iostream: invalid sign of a big integer")
+	errBitWidth    = errors.New("iostream: invalid bit width in a packed block")
+	errWindow      = errors.New("iostream: invalid window in a compressed block")
+	errRunLength   = errors.New("iostream: invalid run length in an encoded array")
+	errEncoding    = errors.New("iostream: unknown encoding of an array")
+	errSize        = errors.New("iostream: invalid size")
 )
 
 // Reader represents a stream reader.
@@ -31,6 +49,68 @@ func (r *Reader) Offset() int64 {
 	return r.src.Offset()
 }
 
+// Peek returns the next n bytes without advancing the reader. If fewer than n
+// bytes are available, it returns them along with an error. The returned slice
+// is only valid until the next read. On a stream, this buffers the stream.
+func (r *Reader) Peek(n int) ([]byte, error) {
+	return r.src.Peek(n)
+}
+
+// Slice returns the next n bytes and advances the reader, without copying them
+// when the reader is backed by a buffer. The returned slice is only valid until
+// the next read and must not be modified.
+func (r *Reader) Slice(n int) ([]byte, error) {
+	return r.src.Slice(n)
+}
+
+// PeekUvarint returns the next variable-size Uint64 without advancing the reader.
+func (r *Reader) PeekUvarint() (uint64, error) {
+	b, err := r.src.Peek(binary.MaxVarintLen64)
+	v, n := binary.Uvarint(b)
+	switch {
+	case n > 0:
+		return v, nil
+	case n < 0:
+		return 0, overflow
+	case len(b) > 0:
+		return 0, io.ErrUnexpectedEOF
+	default:
+		return 0, err
+	}
+}
+
+// maxPrealloc is the maximum number of elements preallocated for an array read
+// from a stream. Since the size of a stream is unknown, larger arrays grow as
+// their elements are read.
+const maxPrealloc = 4096
+
+// readLength reads the length prefix of an array, a single byte of which holds at
+// most density elements. Lengths which the remaining input can not hold are
+// rejected, and the returned capacity is the number of elements which are safe
+// to preallocate.
+func (r *Reader) readLength(density int) (length, capacity int, err error) {
+	v, err := r.ReadUvarint()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if src, ok := r.src.(*sliceSource); ok {
+		if v/uint64(density) > uint64(int64(len(src.buffer))-src.offset) {
+			return 0, 0, errSize
+		}
+		return int(v), int(v), nil
+	}
+
+	switch {
+	case v > math.MaxInt:
+		return 0, 0, errSize
+	case v > maxPrealloc:
+		return int(v), maxPrealloc, nil
+	default:
+		return int(v), int(v), nil
+	}
+}
+
 // --------------------------- io.Reader ---------------------------
 
 // Read implements io.Reader interface by simply calling the Read method on
@@ -89,18 +169,29 @@ func (r *Reader) ReadUint() (uint, error) {
 	return uint(out), err
 }
 
+// ReadUint128 reads a 128-bit unsigned integer and returns its high and low
+// 64-bit halves.
+func (r *Reader) ReadUint128() (hi, lo uint64, err error) {
+	if lo, err = r.ReadUint64(); err == nil {
+		hi, err = r.ReadUint64()
+	}
+	return
+}
+
 // ReadUint8s reads an array of uint8s
 func (r *Reader) ReadUint8s() ([]uint8, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]uint8, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadUint8(); err != nil {
+	out := make([]uint8, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadUint8()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -108,16 +199,18 @@ func (r *Reader) ReadUint8s() ([]uint8, error) {
 
 // ReadUint16s reads an array of uint16s
 func (r *Reader) ReadUint16s() ([]uint16, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]uint16, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadUint16(); err != nil {
+	out := make([]uint16, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadUint16()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -125,16 +218,18 @@ func (r *Reader) ReadUint16s() ([]uint16, error) {
 
 // ReadUint32s reads an array of uint32s
 func (r *Reader) ReadUint32s() ([]uint32, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]uint32, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadUint32(); err != nil {
+	out := make([]uint32, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadUint32()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -142,16 +237,18 @@ func (r *Reader) ReadUint32s() ([]uint32, error) {
 
 // ReadUint64s reads an array of uint64s
 func (r *Reader) ReadUint64s() ([]uint64, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]uint64, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadUint64(); err != nil {
+	out := make([]uint64, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadUint64()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -159,16 +256,121 @@ func (r *Reader) ReadUint64s() ([]uint64, error) {
 
 // ReadUints reads an array of uints
 func (r *Reader) ReadUints() ([]uint, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]uint, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadUint(); err != nil {
+	out := make([]uint, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadUint()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// ReadUvarints reads an array of variable-size uint64s
+func (r *Reader) ReadUvarints() ([]uint64, error) {
+	length, capacity, err := r.readLength(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the preallocated part at once, and append the rest one by one
+	out := make([]uint64, capacity)
+	if err := r.readUvarints(out); err != nil {
+		return nil, err
+	}
+
+	for len(out) < length {
+		v, err := r.src.ReadUvarint()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// ReadDeltaUvarints reads an array of delta-encoded uint64s
+func (r *Reader) ReadDeltaUvarints() ([]uint64, error) {
+	out, err := r.ReadUvarints()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(out); i++ {
+		out[i] += out[i-1]
+	}
+
+	return out, nil
+}
+
+// readUvarints reads variable-size uint64s into the destination slice. If the
+// source is a slice, it is called directly in order to avoid dynamic dispatch.
+func (r *Reader) readUvarints(dst []uint64) (err error) {
+	if src, ok := r.src.(*sliceSource); ok {
+		for i := range dst {
+			if dst[i], err = src.ReadUvarint(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := range dst {
+		if dst[i], err = r.src.ReadUvarint(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPackedUint32s reads an array of uint32s written in bit-packed blocks
+func (r *Reader) ReadPackedUint32s() ([]uint32, error) {
+	length, capacity, err := r.readLength(packedBlock / 2) // At least 2 bytes per block
+	if err != nil {
+		return nil, err
+	}
+
+	var block [packedBlock]uint32
+	out := make([]uint32, 0, capacity)
+	for len(out) < length {
+		n := length - len(out)
+		if n > packedBlock {
+			n = packedBlock
+		}
+
+		min, err := r.ReadUvarint()
+		if err != nil {
+			return nil, err
+		}
+
+		width, err := r.ReadUint8()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case min > math.MaxUint32:
+			return nil, errSize
+		case width > 32:
+			return nil, errBitWidth
+		}
+
+		// Read the entire block at once and unpack it
+		b, err := r.src.Slice(packedSize(n, uint(width)))
+		if err != nil {
+			return nil, err
+		}
+
+		unpackBits(block[:n], b, uint32(min), uint(width))
+		out = append(out, block[:n]...)
 	}
 
 	return out, nil
@@ -213,16 +415,18 @@ func (r *Reader) ReadInt() (int, error) {
 
 // ReadInt8s reads an array of int8s
 func (r *Reader) ReadInt8s() ([]int8, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]int8, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadInt8(); err != nil {
+	out := make([]int8, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadInt8()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -230,16 +434,18 @@ func (r *Reader) ReadInt8s() ([]int8, error) {
 
 // ReadInt16s reads an array of int16s
 func (r *Reader) ReadInt16s() ([]int16, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]int16, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadInt16(); err != nil {
+	out := make([]int16, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadInt16()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -247,16 +453,18 @@ func (r *Reader) ReadInt16s() ([]int16, error) {
 
 // ReadInt32s reads an array of int32s
 func (r *Reader) ReadInt32s() ([]int32, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]int32, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadInt32(); err != nil {
+	out := make([]int32, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadInt32()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -264,16 +472,18 @@ func (r *Reader) ReadInt32s() ([]int32, error) {
 
 // ReadInt64s reads an array of int64s
 func (r *Reader) ReadInt64s() ([]int64, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]int64, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadInt64(); err != nil {
+	out := make([]int64, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadInt64()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -281,21 +491,81 @@ func (r *Reader) ReadInt64s() ([]int64, error) {
 
 // ReadUints reads an array of uints
 func (r *Reader) ReadInts() ([]int, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]int, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadInt(); err != nil {
+	out := make([]int, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadInt()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
 }
 
+// ReadVarints reads an array of variable-size int64s
+func (r *Reader) ReadVarints() ([]int64, error) {
+	length, capacity, err := r.readLength(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the preallocated part at once, and append the rest one by one
+	out := make([]int64, capacity)
+	if err := r.readVarints(out); err != nil {
+		return nil, err
+	}
+
+	for len(out) < length {
+		v, err := r.src.ReadVarint()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// ReadDeltaVarints reads an array of delta-encoded int64s
+func (r *Reader) ReadDeltaVarints() ([]int64, error) {
+	out, err := r.ReadVarints()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(out); i++ {
+		out[i] += out[i-1]
+	}
+
+	return out, nil
+}
+
+// readVarints reads variable-size int64s into the destination slice. If the
+// source is a slice, it is called directly in order to avoid dynamic dispatch.
+func (r *Reader) readVarints(dst []int64) (err error) {
+	if src, ok := r.src.(*sliceSource); ok {
+		for i := range dst {
+			if dst[i], err = src.ReadVarint(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := range dst {
+		if dst[i], err = r.src.ReadVarint(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // --------------------------- Floats ---------------------------
 
 // ReadFloat32 reads a float32
@@ -318,16 +588,18 @@ func (r *Reader) ReadFloat64() (out float64, err error) {
 
 // ReadFloat32s reads an array of float32s
 func (r *Reader) ReadFloat32s() ([]float32, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]float32, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadFloat32(); err != nil {
+	out := make([]float32, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadFloat32()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
@@ -335,21 +607,421 @@ func (r *Reader) ReadFloat32s() ([]float32, error) {
 
 // ReadFloat64s reads an array of float64s
 func (r *Reader) ReadFloat64s() ([]float64, error) {
+	length, capacity, err := r.readLength(1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadFloat64()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// ReadFloat16 reads an IEEE 754 half-precision number as a float32
+func (r *Reader) ReadFloat16() (out float32, err error) {
+	var v uint16
+	if v, err = r.ReadUint16(); err == nil {
+		out = Float16frombits(v)
+	}
+	return
+}
+
+// ReadBFloat16 reads a bfloat16 number as a float32
+func (r *Reader) ReadBFloat16() (out float32, err error) {
+	var v uint16
+	if v, err = r.ReadUint16(); err == nil {
+		out = BFloat16frombits(v)
+	}
+	return
+}
+
+// ReadFloat16s reads an array of IEEE 754 half-precision numbers as float32s
+func (r *Reader) ReadFloat16s() ([]float32, error) {
+	return r.readUint16s(Float16frombits)
+}
+
+// ReadBFloat16s reads an array of bfloat16 numbers as float32s
+func (r *Reader) ReadBFloat16s() ([]float32, error) {
+	return r.readUint16s(BFloat16frombits)
+}
+
+// readUint16s reads an array of 16-bit values in a single slice and converts
+// them into float32s using the decoder function.
+func (r *Reader) readUint16s(fn func(uint16) float32) ([]float32, error) {
 	length, err := r.ReadUvarint()
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]float64, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadFloat64(); err != nil {
+	if length > math.MaxInt/2 {
+		return nil, errSize
+	}
+
+	b, err := r.src.Slice(2 * int(length))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float32, length)
+	for i := range out {
+		out[i] = fn(uint16(b[2*i]) | uint16(b[2*i+1])<<8)
+	}
+	return out, nil
+}
+
+// --------------------------- Big Numbers ---------------------------
+
+// ReadBigInt reads an arbitrary-precision integer
+func (r *Reader) ReadBigInt() (*big.Int, error) {
+	b, err := r.sliceBytes() // Safe, since SetBytes copies the magnitude
+	switch {
+	case err != nil:
+		return nil, err
+	case len(b) == 0:
+		return new(big.Int), nil
+	case b[0] > 1:
+		return nil, errSign
+	}
+
+	out := new(big.Int).SetBytes(b[1:])
+	if b[0] == 1 {
+		out.Neg(out)
+	}
+	return out, nil
+}
+
+// ReadBigFloat reads an arbitrary-precision floating-point number
+func (r *Reader) ReadBigFloat() (*big.Float, error) {
+	b, err := r.sliceBytes() // Safe, since GobDecode copies the mantissa
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(big.Float)
+	if err := out.GobDecode(b); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReadBigRat reads an arbitrary-precision rational number
+func (r *Reader) ReadBigRat() (*big.Rat, error) {
+	num, err := r.ReadBigInt()
+	if err != nil {
+		return nil, err
+	}
+
+	denom, err := r.ReadBigInt()
+	if err != nil {
+		return nil, err
+	}
+
+	if denom.Sign() == 0 {
+		return nil, errDenominator
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
+}
+
+// --------------------------- Time ---------------------------
+
+// ReadTime reads a time written by WriteTime. Times written in UTC are returned
+// in UTC, any other time is returned in a fixed zone with the written offset.
+func (r *Reader) ReadTime() (time.Time, error) {
+	sec, err := r.ReadVarint()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	header, err := r.ReadUvarint()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	out := time.Unix(sec, int64(header>>1))
+	if header&1 == 0 {
+		return out.UTC(), nil
+	}
+
+	offset, err := r.ReadVarint()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return out.In(time.FixedZone("", int(offset))), nil
+}
+
+// ReadDuration reads a duration
+func (r *Reader) ReadDuration() (time.Duration, error) {
+	v, err := r.ReadVarint()
+	return time.Duration(v), err
+}
+
+// ReadTimes reads an array of times
+func (r *Reader) ReadTimes() ([]time.Time, error) {
+	length, capacity, err := r.readLength(1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]time.Time, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadTime()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// ReadDurations reads an array of durations
+func (r *Reader) ReadDurations() ([]time.Duration, error) {
+	length, capacity, err := r.readLength(1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]time.Duration, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadDuration()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
 }
 
+// --------------------------- Time Series ---------------------------
+
+// ReadGorillaFloat64s reads an array of float64s compressed with the scheme
+// used by Facebook's Gorilla.
+func (r *Reader) ReadGorillaFloat64s() ([]float64, error) {
+	length, capacity, err := r.readLength(8) // At least a bit per value
+	switch {
+	case err != nil:
+		return nil, err
+	case length == 0:
+		return []float64{}, nil
+	}
+
+	br := BitReader{src: r}
+	prev, err := br.ReadBits(64)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, 1, capacity)
+	out[0] = math.Float64frombits(prev)
+	lead, size := 65, 0 // No window yet
+	for i := 1; i < length; i++ {
+		control, err := br.ReadBits(1)
+		if err != nil {
+			return nil, err
+		}
+
+		// If the control bit is set, the value is different than the previous one
+		if control == 1 {
+			if control, err = br.ReadBits(1); err != nil {
+				return nil, err
+			}
+
+			// A new window is specified
+			if control == 1 {
+				header, err := br.ReadBits(11)
+				if err != nil {
+					return nil, err
+				}
+
+				lead, size = int(header&0x1f), int(header>>5)+1
+			}
+
+			if lead+size > 64 {
+				return nil, errWindow
+			}
+
+			xor, err := br.ReadBits(size)
+			if err != nil {
+				return nil, err
+			}
+
+			prev ^= xor << uint(64-lead-size)
+		}
+
+		out = append(out, math.Float64frombits(prev))
+	}
+
+	br.AlignByte()
+	return out, nil
+}
+
+// ReadGorillaTimestamps reads an array of timestamps compressed with the
+// delta-of-delta scheme used by Facebook's Gorilla.
+func (r *Reader) ReadGorillaTimestamps() ([]int64, error) {
+	length, capacity, err := r.readLength(8) // At least a bit per value
+	switch {
+	case err != nil:
+		return nil, err
+	case length == 0:
+		return []int64{}, nil
+	}
+
+	br := BitReader{src: r}
+	first, err := br.ReadBits(64)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]int64, 1, capacity)
+	out[0] = int64(first)
+
+	var delta int64
+	for i := 1; i < length; i++ {
+
+		// Count the number of control bits set, up to 4
+		control := 0
+		for ; control < 4; control++ {
+			bit, err := br.ReadBits(1)
+			if err != nil {
+				return nil, err
+			}
+			if bit == 0 {
+				break
+			}
+		}
+
+		// Read the delta-of-delta and sign-extend it
+		if size := [...]int{0, 7, 9, 12, 64}[control]; size > 0 {
+			dod, err := br.ReadBits(size)
+			if err != nil {
+				return nil, err
+			}
+
+			delta += int64(dod<<uint(64-size)) >> uint(64-size)
+		}
+
+		out = append(out, out[i-1]+delta)
+	}
+
+	br.AlignByte()
+	return out, nil
+}
+
+// --------------------------- Run-Length Encoding ---------------------------
+
+// ReadRLEInt64s reads an array of int64s written as runs of the same value. Arrays
+// of more than 2^24 elements are rejected.
+func (r *Reader) ReadRLEInt64s() ([]int64, error) {
+	length, err := r.ReadUvarint()
+	switch {
+	case err != nil:
+		return nil, err
+	case length > maxRunLength:
+		return nil, errSize
+	}
+
+	out := make([]int64, 0)
+	for uint64(len(out)) < length {
+		value, err := r.ReadInt64()
+		if err != nil {
+			return nil, err
+		}
+
+		run, err := r.ReadUvarint()
+		switch {
+		case err != nil:
+			return nil, err
+		case run == 0 || run > length-uint64(len(out)):
+			return nil, errRunLength
+		}
+
+		// Grow the output run by run, rather than trusting the length upfront
+		i := len(out)
+		out = append(out, make([]int64, run)...)
+		for ; i < len(out); i++ {
+			out[i] = value
+		}
+	}
+
+	return out, nil
+}
+
+// ReadRLEUint8s reads an array of uint8s written as runs of the same value. Arrays
+// of more than 2^24 elements are rejected.
+func (r *Reader) ReadRLEUint8s() ([]uint8, error) {
+	length, err := r.ReadUvarint()
+	switch {
+	case err != nil:
+		return nil, err
+	case length > maxRunLength:
+		return nil, errSize
+	}
+
+	out := make([]uint8, 0)
+	for uint64(len(out)) < length {
+		value, err := r.ReadUint8()
+		if err != nil {
+			return nil, err
+		}
+
+		run, err := r.ReadUvarint()
+		switch {
+		case err != nil:
+			return nil, err
+		case run == 0 || run > length-uint64(len(out)):
+			return nil, errRunLength
+		}
+
+		// Grow the output run by run, rather than trusting the length upfront
+		i := len(out)
+		out = append(out, make([]uint8, run)...)
+		for ; i < len(out); i++ {
+			out[i] = value
+		}
+	}
+
+	return out, nil
+}
+
+// ReadAutoInt64s reads an array of int64s written by WriteAutoInt64s
+func (r *Reader) ReadAutoInt64s() ([]int64, error) {
+	tag, err := r.ReadUint8()
+	switch {
+	case err != nil:
+		return nil, err
+	case tag == encodingPlain:
+		return r.ReadInt64s()
+	case tag == encodingRLE:
+		return r.ReadRLEInt64s()
+	default:
+		return nil, errEncoding
+	}
+}
+
+// ReadAutoUint8s reads an array of uint8s written by WriteAutoUint8s
+func (r *Reader) ReadAutoUint8s() ([]uint8, error) {
+	tag, err := r.ReadUint8()
+	switch {
+	case err != nil:
+		return nil, err
+	case tag == encodingPlain:
+		return r.ReadUint8s()
+	case tag == encodingRLE:
+		return r.ReadRLEUint8s()
+	default:
+		return nil, errEncoding
+	}
+}
+
 // --------------------------- Marshaled Types ---------------------------
 
 // sliceBytes reads a byte string prefixed with a variable-size integer size
@@ -393,47 +1065,126 @@ func (r *Reader) ReadSelf(v io.ReaderFrom) error {
 	return err
 }
 
+// ReadStruct reads a fixed-size value into the value pointed to by v, or into each
+// element of v if it is a slice or a pointer to a slice. The input is expected to
+// be in the format of binary.Write with binary.LittleEndian. Blank (_) fields are
+// left untouched.
+func (r *Reader) ReadStruct(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr && value.Type().Elem().Kind() == reflect.Slice {
+		if value.IsNil() {
+			return errStruct
+		}
+		value = value.Elem()
+	}
+
+	// Find out where the values are located in memory
+	var ptr unsafe.Pointer
+	var plan *structPlan
+	count := 1
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return errStruct
+		}
+		if plan = planOf(value.Type().Elem()); plan == nil {
+			return errStruct
+		}
+		ptr = unsafe.Pointer(value.Pointer())
+	case reflect.Slice:
+		if plan = planOf(value.Type().Elem()); plan == nil {
+			return errStruct
+		}
+		count, ptr = value.Len(), unsafe.Pointer(value.Pointer())
+	default:
+		return errStruct
+	}
+
+	for done := 0; done < count; {
+		n := plan.batchOf(count - done)
+		b, err := r.src.Slice(n * plan.size)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < n; i++ {
+			plan.decode(b[i*plan.size:], unsafe.Add(ptr, uintptr(done+i)*plan.stride))
+		}
+		done += n
+	}
+	return nil
+}
+
 // --------------------------- Strings ---------------------------
 
 // ReadString a string prefixed with a variable-size integer size.
 func (r *Reader) ReadString() (out string, err error) {
 	var b []byte
 	if b, err = r.ReadBytes(); err == nil {
-		out = toString(&b)
+		out = convert.ToString(&b)
 	}
 	return
 }
 
 // ReadBytes a byte string prefixed with a variable-size integer size.
-func (r *Reader) ReadBytes() (out []byte, err error) {
-	size, err := r.ReadUvarint()
+func (r *Reader) ReadBytes() ([]byte, error) {
+	b, err := r.sliceBytes() // Bounded by the remaining input, unlike an upfront allocation
 	if err != nil {
 		return nil, err
 	}
 
-	// Allocate a new byte array, in case the underlying buffer is changed after
-	out = make([]byte, int(size))
-	_, err = io.ReadAtLeast(r.src, out, int(size))
-	return
+	// Copy into a new byte array, in case the underlying buffer is changed after
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, nil
 }
 
 // ReadStrings reads an array of strings
 func (r *Reader) ReadStrings() ([]string, error) {
-	length, err := r.ReadUvarint()
+	length, capacity, err := r.readLength(1)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]string, length)
-	for i := 0; i < int(length); i++ {
-		if out[i], err = r.ReadString(); err != nil {
+	out := make([]string, 0, capacity)
+	for i := 0; i < length; i++ {
+		v, err := r.ReadString()
+		if err != nil {
 			return nil, err
 		}
+		out = append(out, v)
 	}
 
 	return out, nil
 }
 
+// ReadSortedStrings reads a block of prefix-compressed strings and decodes all
+// of its strings.
+func (r *Reader) ReadSortedStrings() ([]string, error) {
+	b, err := r.sliceBytes() // Safe, since strings are copied out of the block
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := parseSortedBlock(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return block.Strings()
+}
+
+// ReadSortedBlock reads a block of prefix-compressed strings, without decoding
+// its strings, so that it can be searched.
+func (r *Reader) ReadSortedBlock() (*SortedBlock, error) {
+	b, err := r.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSortedBlock(b)
+}
+
 // --------------------------- Other Types ---------------------------
 
 // ReadRange reads the length of the array from the underlying stream and
@@ -457,3 +1208,176 @@ func (r *Reader) ReadBool() (bool, error) {
 	b, err := r.src.ReadByte()
 	return b == 1, err
 }
+
+// ReadBools reads an array of booleans packed as a bitset
+func (r *Reader) ReadBools() ([]bool, error) {
+	length, err := r.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if length > math.MaxInt-7 {
+		return nil, errSize
+	}
+
+	b, err := r.src.Slice((int(length) + 7) / 8)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]bool, length)
+	for i := range out {
+		out[i] = b[i>>3]&(1<<(i&7)) != 0
+	}
+	return out, nil
+}
+
+// ReadBitmap reads a bitmap represented as an array of uint64s
+func (r *Reader) ReadBitmap() ([]uint64, error) {
+	length, err := r.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if length > math.MaxInt/8 {
+		return nil, errSize
+	}
+
+	b, err := r.src.Slice(8 * int(length))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]uint64, length)
+	for i := range out {
+		v := b[8*i : 8*i+8]
+		out[i] = (uint64(v[0]) | uint64(v[1])<<8 | uint64(v[2])<<16 | uint64(v[3])<<24 |
+			uint64(v[4])<<32 | uint64(v[5])<<40 | uint64(v[6])<<48 | uint64(v[7])<<56)
+	}
+	return out, nil
+}
+
+// --------------------------- Skip ---------------------------
+
+// SkipN skips the next n bytes without decoding them. On a slice this simply
+// moves the offset, while on a stream the bytes are read and discarded.
+func (r *Reader) SkipN(n int) error {
+	return r.src.Skip(n)
+}
+
+// SkipUvarint skips a variable-size integer, either signed or unsigned
+func (r *Reader) SkipUvarint() error {
+	_, err := r.src.ReadUvarint()
+	return err
+}
+
+// SkipBytes skips a byte string prefixed with a variable-size integer size. This
+// can also be used to skip values written by WriteBinary or WriteText.
+func (r *Reader) SkipBytes() error {
+	return r.skipArray(1)
+}
+
+// SkipString skips a string prefixed with a variable-size integer size
+func (r *Reader) SkipString() error {
+	return r.skipArray(1)
+}
+
+// SkipStrings skips an array of strings
+func (r *Reader) SkipStrings() error {
+	return r.skipRange(r.SkipString)
+}
+
+// SkipUvarints skips an array of variable-size integers, either signed or unsigned
+func (r *Reader) SkipUvarints() error {
+	return r.skipRange(r.SkipUvarint)
+}
+
+// SkipUint8s skips an array of uint8s
+func (r *Reader) SkipUint8s() error {
+	return r.skipArray(1)
+}
+
+// SkipUint16s skips an array of uint16s
+func (r *Reader) SkipUint16s() error {
+	return r.skipArray(2)
+}
+
+// SkipUint32s skips an array of uint32s
+func (r *Reader) SkipUint32s() error {
+	return r.skipArray(4)
+}
+
+// SkipUint64s skips an array of uint64s
+func (r *Reader) SkipUint64s() error {
+	return r.skipArray(8)
+}
+
+// SkipUints skips an array of uints
+func (r *Reader) SkipUints() error {
+	return r.skipArray(8)
+}
+
+// SkipInt8s skips an array of int8s
+func (r *Reader) SkipInt8s() error {
+	return r.skipArray(1)
+}
+
+// SkipInt16s skips an array of int16s
+func (r *Reader) SkipInt16s() error {
+	return r.skipArray(2)
+}
+
+// SkipInt32s skips an array of int32s
+func (r *Reader) SkipInt32s() error {
+	return r.skipArray(4)
+}
+
+// SkipInt64s skips an array of int64s
+func (r *Reader) SkipInt64s() error {
+	return r.skipArray(8)
+}
+
+// SkipInts skips an array of ints
+func (r *Reader) SkipInts() error {
+	return r.skipArray(8)
+}
+
+// SkipFloat32s skips an array of float32s
+func (r *Reader) SkipFloat32s() error {
+	return r.skipArray(4)
+}
+
+// SkipFloat64s skips an array of float64s
+func (r *Reader) SkipFloat64s() error {
+	return r.skipArray(8)
+}
+
+// skipArray reads the length of an array of fixed-size elements and skips them
+func (r *Reader) skipArray(size int) error {
+	length, err := r.ReadUvarint()
+	if err != nil {
+		return err
+	}
+
+	if length > uint64(math.MaxInt/size) {
+		return errSize
+	}
+
+	return r.src.Skip(int(length) * size)
+}
+
+// skipRange reads the length of an array of variable-size elements and skips
+// each of them using the provided function.
+func (r *Reader) skipRange(fn func() error) error {
+	length, err := r.ReadUvarint()
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); i < length; i++ {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
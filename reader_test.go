@@ -6,6 +6,8 @@ package iostream
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -43,6 +45,232 @@ func TestNewReader(t *testing.T) {
 	assert.Equal(t, r1, r2)
 }
 
+func TestSkip(t *testing.T) {
+	tests := []struct {
+		encode func(*Writer) error
+		skip   func(*Reader) error
+	}{
+		{func(w *Writer) error { return w.WriteUint64(1) }, func(r *Reader) error { return r.SkipN(8) }},
+		{func(w *Writer) error { return w.WriteUvarint(1 << 40) }, func(r *Reader) error { return r.SkipUvarint() }},
+		{func(w *Writer) error { return w.WriteVarint(-1 << 40) }, func(r *Reader) error { return r.SkipUvarint() }},
+		{func(w *Writer) error { return w.WriteBytes([]byte("hello")) }, func(r *Reader) error { return r.SkipBytes() }},
+		{func(w *Writer) error { return w.WriteString("hello") }, func(r *Reader) error { return r.SkipString() }},
+		{func(w *Writer) error { return w.WriteStrings([]string{"a", "bc"}) }, func(r *Reader) error { return r.SkipStrings() }},
+		{func(w *Writer) error { return w.WriteUvarints([]uint64{1, 1 << 40}) }, func(r *Reader) error { return r.SkipUvarints() }},
+		{func(w *Writer) error { return w.WriteUint8s([]uint8{1, 2}) }, func(r *Reader) error { return r.SkipUint8s() }},
+		{func(w *Writer) error { return w.WriteUint16s([]uint16{1, 2}) }, func(r *Reader) error { return r.SkipUint16s() }},
+		{func(w *Writer) error { return w.WriteUint32s([]uint32{1, 2}) }, func(r *Reader) error { return r.SkipUint32s() }},
+		{func(w *Writer) error { return w.WriteUint64s([]uint64{1, 2}) }, func(r *Reader) error { return r.SkipUint64s() }},
+		{func(w *Writer) error { return w.WriteUints([]uint{1, 2}) }, func(r *Reader) error { return r.SkipUints() }},
+		{func(w *Writer) error { return w.WriteInt8s([]int8{1, 2}) }, func(r *Reader) error { return r.SkipInt8s() }},
+		{func(w *Writer) error { return w.WriteInt16s([]int16{1, 2}) }, func(r *Reader) error { return r.SkipInt16s() }},
+		{func(w *Writer) error { return w.WriteInt32s([]int32{1, 2}) }, func(r *Reader) error { return r.SkipInt32s() }},
+		{func(w *Writer) error { return w.WriteInt64s([]int64{1, 2}) }, func(r *Reader) error { return r.SkipInt64s() }},
+		{func(w *Writer) error { return w.WriteInts([]int{1, 2}) }, func(r *Reader) error { return r.SkipInts() }},
+		{func(w *Writer) error { return w.WriteFloat32s([]float32{1, 2}) }, func(r *Reader) error { return r.SkipFloat32s() }},
+		{func(w *Writer) error { return w.WriteFloat64s([]float64{1, 2}) }, func(r *Reader) error { return r.SkipFloat64s() }},
+	}
+
+	for _, tc := range tests {
+		buffer := bytes.NewBuffer(nil)
+		w := NewWriter(buffer)
+		assert.NoError(t, tc.encode(w))
+		assert.NoError(t, w.WriteString("end"))
+
+		for _, src := range []func([]byte) *Reader{
+			func(b []byte) *Reader { return NewReader(bytes.NewBuffer(b)) },
+			func(b []byte) *Reader { return NewReader(newNetworkSource(b)) },
+		} {
+			r := src(buffer.Bytes())
+			assert.NoError(t, tc.skip(r))
+
+			end, err := r.ReadString()
+			assert.NoError(t, err)
+			assert.Equal(t, "end", end)
+			assert.Equal(t, int64(buffer.Len()), r.Offset())
+
+			// Skipping a truncated value must fail
+			for size := 0; size < buffer.Len()-4; size++ {
+				assert.Error(t, tc.skip(src(buffer.Bytes()[:size])))
+			}
+		}
+	}
+}
+
+func TestSkipInvalid(t *testing.T) {
+	r := NewReader(bytes.NewBuffer([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}))
+	assert.Error(t, r.SkipUint64s())
+
+	for _, src := range []io.Reader{
+		bytes.NewBuffer([]byte{1, 2}),
+		newNetworkSource([]byte{1, 2}),
+	} {
+		r := NewReader(src)
+		assert.Equal(t, errSize, r.SkipN(-1))
+		assert.Equal(t, int64(0), r.Offset())
+	}
+}
+
+func TestPeek(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewWriter(buffer)
+	assert.NoError(t, w.WriteUvarint(300))
+	assert.NoError(t, w.WriteString("hello"))
+	assert.NoError(t, w.WriteBytes(make([]byte, 10000)))
+
+	for _, src := range []io.Reader{
+		bytes.NewBuffer(buffer.Bytes()),
+		bytes.NewReader(buffer.Bytes()),
+		newNetworkSource(buffer.Bytes()),
+	} {
+		r := NewReader(src)
+		tag, err := r.PeekUvarint()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(300), tag)
+		assert.Equal(t, int64(0), r.Offset())
+
+		b, err := r.Peek(2)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xac, 0x02}, b)
+
+		v, err := r.ReadUvarint()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(300), v)
+
+		size, err := r.PeekUvarint()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(5), size)
+
+		s, err := r.ReadString()
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", s)
+		assert.Equal(t, int64(8), r.Offset())
+
+		// Peek more than the default buffer size
+		b, err = r.Peek(10002)
+		assert.NoError(t, err)
+		assert.Len(t, b, 10002)
+		assert.Equal(t, int64(8), r.Offset())
+
+		out, err := r.ReadBytes()
+		assert.NoError(t, err)
+		assert.Len(t, out, 10000)
+
+		b, err = r.Peek(1)
+		assert.Error(t, err)
+		assert.Empty(t, b)
+	}
+}
+
+func TestPeekErrors(t *testing.T) {
+	for _, input := range [][]byte{
+		{},
+		{0x80},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+	} {
+		_, err := NewReader(bytes.NewBuffer(input)).PeekUvarint()
+		assert.Error(t, err)
+
+		_, err = NewReader(newNetworkSource(input)).PeekUvarint()
+		assert.Error(t, err)
+	}
+
+	b, err := NewReader(bytes.NewBuffer([]byte{0x1})).Peek(2)
+	assert.Error(t, err)
+	assert.Equal(t, []byte{0x1}, b)
+
+	for _, src := range []io.Reader{
+		bytes.NewBuffer([]byte{1, 2}),
+		newNetworkSource([]byte{1, 2}),
+	} {
+		r := NewReader(src)
+		_, err = r.Peek(-1)
+		assert.Equal(t, errSize, err)
+		assert.Equal(t, int64(0), r.Offset())
+	}
+}
+
+func TestReadBytesCorruptSize(t *testing.T) {
+	input := []byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x1, 0x2}
+	for _, src := range []io.Reader{
+		bytes.NewBuffer(input),
+		newNetworkSource(input),
+	} {
+		out, err := NewReader(src).ReadBytes()
+		assert.Error(t, err)
+		assert.Nil(t, out)
+	}
+}
+
+func TestReadArrayCorruptLength(t *testing.T) {
+	input := append([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, make([]byte, 5<<10)...)
+	for _, fn := range []func(*Reader) (interface{}, error){
+		func(r *Reader) (interface{}, error) { return r.ReadUint8s() },
+		func(r *Reader) (interface{}, error) { return r.ReadUint16s() },
+		func(r *Reader) (interface{}, error) { return r.ReadUint32s() },
+		func(r *Reader) (interface{}, error) { return r.ReadUint64s() },
+		func(r *Reader) (interface{}, error) { return r.ReadUints() },
+		func(r *Reader) (interface{}, error) { return r.ReadUvarints() },
+		func(r *Reader) (interface{}, error) { return r.ReadInt8s() },
+		func(r *Reader) (interface{}, error) { return r.ReadInt16s() },
+		func(r *Reader) (interface{}, error) { return r.ReadInt32s() },
+		func(r *Reader) (interface{}, error) { return r.ReadInt64s() },
+		func(r *Reader) (interface{}, error) { return r.ReadInts() },
+		func(r *Reader) (interface{}, error) { return r.ReadVarints() },
+		func(r *Reader) (interface{}, error) { return r.ReadFloat32s() },
+		func(r *Reader) (interface{}, error) { return r.ReadFloat64s() },
+		func(r *Reader) (interface{}, error) { return r.ReadStrings() },
+	} {
+		for _, src := range []io.Reader{
+			bytes.NewBuffer(input),
+			newNetworkSource(input),
+		} {
+			_, err := fn(NewReader(src))
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestReadUvarintsLarge(t *testing.T) {
+	input := make([]uint64, 3*maxPrealloc)
+	for i := range input {
+		input[i] = uint64(i)
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, NewWriter(&buffer).WriteUvarints(input))
+
+	out, err := NewReader(newNetworkSource(buffer.Bytes())).ReadUvarints()
+	assert.NoError(t, err)
+	assert.Equal(t, input, out)
+}
+
+func TestSlice(t *testing.T) {
+	input := []byte{1, 2, 3, 4, 5}
+	for _, src := range []io.Reader{
+		bytes.NewBuffer(input),
+		newNetworkSource(input),
+	} {
+		r := NewReader(src)
+		b, err := r.Slice(2)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{1, 2}, b)
+		assert.Equal(t, int64(2), r.Offset())
+
+		b, err = r.Slice(3)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{3, 4, 5}, b)
+
+		_, err = r.Slice(1)
+		assert.Error(t, err)
+		_, err = r.Slice(-1)
+		assert.Equal(t, errSize, err)
+	}
+
+	_, err := NewReader(bytes.NewBuffer(input)).Slice(math.MaxInt)
+	assert.Error(t, err)
+}
+
 // assertRead asserts a single read operation
 func assertRead(t *testing.T, name string, fn func(*Reader) (interface{}, error), input []byte, expect interface{}) {
 	assertReadN(t, name, fn, input, expect, 99999)
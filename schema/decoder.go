@@ -0,0 +1,166 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/kelindar/iostream"
+)
+
+// maxPrealloc is the maximum number of array elements to allocate upfront, so that
+// a corrupt length does not allocate more memory than the input can back.
+const maxPrealloc = 1024
+
+// decodeFn reads a value of a primitive kind
+type decodeFn func(r *iostream.Reader) (interface{}, error)
+
+// decoders maps the primitive kinds to their reader methods
+var decoders = map[Kind]decodeFn{
+	Uvarint:           func(r *iostream.Reader) (interface{}, error) { return r.ReadUvarint() },
+	Varint:            func(r *iostream.Reader) (interface{}, error) { return r.ReadVarint() },
+	Uint8:             func(r *iostream.Reader) (interface{}, error) { return r.ReadUint8() },
+	Uint16:            func(r *iostream.Reader) (interface{}, error) { return r.ReadUint16() },
+	Uint32:            func(r *iostream.Reader) (interface{}, error) { return r.ReadUint32() },
+	Uint64:            func(r *iostream.Reader) (interface{}, error) { return r.ReadUint64() },
+	Uint:              func(r *iostream.Reader) (interface{}, error) { return r.ReadUint() },
+	Int8:              func(r *iostream.Reader) (interface{}, error) { return r.ReadInt8() },
+	Int16:             func(r *iostream.Reader) (interface{}, error) { return r.ReadInt16() },
+	Int32:             func(r *iostream.Reader) (interface{}, error) { return r.ReadInt32() },
+	Int64:             func(r *iostream.Reader) (interface{}, error) { return r.ReadInt64() },
+	Int:               func(r *iostream.Reader) (interface{}, error) { return r.ReadInt() },
+	Float16:           func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat16() },
+	BFloat16:          func(r *iostream.Reader) (interface{}, error) { return r.ReadBFloat16() },
+	Float32:           func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat32() },
+	Float64:           func(r *iostream.Reader) (interface{}, error) { return r.ReadFloat64() },
+	Bool:              func(r *iostream.Reader) (interface{}, error) { return r.ReadBool() },
+	String:            func(r *iostream.Reader) (interface{}, error) { return r.ReadString() },
+	Bytes:             func(r *iostream.Reader) (interface{}, error) { return r.ReadBytes() },
+	Time:              func(r *iostream.Reader) (interface{}, error) { return r.ReadTime() },
+	Duration:          func(r *iostream.Reader) (interface{}, error) { return r.ReadDuration() },
+	BigInt:            func(r *iostream.Reader) (interface{}, error) { return r.ReadBigInt() },
+	BigFloat:          func(r *iostream.Reader) (interface{}, error) { return r.ReadBigFloat() },
+	BigRat:            func(r *iostream.Reader) (interface{}, error) { return r.ReadBigRat() },
+	DeltaUvarints:     func(r *iostream.Reader) (interface{}, error) { return r.ReadDeltaUvarints() },
+	DeltaVarints:      func(r *iostream.Reader) (interface{}, error) { return r.ReadDeltaVarints() },
+	PackedUint32s:     func(r *iostream.Reader) (interface{}, error) { return r.ReadPackedUint32s() },
+	GorillaFloat64s:   func(r *iostream.Reader) (interface{}, error) { return r.ReadGorillaFloat64s() },
+	GorillaTimestamps: func(r *iostream.Reader) (interface{}, error) { return r.ReadGorillaTimestamps() },
+	RLEInt64s:         func(r *iostream.Reader) (interface{}, error) { return r.ReadRLEInt64s() },
+	RLEUint8s:         func(r *iostream.Reader) (interface{}, error) { return r.ReadRLEUint8s() },
+	AutoInt64s:        func(r *iostream.Reader) (interface{}, error) { return r.ReadAutoInt64s() },
+	AutoUint8s:        func(r *iostream.Reader) (interface{}, error) { return r.ReadAutoUint8s() },
+	Bools:             func(r *iostream.Reader) (interface{}, error) { return r.ReadBools() },
+	Bitmap:            func(r *iostream.Reader) (interface{}, error) { return r.ReadBitmap() },
+	SortedStrings:     func(r *iostream.Reader) (interface{}, error) { return r.ReadSortedStrings() },
+}
+
+// Decoder represents a decoder of records described by a schema
+type Decoder struct {
+	record *Type
+	src    *iostream.Reader
+	depth  int // The nesting of the value being decoded
+}
+
+// NewDecoder creates a new decoder of the record, which must be part of a valid
+// schema, either parsed or created with New.
+func NewDecoder(record *Type, src *iostream.Reader) *Decoder {
+	return &Decoder{
+		record: record,
+		src:    src,
+	}
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (d *Decoder) Offset() int64 {
+	return d.src.Offset()
+}
+
+// Decode reads the next record. Primitives are decoded into the type returned by
+// the corresponding iostream.Reader method, such as uint32 or []int64, arrays into
+// []interface{}, optional values into either nil or their value and records into
+// map[string]interface{}. If decoding fails, the error names the offending field,
+// and io.EOF is returned as-is when there are no more records.
+func (d *Decoder) Decode() (map[string]interface{}, error) {
+	offset := d.src.Offset()
+	out, err := d.decodeRecord(d.record)
+	if errors.Is(err, io.EOF) && d.src.Offset() == offset {
+		return nil, io.EOF
+	}
+	return out, err
+}
+
+// decode decodes a value of the type
+func (d *Decoder) decode(t *Type) (interface{}, error) {
+	switch t.Kind {
+	case Record:
+		return d.decodeRecord(t)
+
+	case Optional:
+		present, err := d.src.ReadBool()
+		if err != nil || !present {
+			return nil, err
+		}
+
+		if d.depth >= maxDepth {
+			return nil, errDepth
+		}
+
+		d.depth++
+		defer func() { d.depth-- }()
+		return d.decode(t.Elem)
+
+	case Array:
+		length, err := d.src.ReadUvarint()
+		switch {
+		case err != nil:
+			return nil, err
+		case d.depth >= maxDepth:
+			return nil, errDepth
+		}
+
+		d.depth++
+		defer func() { d.depth-- }()
+		out := make([]interface{}, 0, prealloc(length))
+		for i := uint64(0); i < length; i++ {
+			v, err := d.decode(t.Elem)
+			if err != nil {
+				return nil, withPath("["+strconv.FormatUint(i, 10)+"]", err)
+			}
+			out = append(out, v)
+		}
+		return out, nil
+
+	default:
+		decode, ok := decoders[t.Kind]
+		if !ok {
+			return nil, fmt.Errorf("schema: unknown type %q", t.Kind)
+		}
+		return decode(d.src)
+	}
+}
+
+// decodeRecord decodes the fields of a record, in order
+func (d *Decoder) decodeRecord(t *Type) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(t.Fields))
+	for _, f := range t.Fields {
+		v, err := d.decode(f.Type)
+		if err != nil {
+			return nil, withPath(f.Name, err)
+		}
+		out[f.Name] = v
+	}
+	return out, nil
+}
+
+// prealloc returns the capacity to allocate upfront for the specified length
+func prealloc(length uint64) int {
+	if length > maxPrealloc {
+		return maxPrealloc
+	}
+	return int(length)
+}
@@ -0,0 +1,154 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+// testValues contains a decoded value for every primitive kind
+var testValues = map[Kind]interface{}{
+	Uvarint:           uint64(300),
+	Varint:            int64(-300),
+	Uint8:             uint8(1),
+	Uint16:            uint16(2),
+	Uint32:            uint32(3),
+	Uint64:            uint64(4),
+	Uint:              uint(5),
+	Int8:              int8(-1),
+	Int16:             int16(-2),
+	Int32:             int32(-3),
+	Int64:             int64(-4),
+	Int:               int(-5),
+	Float16:           float32(1.5),
+	BFloat16:          float32(-2),
+	Float32:           float32(0.25),
+	Float64:           float64(3.5),
+	Bool:              true,
+	String:            "hello",
+	Bytes:             []byte{1, 2},
+	Time:              time.Unix(1e9, 5).UTC(),
+	Duration:          time.Minute,
+	BigInt:            big.NewInt(-1 << 40),
+	BigFloat:          big.NewFloat(1.5),
+	BigRat:            big.NewRat(1, 3),
+	DeltaUvarints:     []uint64{1, 5, 10},
+	DeltaVarints:      []int64{-1, 5, -10},
+	PackedUint32s:     []uint32{1, 2, 3},
+	GorillaFloat64s:   []float64{1, 1.5, 2},
+	GorillaTimestamps: []int64{100, 200, 300},
+	RLEInt64s:         []int64{7, 7, 7},
+	RLEUint8s:         []uint8{1, 1},
+	AutoInt64s:        []int64{1, 2},
+	AutoUint8s:        []uint8{3, 3},
+	Bools:             []bool{true, false, true},
+	Bitmap:            []uint64{0xff},
+	SortedStrings:     []string{"a", "ab", "b"},
+}
+
+// newValuesRecord returns a record with a field for every primitive kind
+func newValuesRecord() *Type {
+	record := &Type{Kind: Record, Name: "Values"}
+	for kind := range decoders {
+		record.Fields = append(record.Fields, Field{Name: string(kind), Type: &Type{Kind: kind}})
+	}
+
+	sort.Slice(record.Fields, func(i, j int) bool {
+		return record.Fields[i].Name < record.Fields[j].Name
+	})
+	return record
+}
+
+func TestDecode(t *testing.T) {
+	record := newValuesRecord()
+	_, err := New(record)
+	assert.NoError(t, err)
+	assert.Len(t, testValues, len(decoders))
+
+	value := make(map[string]interface{}, len(testValues))
+	for kind, v := range testValues {
+		value[string(kind)] = v
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(record, iostream.NewWriter(buffer))
+	assert.NoError(t, enc.Encode(value))
+	assert.NoError(t, enc.Encode(value))
+
+	for _, src := range []io.Reader{
+		bytes.NewBuffer(buffer.Bytes()),
+		bytes.NewReader(buffer.Bytes()),
+	} {
+		dec := NewDecoder(record, iostream.NewReader(src))
+		for i := 0; i < 2; i++ {
+			out, err := dec.Decode()
+			assert.NoError(t, err)
+			assert.Equal(t, value, out)
+		}
+
+		_, err := dec.Decode()
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, int64(buffer.Len()), dec.Offset())
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	s, err := Parse(testSchema)
+	assert.NoError(t, err)
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewEncoder(s.Record("Shape"), iostream.NewWriter(buffer)).Encode(map[string]interface{}{
+		"id": 1, "name": "a", "tags": []string{"x"}, "matrix": []interface{}{},
+		"points": []interface{}{map[string]interface{}{"x": 1, "y": 2}},
+	}))
+
+	// Every truncated record must fail with an error other than io.EOF
+	data := buffer.Bytes()
+	for size := 1; size < len(data); size++ {
+		_, err := NewDecoder(s.Record("Shape"), iostream.NewReader(bytes.NewReader(data[:size]))).Decode()
+		assert.Error(t, err)
+		assert.NotEqual(t, io.EOF, err)
+	}
+
+	// The path of the failing value must be reported
+	_, err = NewDecoder(s.Record("Shape"), iostream.NewReader(bytes.NewReader(data[:17]))).Decode()
+	assert.Contains(t, err.Error(), "(at points[0].y)")
+
+	// Deeply nested values must fail instead of exhausting the stack
+	chain, err := Parse(`record Node { next *Node }`)
+	assert.NoError(t, err)
+	_, err = NewDecoder(chain.Record("Node"), iostream.NewReader(bytes.NewReader(bytes.Repeat([]byte{1}, 2*maxDepth)))).Decode()
+	assert.True(t, errors.Is(err, errDepth))
+
+	// Corrupt lengths must fail without allocating the whole array upfront
+	corrupt := append([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, make([]byte, 5<<10)...)
+	for _, typ := range []*Type{{Kind: Array, Elem: &Type{Kind: Uint8}}, {Kind: String}, {Kind: Bytes},
+		{Kind: DeltaUvarints}, {Kind: PackedUint32s}, {Kind: GorillaFloat64s}, {Kind: RLEInt64s},
+		{Kind: Bools}, {Kind: Bitmap}, {Kind: SortedStrings}} {
+		record := &Type{Kind: Record, Fields: []Field{{"a", typ}}}
+		for _, src := range []io.Reader{
+			bytes.NewBuffer(corrupt),
+			bytes.NewReader(corrupt),
+		} {
+			_, err := NewDecoder(record, iostream.NewReader(src)).Decode()
+			assert.Error(t, err, typ.Kind)
+			assert.NotEqual(t, io.EOF, err, typ.Kind)
+		}
+	}
+
+	// Unknown kinds of unvalidated types
+	record := &Type{Kind: Record, Fields: []Field{{"a", &Type{Kind: "unknown"}}}}
+	_, err = NewDecoder(record, iostream.NewReader(bytes.NewBuffer([]byte{1}))).Decode()
+	assert.Error(t, err)
+	assert.Error(t, NewEncoder(record, iostream.NewWriter(bytes.NewBuffer(nil))).Encode(map[string]interface{}{"a": 1}))
+}
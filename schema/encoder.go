@@ -0,0 +1,682 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelindar/iostream"
+)
+
+// The largest finite values of the floating point kinds smaller than a float64
+const (
+	maxFloat16  = 65504
+	maxBFloat16 = 0x1.fep127
+)
+
+// encodeFn writes a value of a primitive kind
+type encodeFn func(w *iostream.Writer, v interface{}) error
+
+// encoders maps the primitive kinds to their writer methods, converting the value
+// into the type the method expects.
+var encoders = map[Kind]encodeFn{
+	Uvarint: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUint(v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteUvarint(u)
+	},
+	Varint: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInt(v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteVarint(i)
+	},
+	Uint8: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUint(v, 8)
+		if err != nil {
+			return err
+		}
+		return w.WriteUint8(uint8(u))
+	},
+	Uint16: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUint(v, 16)
+		if err != nil {
+			return err
+		}
+		return w.WriteUint16(uint16(u))
+	},
+	Uint32: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUint(v, 32)
+		if err != nil {
+			return err
+		}
+		return w.WriteUint32(uint32(u))
+	},
+	Uint64: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUint(v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteUint64(u)
+	},
+	Uint: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUint(v, strconv.IntSize)
+		if err != nil {
+			return err
+		}
+		return w.WriteUint(uint(u))
+	},
+	Int8: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInt(v, 8)
+		if err != nil {
+			return err
+		}
+		return w.WriteInt8(int8(i))
+	},
+	Int16: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInt(v, 16)
+		if err != nil {
+			return err
+		}
+		return w.WriteInt16(int16(i))
+	},
+	Int32: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInt(v, 32)
+		if err != nil {
+			return err
+		}
+		return w.WriteInt32(int32(i))
+	},
+	Int64: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInt(v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteInt64(i)
+	},
+	Int: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInt(v, strconv.IntSize)
+		if err != nil {
+			return err
+		}
+		return w.WriteInt(int(i))
+	},
+	Float16: func(w *iostream.Writer, v interface{}) error {
+		f, err := toFloatOf(v, maxFloat16)
+		if err != nil {
+			return err
+		}
+		return w.WriteFloat16(float32(f))
+	},
+	BFloat16: func(w *iostream.Writer, v interface{}) error {
+		f, err := toFloatOf(v, maxBFloat16)
+		if err != nil {
+			return err
+		}
+		return w.WriteBFloat16(float32(f))
+	},
+	Float32: func(w *iostream.Writer, v interface{}) error {
+		f, err := toFloatOf(v, math.MaxFloat32)
+		if err != nil {
+			return err
+		}
+		return w.WriteFloat32(float32(f))
+	},
+	Float64: func(w *iostream.Writer, v interface{}) error {
+		f, err := toFloat(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteFloat64(f)
+	},
+	Bool: func(w *iostream.Writer, v interface{}) error {
+		b, ok := v.(bool)
+		if !ok {
+			return errType
+		}
+		return w.WriteBool(b)
+	},
+	String: func(w *iostream.Writer, v interface{}) error {
+		s, ok := v.(string)
+		if !ok {
+			return errType
+		}
+		return w.WriteString(s)
+	},
+	Bytes: func(w *iostream.Writer, v interface{}) error {
+		b, ok := v.([]byte)
+		if !ok {
+			return errType
+		}
+		return w.WriteBytes(b)
+	},
+	Time: func(w *iostream.Writer, v interface{}) error {
+		t, ok := v.(time.Time)
+		if !ok {
+			return errType
+		}
+		return w.WriteTime(t)
+	},
+	Duration: func(w *iostream.Writer, v interface{}) error {
+		d, err := toInt(v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteDuration(time.Duration(d))
+	},
+	BigInt: func(w *iostream.Writer, v interface{}) error {
+		b, ok := v.(*big.Int)
+		if !ok || b == nil {
+			return errType
+		}
+		return w.WriteBigInt(b)
+	},
+	BigFloat: func(w *iostream.Writer, v interface{}) error {
+		b, ok := v.(*big.Float)
+		if !ok || b == nil {
+			return errType
+		}
+		return w.WriteBigFloat(b)
+	},
+	BigRat: func(w *iostream.Writer, v interface{}) error {
+		b, ok := v.(*big.Rat)
+		if !ok || b == nil {
+			return errType
+		}
+		return w.WriteBigRat(b)
+	},
+	DeltaUvarints: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUints(v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteDeltaUvarints(u)
+	},
+	DeltaVarints: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInts(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteDeltaVarints(i)
+	},
+	PackedUint32s: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUints(v, 32)
+		if err != nil {
+			return err
+		}
+
+		out := make([]uint32, len(u))
+		for i := range u {
+			out[i] = uint32(u[i])
+		}
+		return w.WritePackedUint32s(out)
+	},
+	GorillaFloat64s: func(w *iostream.Writer, v interface{}) error {
+		f, err := toFloats(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteGorillaFloat64s(f)
+	},
+	GorillaTimestamps: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInts(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteGorillaTimestamps(i)
+	},
+	RLEInt64s: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInts(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteRLEInt64s(i)
+	},
+	RLEUint8s: func(w *iostream.Writer, v interface{}) error {
+		b, err := toBytes(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteRLEUint8s(b)
+	},
+	AutoInt64s: func(w *iostream.Writer, v interface{}) error {
+		i, err := toInts(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteAutoInt64s(i)
+	},
+	AutoUint8s: func(w *iostream.Writer, v interface{}) error {
+		b, err := toBytes(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteAutoUint8s(b)
+	},
+	Bools: func(w *iostream.Writer, v interface{}) error {
+		b, err := toBools(v)
+		if err != nil {
+			return err
+		}
+		return w.WriteBools(b)
+	},
+	Bitmap: func(w *iostream.Writer, v interface{}) error {
+		u, err := toUints(v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteBitmap(u)
+	},
+	SortedStrings: func(w *iostream.Writer, v interface{}) error {
+		s, err := toStrings(v)
+		switch {
+		case err != nil:
+			return err
+		case !sort.StringsAreSorted(s):
+			return errUnsorted
+		}
+		return w.WriteSortedStrings(s)
+	},
+}
+
+// Encoder represents an encoder of records described by a schema
+type Encoder struct {
+	record *Type
+	out    *iostream.Writer
+	buffer *bytes.Buffer
+	value  *iostream.Writer
+	depth  int // The nesting of the value being encoded
+}
+
+// NewEncoder creates a new encoder of the record, which must be part of a valid
+// schema, either parsed or created with New.
+func NewEncoder(record *Type, out *iostream.Writer) *Encoder {
+	buffer := bytes.NewBuffer(nil)
+	return &Encoder{
+		record: record,
+		out:    out,
+		buffer: buffer,
+		value:  iostream.NewWriter(buffer),
+	}
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (e *Encoder) Offset() int64 {
+	return e.out.Offset()
+}
+
+// Encode validates the value against the record and writes it. Every field of
+// the record must be present, except for optional ones, and the map must not
+// contain any other key. Numbers are accepted as any Go integer or float type
+// as long as they fit in the field without losing their integer part. If the
+// value does not match, nothing is written and the error names the offending
+// field.
+func (e *Encoder) Encode(v map[string]interface{}) error {
+	e.buffer.Reset()
+	if err := e.encodeRecord(e.record, v); err != nil {
+		return err
+	}
+
+	_, err := e.out.Write(e.buffer.Bytes())
+	return err
+}
+
+// encode encodes a value of the type
+func (e *Encoder) encode(t *Type, v interface{}) error {
+	switch t.Kind {
+	case Record:
+		record, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch(t, v)
+		}
+		return e.encodeRecord(t, record)
+
+	case Optional:
+		if err := e.value.WriteBool(v != nil); err != nil || v == nil {
+			return err
+		}
+
+		if e.depth >= maxDepth {
+			return errDepth
+		}
+
+		e.depth++
+		defer func() { e.depth-- }()
+		return e.encode(t.Elem, v)
+
+	case Array:
+		rv := reflect.ValueOf(v)
+		switch {
+		case rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array:
+			return mismatch(t, v)
+		case e.depth >= maxDepth:
+			return errDepth
+		}
+
+		e.depth++
+		defer func() { e.depth-- }()
+
+		if err := e.value.WriteUvarint(uint64(rv.Len())); err != nil {
+			return err
+		}
+
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.encode(t.Elem, rv.Index(i).Interface()); err != nil {
+				return withPath("["+strconv.Itoa(i)+"]", err)
+			}
+		}
+		return nil
+
+	default:
+		encode, ok := encoders[t.Kind]
+		if !ok {
+			return fmt.Errorf("schema: unknown type %q", t.Kind)
+		}
+
+		switch err := encode(e.value, v); err {
+		case errType:
+			return mismatch(t, v)
+		case errRange:
+			return fmt.Errorf("%w, %v does not fit in %s", errRange, v, t)
+		default:
+			return err
+		}
+	}
+}
+
+// encodeRecord encodes the fields of a record, in order
+func (e *Encoder) encodeRecord(t *Type, v map[string]interface{}) error {
+	found := 0
+	for _, f := range t.Fields {
+		value, ok := v[f.Name]
+		switch {
+		case ok:
+			found++
+		case f.Type.Kind != Optional:
+			return withPath(f.Name, errMissing)
+		}
+
+		if err := e.encode(f.Type, value); err != nil {
+			return withPath(f.Name, err)
+		}
+	}
+
+	// Report the first unknown key, if any
+	if found < len(v) {
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			if t.Field(k) == nil {
+				keys = append(keys, k)
+			}
+		}
+
+		sort.Strings(keys)
+		return withPath(keys[0], errUnknown)
+	}
+	return nil
+}
+
+// --------------------------- Errors ---------------------------
+
+// pathError represents an error of a value located at a path within a record,
+// such as "points[2].x".
+type pathError struct {
+	path string
+	err  error
+}
+
+// Error returns the error message
+func (e *pathError) Error() string {
+	return fmt.Sprintf("%v (at %s)", e.err, e.path)
+}
+
+// Unwrap returns the underlying error
+func (e *pathError) Unwrap() error {
+	return e.err
+}
+
+// withPath prefixes the path of the error with a field name or an array index
+func withPath(prefix string, err error) error {
+	perr, ok := err.(*pathError)
+	switch {
+	case !ok:
+		return &pathError{path: prefix, err: err}
+	case strings.HasPrefix(perr.path, "["):
+		return &pathError{path: prefix + perr.path, err: perr.err}
+	default:
+		return &pathError{path: prefix + "." + perr.path, err: perr.err}
+	}
+}
+
+// mismatch returns an error for a value which does not match the type
+func mismatch(t *Type, v interface{}) error {
+	return fmt.Errorf("%w, expected %s but got %T", errType, t, v)
+}
+
+// --------------------------- Conversions ---------------------------
+
+// toUint converts an integer, or a float without a fractional part, into an
+// unsigned integer which fits in the specified number of bits.
+func toUint(v interface{}, size int) (uint64, error) {
+	var out uint64
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		out = rv.Uint()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, errRange
+		}
+		out = uint64(rv.Int())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case f != math.Trunc(f):
+			return 0, errType
+		case f < 0 || f >= math.Ldexp(1, 64):
+			return 0, errRange
+		}
+		out = uint64(f)
+	default:
+		return 0, errType
+	}
+
+	if size < 64 && out >= 1<<uint(size) {
+		return 0, errRange
+	}
+	return out, nil
+}
+
+// toInt converts an integer, or a float without a fractional part, into a signed
+// integer which fits in the specified number of bits.
+func toInt(v interface{}, size int) (int64, error) {
+	var out int64
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, errRange
+		}
+		out = int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case f != math.Trunc(f):
+			return 0, errType
+		case f < math.MinInt64 || f >= math.Ldexp(1, 63):
+			return 0, errRange
+		}
+		out = int64(f)
+	default:
+		return 0, errType
+	}
+
+	if size < 64 && (out < -1<<uint(size-1) || out >= 1<<uint(size-1)) {
+		return 0, errRange
+	}
+	return out, nil
+}
+
+// toFloat converts any integer or float into a float64
+func toFloat(v interface{}) (float64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	default:
+		return 0, errType
+	}
+}
+
+// toFloatOf converts any integer or float into a float64 whose magnitude is at
+// most the specified maximum, so that it does not overflow to an infinity once
+// converted into a smaller float. Infinities and NaNs are kept as-is.
+func toFloatOf(v interface{}, max float64) (float64, error) {
+	f, err := toFloat(v)
+	switch {
+	case err != nil:
+		return 0, err
+	case !math.IsInf(f, 0) && math.Abs(f) > max:
+		return 0, errRange
+	default:
+		return f, nil
+	}
+}
+
+// eachOf calls the function for every element of a slice or an array
+func eachOf(v interface{}, fn func(i int, elem interface{}) error) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return errType
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if err := fn(i, rv.Index(i).Interface()); err != nil {
+			return withPath("["+strconv.Itoa(i)+"]", err)
+		}
+	}
+	return nil
+}
+
+// toUints converts a slice of numbers into unsigned integers of the specified size
+func toUints(v interface{}, size int) (out []uint64, err error) {
+	if u, ok := v.([]uint64); ok {
+		return u, nil
+	}
+
+	out = make([]uint64, lenOf(v))
+	err = eachOf(v, func(i int, elem interface{}) (err error) {
+		out[i], err = toUint(elem, size)
+		return
+	})
+	return
+}
+
+// toInts converts a slice of numbers into signed integers
+func toInts(v interface{}) (out []int64, err error) {
+	if i, ok := v.([]int64); ok {
+		return i, nil
+	}
+
+	out = make([]int64, lenOf(v))
+	err = eachOf(v, func(i int, elem interface{}) (err error) {
+		out[i], err = toInt(elem, 64)
+		return
+	})
+	return
+}
+
+// toFloats converts a slice of numbers into floats
+func toFloats(v interface{}) (out []float64, err error) {
+	if f, ok := v.([]float64); ok {
+		return f, nil
+	}
+
+	out = make([]float64, lenOf(v))
+	err = eachOf(v, func(i int, elem interface{}) (err error) {
+		out[i], err = toFloat(elem)
+		return
+	})
+	return
+}
+
+// toBytes converts a slice of numbers into bytes
+func toBytes(v interface{}) (out []byte, err error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+
+	out = make([]byte, lenOf(v))
+	err = eachOf(v, func(i int, elem interface{}) error {
+		u, err := toUint(elem, 8)
+		out[i] = byte(u)
+		return err
+	})
+	return
+}
+
+// toBools converts a slice of booleans
+func toBools(v interface{}) (out []bool, err error) {
+	if b, ok := v.([]bool); ok {
+		return b, nil
+	}
+
+	out = make([]bool, lenOf(v))
+	err = eachOf(v, func(i int, elem interface{}) error {
+		b, ok := elem.(bool)
+		if !ok {
+			return errType
+		}
+
+		out[i] = b
+		return nil
+	})
+	return
+}
+
+// toStrings converts a slice of strings
+func toStrings(v interface{}) (out []string, err error) {
+	if s, ok := v.([]string); ok {
+		return s, nil
+	}
+
+	out = make([]string, lenOf(v))
+	err = eachOf(v, func(i int, elem interface{}) (err error) {
+		s, ok := elem.(string)
+		if !ok {
+			return errType
+		}
+
+		out[i] = s
+		return nil
+	})
+	return
+}
+
+// lenOf returns the length of a slice or an array, or zero for any other value
+func lenOf(v interface{}) int {
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv.Len()
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/kelindar/iostream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	s, err := Parse(testSchema)
+	assert.NoError(t, err)
+
+	buffer := bytes.NewBuffer(nil)
+	enc := NewEncoder(s.Record("Shape"), iostream.NewWriter(buffer))
+	assert.NoError(t, enc.Encode(map[string]interface{}{
+		"id":     uint64(300),
+		"name":   "square",
+		"tags":   []string{"a", "b"},
+		"points": []interface{}{map[string]interface{}{"x": 1.5, "y": 2}},
+		"parent": map[string]interface{}{
+			"id":     1,
+			"name":   "root",
+			"tags":   []interface{}{},
+			"points": []interface{}{},
+			"matrix": [][]interface{}{{int32(7), nil}},
+		},
+		"matrix": []interface{}{},
+	}))
+
+	// Must be identical to the same record written by hand
+	expect := bytes.NewBuffer(nil)
+	w := iostream.NewWriter(expect)
+	w.WriteUvarint(300)
+	w.WriteString("square")
+	w.WriteStrings([]string{"a", "b"})
+	w.WriteRange(1, func(i int, w *iostream.Writer) error {
+		w.WriteFloat64(1.5)
+		return w.WriteFloat64(2)
+	})
+	w.WriteBool(true)
+	w.WriteUvarint(1)
+	w.WriteString("root")
+	w.WriteStrings(nil)
+	w.WriteUvarint(0)
+	w.WriteBool(false)
+	w.WriteUvarint(1)
+	w.WriteUvarint(2)
+	w.WriteBool(true)
+	w.WriteInt32(7)
+	w.WriteBool(false)
+	w.WriteUvarint(0)
+
+	assert.Equal(t, expect.Bytes(), buffer.Bytes())
+	assert.Equal(t, int64(expect.Len()), enc.Offset())
+}
+
+func TestEncodeJSON(t *testing.T) {
+	s, err := Parse(testSchema)
+	assert.NoError(t, err)
+
+	var value map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"id": 42, "name": "json", "tags": ["x"], "matrix": [[1, null], []],
+		"points": [{"x": 0.5, "y": -1}]
+	}`), &value))
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewEncoder(s.Record("Shape"), iostream.NewWriter(buffer)).Encode(value))
+
+	out, err := NewDecoder(s.Record("Shape"), iostream.NewReader(buffer)).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":     uint64(42),
+		"name":   "json",
+		"tags":   []interface{}{"x"},
+		"points": []interface{}{map[string]interface{}{"x": 0.5, "y": -1.0}},
+		"parent": nil,
+		"matrix": []interface{}{[]interface{}{int32(1), nil}, []interface{}{}},
+	}, out)
+}
+
+func TestEncodeErrors(t *testing.T) {
+	s, err := Parse(testSchema + `
+	record Values {
+		u8  uint8
+		i8  int8
+		u   uvarint
+		i   varint
+		f   float32
+		b   bool
+		s   sortedstrings
+		p   packeduint32s
+		r   rleint64s
+		o   bools
+		h   float16
+		bf  bfloat16
+	}`)
+	assert.NoError(t, err)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"u8": 1, "i8": -1, "u": uint(1), "i": 1.0, "f": 1, "b": true,
+			"s": []string{"a", "b"}, "p": []int{1}, "r": []interface{}{1}, "o": []interface{}{true},
+			"h": 65504, "bf": -1e38,
+		}
+	}
+
+	tests := []struct {
+		field  string
+		value  interface{}
+		expect string
+	}{
+		{"u8", 256, "does not fit in uint8 (at u8)"},
+		{"u8", -1, "does not fit in uint8 (at u8)"},
+		{"u8", 1.5, "expected uint8 but got float64 (at u8)"},
+		{"u8", "1", "expected uint8 but got string (at u8)"},
+		{"i8", 128, "does not fit in int8 (at i8)"},
+		{"i8", uint64(math.MaxUint64), "does not fit in int8 (at i8)"},
+		{"i8", -129.0, "does not fit in int8 (at i8)"},
+		{"u", math.Inf(1), "does not fit in uvarint (at u)"},
+		{"u", math.NaN(), "expected uvarint but got float64 (at u)"},
+		{"i", 1e19, "does not fit in varint (at i)"},
+		{"f", true, "expected float32 but got bool (at f)"},
+		{"f", 1e39, "does not fit in float32 (at f)"},
+		{"h", 65536, "does not fit in float16 (at h)"},
+		{"h", -1e5, "does not fit in float16 (at h)"},
+		{"bf", 3.4e38, "does not fit in bfloat16 (at bf)"},
+		{"b", 1, "expected bool but got int (at b)"},
+		{"s", []string{"b", "a"}, "strings are not sorted (at s)"},
+		{"s", []interface{}{"a", 1}, "does not match the type (at s[1])"},
+		{"p", []int{1 << 40}, "value is out of range (at p[0])"},
+		{"p", 1, "expected packeduint32s but got int (at p)"},
+		{"r", []interface{}{1, 0.5}, "does not match the type (at r[1])"},
+		{"o", []interface{}{1}, "does not match the type (at o[0])"},
+	}
+
+	for _, tc := range tests {
+		value := valid()
+		value[tc.field] = tc.value
+
+		enc := NewEncoder(s.Record("Values"), iostream.NewWriter(bytes.NewBuffer(nil)))
+		err := enc.Encode(value)
+		if assert.Error(t, err, "%v", tc.value) {
+			assert.Contains(t, err.Error(), tc.expect)
+		}
+		assert.Equal(t, int64(0), enc.Offset())
+	}
+
+	// The valid value must succeed
+	assert.NoError(t, NewEncoder(s.Record("Values"), iostream.NewWriter(bytes.NewBuffer(nil))).Encode(valid()))
+
+	// Records with missing, unknown or mistyped fields
+	shape := map[string]interface{}{
+		"id": 1, "name": "a", "tags": []string{}, "matrix": []int{},
+		"points": []interface{}{map[string]interface{}{"x": 1, "y": 2}, map[string]interface{}{"x": "1", "y": 2}},
+	}
+
+	enc := NewEncoder(s.Record("Shape"), iostream.NewWriter(bytes.NewBuffer(nil)))
+	err = enc.Encode(shape)
+	assert.Contains(t, err.Error(), "expected float64 but got string (at points[1].x)")
+
+	shape["points"] = []interface{}{map[string]interface{}{"x": 1}}
+	assert.Contains(t, enc.Encode(shape).Error(), "missing field (at points[0].y)")
+
+	shape["points"] = []interface{}{map[string]interface{}{"x": 1, "y": 1, "z": 1, "a": 1}}
+	assert.Contains(t, enc.Encode(shape).Error(), "unknown field (at points[0].a)")
+
+	shape["points"] = []interface{}{1}
+	assert.Contains(t, enc.Encode(shape).Error(), "expected Point but got int (at points[0])")
+
+	shape["points"] = "none"
+	assert.Contains(t, enc.Encode(shape).Error(), "expected []Point but got string (at points)")
+	assert.Equal(t, int64(0), enc.Offset())
+
+	// Deeply nested values must fail instead of exhausting the stack
+	chain, err := Parse(`record Node { next *Node }`)
+	assert.NoError(t, err)
+	node := map[string]interface{}{}
+	for i := 0; i < 2*maxDepth; i++ {
+		node = map[string]interface{}{"next": node}
+	}
+	assert.True(t, errors.Is(NewEncoder(chain.Record("Node"), iostream.NewWriter(bytes.NewBuffer(nil))).Encode(node), errDepth))
+}
@@ -0,0 +1,197 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parse parses a schema in the text format. A schema is a list of records, each
+// declared as "record Name { ... }" with one "name type" field per line. A type is
+// either a primitive kind such as "uvarint" or "string", the name of a record, an
+// array "[]T" or an optional value "*T". Comments start with "//".
+func Parse(text string) (*Schema, error) {
+	p := &parser{tokens: tokenize(text)}
+	decls, err := p.parseRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	// Declare all of the records first, so that fields can refer to any of them
+	records := make([]*Type, 0, len(decls))
+	named := make(map[string]*Type, len(decls))
+	for _, decl := range decls {
+		if _, ok := named[decl.name.text]; ok {
+			return nil, decl.name.errorf("record %s is declared twice", decl.name.text)
+		}
+
+		t := &Type{Kind: Record, Name: decl.name.text}
+		named[t.Name] = t
+		records = append(records, t)
+	}
+
+	for i, decl := range decls {
+		for _, f := range decl.fields {
+			t, err := resolveType(f.typ, named)
+			if err != nil {
+				return nil, err
+			}
+
+			records[i].Fields = append(records[i].Fields, Field{Name: f.name.text, Type: t})
+		}
+	}
+
+	return New(records...)
+}
+
+// resolveType resolves a type expression such as "[]*Point"
+func resolveType(expr token, named map[string]*Type) (*Type, error) {
+	switch {
+	case strings.HasPrefix(expr.text, "[]"):
+		elem, err := resolveType(token{expr.text[2:], expr.line}, named)
+		return ArrayOf(elem), err
+	case strings.HasPrefix(expr.text, "*"):
+		elem, err := resolveType(token{expr.text[1:], expr.line}, named)
+		return OptionalOf(elem), err
+	case isKind(expr.text):
+		return &Type{Kind: Kind(expr.text)}, nil
+	default:
+		if t, ok := named[expr.text]; ok {
+			return t, nil
+		}
+		return nil, expr.errorf("unknown type %q", expr.text)
+	}
+}
+
+// --------------------------- Parser ---------------------------
+
+// token represents a single word of the text format
+type token struct {
+	text string
+	line int
+}
+
+// errorf returns an error located at the token
+func (t token) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("schema: line %d: %s", t.line, fmt.Sprintf(format, args...))
+}
+
+// tokenize splits the text into tokens, discarding the comments
+func tokenize(text string) []token {
+	var tokens []token
+	for i, line := range strings.Split(text, "\n") {
+		if at := strings.Index(line, "//"); at >= 0 {
+			line = line[:at]
+		}
+
+		// Braces are tokens on their own, even without whitespace around them
+		line = strings.NewReplacer("{", " { ", "}", " } ").Replace(line)
+		for _, word := range strings.Fields(line) {
+			tokens = append(tokens, token{word, i + 1})
+		}
+	}
+	return tokens
+}
+
+// parser represents a parser of the text format
+type parser struct {
+	tokens []token
+	next   int
+}
+
+// recordDecl represents a parsed, but not yet resolved record
+type recordDecl struct {
+	name   token
+	fields []fieldDecl
+}
+
+// fieldDecl represents a parsed, but not yet resolved field
+type fieldDecl struct {
+	name token
+	typ  token
+}
+
+// parseRecords parses the list of record declarations
+func (p *parser) parseRecords() ([]recordDecl, error) {
+	var out []recordDecl
+	for p.next < len(p.tokens) {
+		if _, err := p.expect("record"); err != nil {
+			return nil, err
+		}
+
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.expect("{"); err != nil {
+			return nil, err
+		}
+
+		decl := recordDecl{name: name}
+		for !p.peek("}") {
+			field, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+
+			typ, err := p.take()
+			switch {
+			case err != nil:
+				return nil, err
+			case typ.text == "{" || typ.text == "}":
+				return nil, typ.errorf("expected a type, got %q", typ.text)
+			}
+
+			decl.fields = append(decl.fields, fieldDecl{name: field, typ: typ})
+		}
+
+		p.next++ // Closing brace
+		out = append(out, decl)
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("schema: no records declared")
+	}
+	return out, nil
+}
+
+// take returns the next token
+func (p *parser) take() (token, error) {
+	if p.next >= len(p.tokens) {
+		line := 1
+		if len(p.tokens) > 0 {
+			line = p.tokens[len(p.tokens)-1].line
+		}
+		return token{}, token{line: line}.errorf("unexpected end of schema")
+	}
+
+	t := p.tokens[p.next]
+	p.next++
+	return t, nil
+}
+
+// peek returns whether the next token is the expected one
+func (p *parser) peek(text string) bool {
+	return p.next < len(p.tokens) && p.tokens[p.next].text == text
+}
+
+// expect consumes the next token, which must be the expected one
+func (p *parser) expect(text string) (token, error) {
+	t, err := p.take()
+	if err == nil && t.text != text {
+		err = t.errorf("expected %q, got %q", text, t.text)
+	}
+	return t, err
+}
+
+// ident consumes the next token, which must be an identifier
+func (p *parser) ident() (token, error) {
+	t, err := p.take()
+	if err == nil && !isIdent(t.text) {
+		err = t.errorf("expected a name, got %q", t.text)
+	}
+	return t, err
+}
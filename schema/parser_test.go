@@ -0,0 +1,73 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `
+// A point in space
+record Point {
+	x float64 // horizontal
+	y float64
+}
+
+record Shape {
+	id     uvarint
+	name   string
+	tags   []string
+	points []Point
+	parent *Shape
+	matrix [][]*int32
+}
+
+record Empty {}`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(testSchema)
+	assert.NoError(t, err)
+	assert.Len(t, s.Records(), 3)
+
+	shape := s.Record("Shape")
+	assert.Equal(t, Record, shape.Kind)
+	assert.Equal(t, s.Record("Point"), shape.Field("points").Type.Elem)
+	assert.Equal(t, shape, shape.Field("parent").Type.Elem)
+	assert.Equal(t, "[][]*int32", shape.Field("matrix").Type.String())
+	assert.Empty(t, s.Record("Empty").Fields)
+
+	// The text format must round-trip
+	again, err := Parse(s.String())
+	assert.NoError(t, err)
+	assert.Equal(t, s.String(), again.String())
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                                      "no records declared",
+		"// comment only":                       "no records declared",
+		"struct A {}":                           `line 1: expected "record", got "struct"`,
+		"record {}":                             `line 1: expected a name, got "{"`,
+		"record A":                              "line 1: unexpected end of schema",
+		"record A x":                            `line 1: expected "{", got "x"`,
+		"record A {\n x uint32":                 "line 2: unexpected end of schema",
+		"record A {\n x \n}":                    `line 3: expected a type, got "}"`,
+		"record A {\n x unknown\n}":             `line 2: unknown type "unknown"`,
+		"record A {\n x []\n}":                  `line 2: unknown type ""`,
+		"record A {\n 1x uint32\n}":             `line 2: expected a name, got "1x"`,
+		"record A {}\nrecord A {}":              "line 2: record A is declared twice",
+		"record A {\n x uint32\n x bool\n}":     "field x is declared twice in record A",
+		"record A {\n b B\n}\nrecord B { a A }": "contains itself",
+		"record uint32 {}":                      `invalid record name "uint32"`,
+	}
+
+	for text, expect := range tests {
+		_, err := Parse(text)
+		if assert.Error(t, err, text) {
+			assert.Contains(t, err.Error(), expect, text)
+		}
+	}
+}
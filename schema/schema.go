@@ -0,0 +1,336 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+// Package schema describes records in terms of iostream primitives and provides a
+// dynamic codec which encodes and decodes them from and to plain Go values, such as
+// map[string]interface{}, without compiling a Go type for each record. A schema can
+// be built as a Go value or parsed from a small text format:
+//
+//	// A point in space
+//	record Point {
+//		x float64
+//		y float64
+//	}
+//
+//	record Shape {
+//		name   string
+//		tags   []string
+//		points []Point
+//		parent *Shape
+//	}
+//
+// Fields are written one after another, in order, using the corresponding method
+// of iostream.Writer. An array ([]T) is written with its length followed by its
+// elements, exactly like WriteRange, and an optional value (*T) is written as a
+// boolean presence flag followed by the value when present.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	errType     = errors.New("schema: value does not match the type")
+	errRange    = errors.New("schema: value is out of range")
+	errMissing  = errors.New("schema: missing field")
+	errUnknown  = errors.New("schema: unknown field")
+	errUnsorted = errors.New("schema: strings are not sorted")
+	errDepth    = errors.New("schema: values are nested too deeply")
+)
+
+// maxDepth is the maximum nesting of optional values and arrays, such as a chain of
+// records referring to their parent, so that a corrupt input does not exhaust the stack.
+const maxDepth = 1024
+
+// Kind represents the kind of a type
+type Kind string
+
+// Primitive kinds, each named after the iostream method which reads and writes it
+const (
+	Uvarint           Kind = "uvarint"
+	Varint            Kind = "varint"
+	Uint8             Kind = "uint8"
+	Uint16            Kind = "uint16"
+	Uint32            Kind = "uint32"
+	Uint64            Kind = "uint64"
+	Uint              Kind = "uint"
+	Int8              Kind = "int8"
+	Int16             Kind = "int16"
+	Int32             Kind = "int32"
+	Int64             Kind = "int64"
+	Int               Kind = "int"
+	Float16           Kind = "float16"
+	BFloat16          Kind = "bfloat16"
+	Float32           Kind = "float32"
+	Float64           Kind = "float64"
+	Bool              Kind = "bool"
+	String            Kind = "string"
+	Bytes             Kind = "bytes"
+	Time              Kind = "time"
+	Duration          Kind = "duration"
+	BigInt            Kind = "bigint"
+	BigFloat          Kind = "bigfloat"
+	BigRat            Kind = "bigrat"
+	DeltaUvarints     Kind = "deltauvarints"
+	DeltaVarints      Kind = "deltavarints"
+	PackedUint32s     Kind = "packeduint32s"
+	GorillaFloat64s   Kind = "gorillafloat64s"
+	GorillaTimestamps Kind = "gorillatimestamps"
+	RLEInt64s         Kind = "rleint64s"
+	RLEUint8s         Kind = "rleuint8s"
+	AutoInt64s        Kind = "autoint64s"
+	AutoUint8s        Kind = "autouint8s"
+	Bools             Kind = "bools"
+	Bitmap            Kind = "bitmap"
+	SortedStrings     Kind = "sortedstrings"
+)
+
+// Composite kinds
+const (
+	Array    Kind = "array"    // A length followed by the elements
+	Optional Kind = "optional" // A presence flag followed by the value
+	Record   Kind = "record"   // A named list of fields
+)
+
+// Type represents a type of a schema
+type Type struct {
+	Kind   Kind    // The kind of the type
+	Name   string  // The name of a record
+	Elem   *Type   // The element type of an array or an optional value
+	Fields []Field // The fields of a record
+}
+
+// Field represents a field of a record
+type Field struct {
+	Name string // The name of the field
+	Type *Type  // The type of the field
+}
+
+// ArrayOf returns an array type of the element type.
+func ArrayOf(elem *Type) *Type {
+	return &Type{Kind: Array, Elem: elem}
+}
+
+// OptionalOf returns an optional type of the element type.
+func OptionalOf(elem *Type) *Type {
+	return &Type{Kind: Optional, Elem: elem}
+}
+
+// Field returns the field with the specified name, or nil if there is none.
+func (t *Type) Field(name string) *Field {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
+
+// String returns the type as written in the text format.
+func (t *Type) String() string {
+	switch t.Kind {
+	case Array:
+		return "[]" + t.Elem.String()
+	case Optional:
+		return "*" + t.Elem.String()
+	case Record:
+		return t.Name
+	default:
+		return string(t.Kind)
+	}
+}
+
+// --------------------------- Schema ---------------------------
+
+// Schema represents a validated set of records
+type Schema struct {
+	records []*Type
+	index   map[string]*Type
+}
+
+// New creates a schema from the records and validates it. Records which are only
+// referenced by the fields of other records are included as well.
+func New(records ...*Type) (*Schema, error) {
+	s := &Schema{index: make(map[string]*Type)}
+	for _, t := range records {
+		if t == nil || t.Kind != Record {
+			return nil, fmt.Errorf("schema: expected a record, got %v", t)
+		}
+
+		if err := s.register(t); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[*Type]bool)
+	for _, t := range records {
+		if err := s.validate(t, seen); err != nil {
+			return nil, err
+		}
+	}
+
+	// Records must not contain themselves, since they would be infinitely large
+	for _, t := range s.records {
+		if err := checkCycle(t, t, make(map[*Type]bool)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Record returns the record with the specified name, or nil if there is none.
+func (s *Schema) Record(name string) *Type {
+	return s.index[name]
+}
+
+// Records returns the records of the schema, in the order they were declared.
+func (s *Schema) Records() []*Type {
+	return s.records
+}
+
+// String returns the schema in the text format.
+func (s *Schema) String() string {
+	var sb strings.Builder
+	for i, t := range s.records {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+
+		fmt.Fprintf(&sb, "record %s {\n", t.Name)
+		for _, f := range t.Fields {
+			fmt.Fprintf(&sb, "\t%s %s\n", f.Name, f.Type)
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+// register adds the record into the index, unless it is already there.
+func (s *Schema) register(t *Type) error {
+	switch existing, ok := s.index[t.Name]; {
+	case ok && existing == t:
+		return nil
+	case ok:
+		return fmt.Errorf("schema: record %s is declared twice", t.Name)
+	case !isIdent(t.Name) || isKind(t.Name):
+		return fmt.Errorf("schema: invalid record name %q", t.Name)
+	}
+
+	s.index[t.Name] = t
+	s.records = append(s.records, t)
+	return nil
+}
+
+// validate validates the type and registers the records it refers to.
+func (s *Schema) validate(t *Type, seen map[*Type]bool) error {
+	if t == nil {
+		return fmt.Errorf("schema: missing type")
+	}
+
+	switch t.Kind {
+	case Array:
+		if t.Elem != nil && isEmpty(t.Elem, make(map[*Type]bool)) {
+			return fmt.Errorf("schema: array of %s whose elements are empty", t.Elem)
+		}
+		return s.validate(t.Elem, seen)
+	case Optional:
+		return s.validate(t.Elem, seen)
+	case Record:
+		if seen[t] {
+			return nil
+		}
+
+		seen[t] = true
+		if err := s.register(t); err != nil {
+			return err
+		}
+
+		names := make(map[string]bool, len(t.Fields))
+		for _, f := range t.Fields {
+			switch {
+			case !isIdent(f.Name):
+				return fmt.Errorf("schema: invalid field name %q in record %s", f.Name, t.Name)
+			case names[f.Name]:
+				return fmt.Errorf("schema: field %s is declared twice in record %s", f.Name, t.Name)
+			}
+
+			names[f.Name] = true
+			if err := s.validate(f.Type, seen); err != nil {
+				return fmt.Errorf("%w in field %s of record %s", err, f.Name, t.Name)
+			}
+		}
+		return nil
+	default:
+		if !isKind(string(t.Kind)) {
+			return fmt.Errorf("schema: unknown type %q", t.Kind)
+		}
+		return nil
+	}
+}
+
+// checkCycle returns an error if the root record is reachable from the record
+// through fields which are neither arrays nor optional values.
+func checkCycle(root, t *Type, visited map[*Type]bool) error {
+	for _, f := range t.Fields {
+		if f.Type.Kind != Record {
+			continue
+		}
+
+		switch {
+		case f.Type == root:
+			return fmt.Errorf("schema: record %s contains itself, use an optional or an array", root.Name)
+		case visited[f.Type]:
+			continue
+		}
+
+		visited[f.Type] = true
+		if err := checkCycle(root, f.Type, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmpty returns whether the values of the type are encoded with zero bytes, which
+// is the case of records without fields or whose fields are all empty records.
+func isEmpty(t *Type, visited map[*Type]bool) bool {
+	switch {
+	case t.Kind != Record:
+		return false
+	case visited[t]:
+		return true // Either already found empty, or a cycle which is rejected separately
+	}
+
+	visited[t] = true
+	for _, f := range t.Fields {
+		if f.Type == nil || !isEmpty(f.Type, visited) {
+			return false
+		}
+	}
+	return true
+}
+
+// isKind returns whether the name is the name of a primitive kind
+func isKind(name string) bool {
+	_, ok := decoders[Kind(name)]
+	return ok
+}
+
+// isIdent returns whether the name is a valid identifier
+func isIdent(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	point := &Type{Kind: Record, Name: "Point", Fields: []Field{
+		{"x", &Type{Kind: Float64}},
+		{"y", &Type{Kind: Float64}},
+	}}
+
+	shape := &Type{Kind: Record, Name: "Shape", Fields: []Field{
+		{"name", &Type{Kind: String}},
+		{"points", ArrayOf(point)},
+	}}
+	shape.Fields = append(shape.Fields, Field{"parent", OptionalOf(shape)})
+
+	s, err := New(shape)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Type{shape, point}, s.Records())
+	assert.Equal(t, point, s.Record("Point"))
+	assert.Nil(t, s.Record("Missing"))
+	assert.Equal(t, "[]Point", shape.Field("points").Type.String())
+	assert.Nil(t, shape.Field("missing"))
+	assert.Equal(t, "record Shape {\n\tname string\n\tpoints []Point\n\tparent *Shape\n}\n\n"+
+		"record Point {\n\tx float64\n\ty float64\n}\n", s.String())
+}
+
+func TestNewErrors(t *testing.T) {
+	str := &Type{Kind: String}
+	self := &Type{Kind: Record, Name: "Self"}
+	self.Fields = []Field{{"self", self}}
+	outer := &Type{Kind: Record, Name: "Outer"}
+	inner := &Type{Kind: Record, Name: "Inner", Fields: []Field{{"outer", outer}}}
+	outer.Fields = []Field{{"inner", inner}}
+	empty := &Type{Kind: Record, Name: "Empty"}
+	wrapper := &Type{Kind: Record, Name: "Wrapper", Fields: []Field{{"a", empty}, {"b", empty}}}
+
+	for _, records := range [][]*Type{
+		{nil},
+		{str},
+		{{Kind: Record, Name: ""}},
+		{{Kind: Record, Name: "1a"}},
+		{{Kind: Record, Name: "string"}},
+		{{Kind: Record, Name: "A"}, {Kind: Record, Name: "A"}},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a", str}, {"a", str}}}},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a-b", str}}}},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a", nil}}}},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a", &Type{Kind: "unknown"}}}}},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a", ArrayOf(nil)}}}},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a", &Type{Kind: Record, Name: "A"}}}}},
+		{self},
+		{outer},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a", ArrayOf(empty)}}}},
+		{{Kind: Record, Name: "A", Fields: []Field{{"a", OptionalOf(ArrayOf(wrapper))}}}},
+	} {
+		_, err := New(records...)
+		assert.Error(t, err)
+	}
+}
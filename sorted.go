@@ -0,0 +1,224 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+var (
+	errSortedBlock = errors.New("iostream: corrupt sorted strings block")
+	errInterval    = errors.New("iostream: restart interval must be positive")
+)
+
+// sortedInterval is the number of entries between two restart points
+const sortedInterval = 16
+
+// --------------------------- Sorted Strings Block ---------------------------
+
+// SortedBlock represents a block of prefix-compressed sorted strings. Every string
+// is stored as the length of the prefix it shares with the previous string, followed
+// by the remaining suffix. Every few entries, a string is stored in full in order
+// to provide a restart point, allowing to search the block without decoding it.
+type SortedBlock struct {
+	data     []byte   // The encoded entries
+	restarts []uint32 // The offsets of the restart points
+	count    int      // The number of entries
+	interval int      // The number of entries between restart points
+}
+
+// encodeSortedBlock encodes the strings into a sorted block with a restart point
+// every interval entries, in the format expected by parseSortedBlock. The interval
+// is capped to the number of strings, since a block has at least one restart point.
+func encodeSortedBlock(v []string, interval int) []byte {
+	if interval > len(v) {
+		interval = len(v)
+	}
+	if interval < 1 {
+		interval = 1
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	block := NewWriter(buffer)
+	_ = block.WriteUvarint(uint64(len(v)))
+	_ = block.WriteUvarint(uint64(interval))
+
+	// Write all of the entries, keeping track of where the restart points are
+	header := block.Offset()
+	restarts := make([]uint32, 0, (len(v)+interval-1)/interval)
+	for i, s := range v {
+		shared := 0
+		if i%interval == 0 {
+			restarts = append(restarts, uint32(block.Offset()-header))
+		} else {
+			shared = sharedPrefix(v[i-1], s)
+		}
+
+		_ = block.WriteUvarint(uint64(shared))
+		_ = block.WriteString(s[shared:])
+	}
+
+	for _, offset := range restarts {
+		_ = block.WriteUint32(offset)
+	}
+	return buffer.Bytes()
+}
+
+// parseSortedBlock parses a sorted block. The block keeps a reference to the
+// buffer provided, without copying it.
+func parseSortedBlock(b []byte) (*SortedBlock, error) {
+	src := newSliceSource(b)
+	count, err := src.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := src.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	// The interval is at most the number of strings, or 1 for an empty block
+	if count > uint64(len(b)) || interval == 0 || interval > count && interval > 1 {
+		return nil, errSortedBlock
+	}
+
+	// Restart points are at the end of the block
+	data := b[src.Offset():]
+	restarts := make([]uint32, (count+interval-1)/interval)
+
+	if len(data) < 4*len(restarts) {
+		return nil, errSortedBlock
+	}
+
+	table := data[len(data)-4*len(restarts):]
+	data = data[:len(data)-4*len(restarts)]
+	for i := range restarts {
+		restarts[i] = binary.LittleEndian.Uint32(table[4*i:])
+		if int(restarts[i]) > len(data) {
+			return nil, errSortedBlock
+		}
+	}
+
+	return &SortedBlock{
+		data:     data,
+		restarts: restarts,
+		count:    int(count),
+		interval: int(interval),
+	}, nil
+}
+
+// Len returns the number of strings in the block.
+func (b *SortedBlock) Len() int {
+	return b.count
+}
+
+// At returns the string at the specified index, decoding at most the entries
+// since the preceding restart point.
+func (b *SortedBlock) At(i int) (string, error) {
+	if i < 0 || i >= b.count {
+		return "", errSortedBlock
+	}
+
+	var key []byte
+	var err error
+	offset := int(b.restarts[i/b.interval])
+	for n := i % b.interval; n >= 0; n-- {
+		if key, offset, err = b.next(key, offset); err != nil {
+			return "", err
+		}
+	}
+
+	return string(key), nil
+}
+
+// Search searches for the key in a block of sorted strings and returns the index
+// of the first string greater or equal to the key, and whether it is equal to it.
+func (b *SortedBlock) Search(key string) (int, bool, error) {
+	var err error
+
+	// Find the first restart point with a string greater than the key and start
+	// scanning from the one before, since restart points are stored in full.
+	restart := sort.Search(len(b.restarts), func(i int) bool {
+		if err != nil {
+			return true
+		}
+
+		var s []byte
+		s, _, err = b.next(nil, int(b.restarts[i]))
+		return string(s) > key
+	})
+
+	switch {
+	case err != nil:
+		return 0, false, err
+	case b.count == 0:
+		return 0, false, nil
+	case restart > 0:
+		restart--
+	}
+
+	var s []byte
+	offset := int(b.restarts[restart])
+	for i := restart * b.interval; i < b.count; i++ {
+		if s, offset, err = b.next(s, offset); err != nil {
+			return 0, false, err
+		}
+
+		if string(s) >= key {
+			return i, string(s) == key, nil
+		}
+	}
+
+	return b.count, false, nil
+}
+
+// Strings decodes all of the strings of the block
+func (b *SortedBlock) Strings() ([]string, error) {
+	var key []byte
+	var err error
+	out := make([]string, b.count)
+	for i, offset := 0, 0; i < b.count; i++ {
+		if key, offset, err = b.next(key, offset); err != nil {
+			return nil, err
+		}
+
+		out[i] = string(key)
+	}
+	return out, nil
+}
+
+// next decodes the entry at the offset, given the previous key, and returns the
+// key along with the offset of the next entry. The previous key is overwritten.
+func (b *SortedBlock) next(prev []byte, offset int) ([]byte, int, error) {
+	if offset >= len(b.data) {
+		return nil, 0, errSortedBlock
+	}
+
+	shared, n1 := binary.Uvarint(b.data[offset:])
+	if n1 <= 0 || shared > uint64(len(prev)) {
+		return nil, 0, errSortedBlock
+	}
+
+	size, n2 := binary.Uvarint(b.data[offset+n1:])
+	offset += n1 + n2
+	if n2 <= 0 || size > uint64(len(b.data)-offset) {
+		return nil, 0, errSortedBlock
+	}
+
+	next := offset + int(size)
+	return append(prev[:shared], b.data[offset:next]...), next, nil
+}
+
+// sharedPrefix returns the length of the prefix shared by both strings
+func sharedPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
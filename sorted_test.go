@@ -0,0 +1,120 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedBlock(t *testing.T) {
+	input := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		input = append(input, fmt.Sprintf("key:%05d", i*2))
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	w := NewWriter(buffer)
+	assert.NoError(t, w.WriteSortedStrings(input))
+	assert.NoError(t, w.WriteSortedStrings(input))
+
+	r := NewReader(buffer)
+	block, err := r.ReadSortedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, len(input), block.Len())
+
+	// Exact lookups
+	for i, key := range input {
+		idx, found, err := block.Search(key)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, i, idx)
+
+		s, err := block.At(i)
+		assert.NoError(t, err)
+		assert.Equal(t, key, s)
+	}
+
+	// Lookups of missing keys
+	for _, key := range []string{"", "a", "key:00001", "key:00031", "key:01997", "key:01999", "z"} {
+		idx, found, err := block.Search(key)
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Equal(t, sort.SearchStrings(input, key), idx, key)
+	}
+
+	out, err := r.ReadSortedStrings()
+	assert.NoError(t, err)
+	assert.Equal(t, input, out)
+}
+
+func TestSortedBlockInterval(t *testing.T) {
+	input := []string{"apple", "applet", "apply", "banana", "band", "bandana", "can"}
+	for _, interval := range []int{1, 2, 3, 7, 100} {
+		buffer := bytes.NewBuffer(nil)
+		assert.NoError(t, NewWriter(buffer).WriteSortedStringsN(input, interval))
+
+		block, err := NewReader(buffer).ReadSortedBlock()
+		assert.NoError(t, err)
+		for i, key := range input {
+			idx, found, err := block.Search(key)
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, i, idx)
+		}
+	}
+
+	assert.Error(t, NewWriter(bytes.NewBuffer(nil)).WriteSortedStringsN(input, 0))
+}
+
+func TestSortedBlockEmpty(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteSortedStrings(nil))
+
+	block, err := NewReader(buffer).ReadSortedBlock()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, block.Len())
+
+	idx, found, err := block.Search("a")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 0, idx)
+
+	_, err = block.At(0)
+	assert.Error(t, err)
+}
+
+func TestSortedBlockCorrupt(t *testing.T) {
+	inputs := [][]byte{
+		{},
+		{0x1},
+		{0x1, 0x0},                               // zero interval
+		{0x1, 0x2, 0x0, 0x0, 0x0, 0x0},           // interval larger than count
+		{0x0, 0xff, 0xff, 0xff, 0xff, 0x0f},      // interval of an empty block
+		{0x1, 0x1, 0x0},                          // missing restart table
+		{0x1, 0x1, 0x5, 0x0, 0x0, 0x0},           // restart out of range
+		{0x2, 0x1, 0x0, 0x0, 0x0, 0x0},           // missing entries
+		{0x1, 0x1, 0x1, 0x0, 0x0, 0x0, 0x0, 0x0}, // shared prefix too long
+		{0x1, 0x1, 0x0, 0x5, 0x0, 0x0, 0x0, 0x0}, // suffix too long
+	}
+
+	for _, input := range inputs {
+		block, err := parseSortedBlock(input)
+		if err == nil {
+			_, err = block.Strings()
+		}
+		assert.Error(t, err, "%v", input)
+	}
+
+	block, err := parseSortedBlock([]byte{0x1, 0x1, 0x0, 0x5, 0x0, 0x0, 0x0, 0x0})
+	assert.NoError(t, err)
+	_, _, err = block.Search("a")
+	assert.Error(t, err)
+	_, err = block.At(0)
+	assert.Error(t, err)
+}
@@ -9,8 +9,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
-	"reflect"
-	"unsafe"
 )
 
 // MaxVarintLenN is the maximum length of a varint-encoded N-bit integer.
@@ -18,6 +16,10 @@ const (
 	maxVarintLen64 = 10 * 7
 )
 
+// maxScratch is the size above which the scratch buffer of a stream grows as the
+// bytes are read, rather than being allocated upfront.
+const maxScratch = 64 << 10
+
 var overflow = errors.New("binary: varint overflows a 64-bit integer")
 
 // source represents a required contract for a decoder to work properly
@@ -25,6 +27,8 @@ type source interface {
 	io.Reader
 	io.ByteReader
 	Slice(n int) (buffer []byte, err error)
+	Skip(n int) error
+	Peek(n int) ([]byte, error)
 	ReadUvarint() (uint64, error)
 	ReadVarint() (int64, error)
 	Offset() int64
@@ -93,7 +97,10 @@ func (r *sliceSource) ReadByte() (byte, error) {
 // returns a sub-slice pointing to the same array. Since this requires access
 // to the underlying data, this is only available for our default source.
 func (r *sliceSource) Slice(n int) ([]byte, error) {
-	if r.offset+int64(n) > int64(len(r.buffer)) {
+	switch {
+	case n < 0:
+		return nil, errSize
+	case int64(n) > int64(len(r.buffer))-r.offset:
 		return nil, io.EOF
 	}
 
@@ -102,6 +109,33 @@ func (r *sliceSource) Slice(n int) ([]byte, error) {
 	return r.buffer[cur:r.offset], nil
 }
 
+// Skip advances the source by n bytes, without reading them.
+func (r *sliceSource) Skip(n int) error {
+	switch {
+	case n < 0:
+		return errSize
+	case int64(n) > int64(len(r.buffer))-r.offset:
+		return io.EOF
+	}
+
+	r.offset += int64(n)
+	return nil
+}
+
+// Peek returns the next n bytes without advancing the source. If fewer than n
+// bytes are available, it returns them along with an error.
+func (r *sliceSource) Peek(n int) ([]byte, error) {
+	remaining := r.buffer[r.offset:]
+	switch {
+	case n < 0:
+		return nil, errSize
+	case n > len(remaining):
+		return remaining, io.EOF
+	}
+
+	return remaining[:n], nil
+}
+
 // ReadUvarint reads an encoded unsigned integer from r and returns it as a uint64.
 func (r *sliceSource) ReadUvarint() (uint64, error) {
 	var x uint64
@@ -177,13 +211,20 @@ func (r *streamSource) Read(b []byte) (int, error) {
 // ReadByte implements the io.ByteReader interface.
 func (r *streamSource) ReadByte() (byte, error) {
 	v, err := r.ByteReader.ReadByte()
-	r.offset++
+	if err == nil {
+		r.offset++
+	}
 	return v, err
 }
 
 // Slice selects a sub-slice of next bytes.
 func (r *streamSource) Slice(n int) ([]byte, error) {
-	if len(r.scratch) < n {
+	switch {
+	case n < 0:
+		return nil, errSize
+	case len(r.scratch) < n && n > maxScratch:
+		return r.sliceLarge(n)
+	case len(r.scratch) < n:
 		r.scratch = make([]byte, capacityFor(uint(n+1)))
 	}
 
@@ -193,6 +234,54 @@ func (r *streamSource) Slice(n int) ([]byte, error) {
 	return r.scratch[:n], err
 }
 
+// sliceLarge selects a large sub-slice of next bytes. Since the size may be corrupt,
+// the scratch buffer grows as the bytes are read rather than being allocated upfront,
+// so that it never holds much more than what the stream actually contains.
+func (r *streamSource) sliceLarge(n int) ([]byte, error) {
+	buffer := bytes.NewBuffer(r.scratch[:0])
+	m, err := io.CopyN(buffer, r.Reader, int64(n))
+	r.offset += m
+	r.scratch = buffer.Bytes()
+	if err == io.EOF && m > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return r.scratch, err
+}
+
+// Skip advances the source by n bytes by discarding them.
+func (r *streamSource) Skip(n int) error {
+	if n < 0 {
+		return errSize
+	}
+
+	m, err := io.CopyN(io.Discard, r.Reader, int64(n))
+	r.offset += m
+	return err
+}
+
+// Peek returns the next n bytes without advancing the source. Since this requires
+// an internal buffer, the stream gets wrapped with a buffered reader large enough
+// to hold n bytes, unless it already is.
+func (r *streamSource) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errSize
+	}
+
+	buffered, ok := r.Reader.(*bufio.Reader)
+	if !ok || buffered.Size() < n {
+		size := 4096
+		if n > size {
+			size = n
+		}
+
+		buffered = bufio.NewReaderSize(r.Reader, size)
+		r.Reader = buffered
+		r.ByteReader = buffered
+	}
+
+	return buffered.Peek(n)
+}
+
 // ReadUvarint reads an encoded unsigned integer from r and returns it as a uint64.
 func (r *streamSource) ReadUvarint() (uint64, error) {
 	return binary.ReadUvarint(r)
@@ -205,23 +294,6 @@ func (r *streamSource) ReadVarint() (int64, error) {
 
 // --------------------------- Convert Funcs ---------------------------
 
-// toString converts byte slice to a string without allocating.
-func toString(b *[]byte) string {
-	return *(*string)(unsafe.Pointer(b))
-}
-
-// toBytes converts a string to a byte slice without allocating.
-func toBytes(v string) (b []byte) {
-	strHeader := (*reflect.StringHeader)(unsafe.Pointer(&v))
-	byteHeader := (*reflect.SliceHeader)(unsafe.Pointer(&b))
-	byteHeader.Data = strHeader.Data
-
-	l := len(v)
-	byteHeader.Len = l
-	byteHeader.Cap = l
-	return
-}
-
 // capacityFor computes the next power of 2 for a given index
 func capacityFor(v uint) int {
 	v--
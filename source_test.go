@@ -6,24 +6,13 @@ package iostream
 import (
 	"bytes"
 	"io"
+	"math"
 	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestConvertString(t *testing.T) {
-	v := "hi there"
-
-	b := toBytes(v)
-	assert.NotEmpty(t, b)
-	assert.Equal(t, v, string(b))
-
-	o := toString(&b)
-	assert.NotEmpty(t, b)
-	assert.Equal(t, v, o)
-}
-
 func TestNewSource(t *testing.T) {
 	assert.IsType(t, &sliceSource{}, newSource(nil))
 	assert.IsType(t, &sliceSource{}, newSource(&sliceSource{}))
@@ -68,12 +57,42 @@ func TestReadByteEOF(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestStreamReadByteEOF(t *testing.T) {
+	src := newStreamSource(bytes.NewReader([]byte{1}))
+	_, err := src.ReadByte()
+	assert.NoError(t, err)
+	_, err = src.ReadByte()
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), src.Offset())
+}
+
 func TestSliceEOF(t *testing.T) {
 	src := newSliceSource([]byte{})
 	_, err := src.Slice(10)
 	assert.Error(t, err)
 }
 
+func TestStreamSliceLarge(t *testing.T) {
+	input := make([]byte, 3*maxScratch)
+	for i := range input {
+		input[i] = byte(i)
+	}
+
+	src := newStreamSource(newNetworkSource(input))
+	b, err := src.Slice(2 * maxScratch)
+	assert.NoError(t, err)
+	assert.Equal(t, input[:2*maxScratch], b)
+	assert.Equal(t, int64(2*maxScratch), src.Offset())
+
+	b, err = src.Slice(math.MaxInt)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, input[2*maxScratch:], b)
+	assert.Equal(t, int64(len(input)), src.Offset())
+
+	_, err = src.Slice(math.MaxInt)
+	assert.Equal(t, io.EOF, err)
+}
+
 // --------------------------- Fake Network Source ---------------------------
 
 type networkSource struct {
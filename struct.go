@@ -0,0 +1,208 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+var errStruct = errors.New("iostream: invalid type, expected a fixed-size value")
+
+// Kinds of operations of a struct plan
+const (
+	opBytes  = iota // A run of single bytes, copied as-is
+	opBool          // A boolean, written as 0 or 1
+	opUint16        // A 16-bit value in little-endian order
+	opUint32        // A 32-bit value in little-endian order
+	opUint64        // A 64-bit value in little-endian order
+	opPad           // A blank field, written as zeros and skipped on read
+)
+
+// plans caches a *structPlan (or nil for unsupported types) by reflect.Type
+var plans sync.Map
+
+// Size returns how many bytes WriteStruct would write for v, which must be a
+// fixed-size value, a slice of fixed-size values, or a pointer to either. If v
+// is not of such type, Size returns -1. This matches binary.Size.
+func Size(v interface{}) int {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if !value.IsValid() {
+		return -1
+	}
+
+	if value.Kind() == reflect.Slice {
+		if p := planOf(value.Type().Elem()); p != nil {
+			return p.size * value.Len()
+		}
+		return -1
+	}
+
+	if p := planOf(value.Type()); p != nil {
+		return p.size
+	}
+	return -1
+}
+
+// --------------------------- Struct Plan ---------------------------
+
+// structOp represents a single operation of a struct plan
+type structOp struct {
+	kind int     // The kind of operation
+	mem  uintptr // The offset of the value in memory
+	enc  int     // The offset of the value in the encoded buffer
+	n    int     // The number of bytes for opBytes and opPad
+}
+
+// structPlan represents a compiled list of operations which convert a fixed-size
+// type between its memory and its little-endian binary representation.
+type structPlan struct {
+	ops    []structOp
+	size   int     // The encoded size of a value
+	stride uintptr // The in-memory size of a value
+}
+
+// planOf returns a cached plan for the type, or nil if the type is not fixed-size.
+func planOf(t reflect.Type) *structPlan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*structPlan)
+	}
+
+	p := &structPlan{stride: t.Size()}
+	if !p.compile(t, 0) {
+		p = nil
+	}
+
+	plans.Store(t, p)
+	return p
+}
+
+// compile appends the operations for a value of the type located at the specified
+// memory offset, and reports whether the type is fixed-size.
+func (p *structPlan) compile(t reflect.Type, mem uintptr) bool {
+	switch t.Kind() {
+	case reflect.Bool:
+		p.append(opBool, mem, 1)
+	case reflect.Int8, reflect.Uint8:
+		p.append(opBytes, mem, 1)
+	case reflect.Int16, reflect.Uint16:
+		p.append(opUint16, mem, 2)
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		p.append(opUint32, mem, 4)
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		p.append(opUint64, mem, 8)
+	case reflect.Complex64:
+		p.append(opUint32, mem, 4)
+		p.append(opUint32, mem+4, 4)
+	case reflect.Complex128:
+		p.append(opUint64, mem, 8)
+		p.append(opUint64, mem+8, 8)
+	case reflect.Array:
+		for i := 0; i < t.Len(); i++ {
+			if !p.compile(t.Elem(), mem+uintptr(i)*t.Elem().Size()) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Name != "_" {
+				if !p.compile(field.Type, mem+field.Offset) {
+					return false
+				}
+				continue
+			}
+
+			// Blank fields still need to be a fixed size
+			blank := &structPlan{}
+			if !blank.compile(field.Type, 0) {
+				return false
+			}
+			p.append(opPad, mem+field.Offset, blank.size)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// append appends an operation, merging adjacent byte runs and paddings together.
+func (p *structPlan) append(kind int, mem uintptr, n int) {
+	enc := p.size
+	p.size += n
+	if kind == opBytes || kind == opPad {
+		if last := len(p.ops) - 1; last >= 0 && p.ops[last].kind == kind &&
+			p.ops[last].enc+p.ops[last].n == enc && (kind == opPad || p.ops[last].mem+uintptr(p.ops[last].n) == mem) {
+			p.ops[last].n += n
+			return
+		}
+	}
+
+	p.ops = append(p.ops, structOp{kind: kind, mem: mem, enc: enc, n: n})
+}
+
+// encode writes a value located at the pointer into the buffer.
+func (p *structPlan) encode(dst []byte, ptr unsafe.Pointer) {
+	for _, op := range p.ops {
+		at := unsafe.Add(ptr, op.mem)
+		switch op.kind {
+		case opBytes:
+			copy(dst[op.enc:op.enc+op.n], unsafe.Slice((*byte)(at), op.n))
+		case opBool:
+			dst[op.enc] = 0
+			if *(*bool)(at) {
+				dst[op.enc] = 1
+			}
+		case opUint16:
+			binary.LittleEndian.PutUint16(dst[op.enc:], *(*uint16)(at))
+		case opUint32:
+			binary.LittleEndian.PutUint32(dst[op.enc:], *(*uint32)(at))
+		case opUint64:
+			binary.LittleEndian.PutUint64(dst[op.enc:], *(*uint64)(at))
+		case opPad:
+			for i := op.enc; i < op.enc+op.n; i++ {
+				dst[i] = 0
+			}
+		}
+	}
+}
+
+// decode reads a value from the buffer into the memory located at the pointer.
+func (p *structPlan) decode(src []byte, ptr unsafe.Pointer) {
+	for _, op := range p.ops {
+		at := unsafe.Add(ptr, op.mem)
+		switch op.kind {
+		case opBytes:
+			copy(unsafe.Slice((*byte)(at), op.n), src[op.enc:op.enc+op.n])
+		case opBool:
+			*(*bool)(at) = src[op.enc] != 0
+		case opUint16:
+			*(*uint16)(at) = binary.LittleEndian.Uint16(src[op.enc:])
+		case opUint32:
+			*(*uint32)(at) = binary.LittleEndian.Uint32(src[op.enc:])
+		case opUint64:
+			*(*uint64)(at) = binary.LittleEndian.Uint64(src[op.enc:])
+		}
+	}
+}
+
+// batchOf returns how many values of the plan fit in a single chunk.
+func (p *structPlan) batchOf(count int) int {
+	batch := count
+	if p.size > 0 {
+		batch = chunkSize / p.size
+	}
+
+	if batch < 1 {
+		batch = 1
+	}
+
+	if batch > count {
+		batch = count
+	}
+	return batch
+}
@@ -0,0 +1,177 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testHeader struct {
+	Magic   [4]byte
+	Version uint16
+	Flags   int8
+	Ready   bool
+	Size    int64
+	Scale   float32
+	_       [3]byte
+	Ratio   float64
+	Phase   complex64
+	Wave    complex128
+	Points  [2]testPoint
+	crc     uint32
+}
+
+type testPoint struct {
+	X, Y int16
+	Z    uint8
+}
+
+type testBlank struct {
+	A uint8
+	_ uint32
+	B uint8
+}
+
+type testLarge struct {
+	Data [chunkSize + 10]byte
+	Tail uint32
+}
+
+func newTestHeader(i int) testHeader {
+	return testHeader{
+		Magic:   [4]byte{'I', 'O', 'S', byte(i)},
+		Version: uint16(i + 1),
+		Flags:   -3,
+		Ready:   i%2 == 0,
+		Size:    -int64(i) << 40,
+		Scale:   1.5,
+		Ratio:   math.Pi,
+		Phase:   complex(1, -2),
+		Wave:    complex(math.Inf(1), 3),
+		Points:  [2]testPoint{{1, -1, 2}, {int16(i), 5, 255}},
+		crc:     0xdeadbeef,
+	}
+}
+
+func TestWriteStruct(t *testing.T) {
+	headers := make([]testHeader, 300)
+	for i := range headers {
+		headers[i] = newTestHeader(i)
+	}
+
+	large := &testLarge{Tail: 7}
+	large.Data[0], large.Data[chunkSize] = 1, 2
+
+	for _, v := range []interface{}{
+		headers[0], &headers[1], headers, &headers, headers[:0],
+		uint8(1), int16(-2), true, float64(1.5), complex64(1i),
+		[]uint32{1, 2, 3}, []bool{true, false}, &[3]int64{1, -1, 2},
+		testBlank{A: 1, B: 2}, struct{}{}, []struct{}{{}, {}},
+		large, []testLarge{*large, *large},
+	} {
+		expect := bytes.NewBuffer(nil)
+		assert.NoError(t, binary.Write(expect, binary.LittleEndian, v))
+
+		buffer := bytes.NewBuffer(nil)
+		w := NewWriter(buffer)
+		assert.NoError(t, w.WriteStruct(v))
+		assert.Equal(t, expect.Bytes(), buffer.Bytes())
+		assert.Equal(t, int64(expect.Len()), w.Offset())
+		assert.Equal(t, binary.Size(v), Size(v))
+	}
+}
+
+func TestWriteStructInvalid(t *testing.T) {
+	w := NewWriter(bytes.NewBuffer(nil))
+	for _, v := range []interface{}{
+		nil, 1, "hello", []string{"a"}, map[string]int{},
+		struct{ A *int }{}, struct{ _ string }{}, [2]uint{}, (*testPoint)(nil),
+	} {
+		assert.Error(t, w.WriteStruct(v), "%T", v)
+		assert.Equal(t, -1, Size(v), "%T", v)
+	}
+}
+
+func TestReadStruct(t *testing.T) {
+	headers := make([]testHeader, 300)
+	for i := range headers {
+		headers[i] = newTestHeader(i)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	w := NewWriter(buffer)
+	assert.NoError(t, w.WriteStruct(headers[7]))
+	assert.NoError(t, w.WriteStruct(headers))
+	assert.NoError(t, w.WriteStruct(&headers))
+	assert.NoError(t, w.WriteUint32(42))
+
+	for _, src := range []io.Reader{
+		bytes.NewBuffer(buffer.Bytes()),
+		newNetworkSource(buffer.Bytes()),
+	} {
+		r := NewReader(src)
+
+		var one testHeader
+		assert.NoError(t, r.ReadStruct(&one))
+		assert.Equal(t, headers[7], one)
+
+		many := make([]testHeader, len(headers))
+		assert.NoError(t, r.ReadStruct(many))
+		assert.Equal(t, headers, many)
+
+		again := make([]testHeader, len(headers))
+		assert.NoError(t, r.ReadStruct(&again))
+		assert.Equal(t, headers, again)
+
+		var tail uint32
+		assert.NoError(t, r.ReadStruct(&tail))
+		assert.Equal(t, uint32(42), tail)
+		assert.Equal(t, int64(buffer.Len()), r.Offset())
+	}
+}
+
+func TestReadStructCompat(t *testing.T) {
+	input := []byte{1, 0xff, 0xff, 0xff, 0xff, 2, 0x80, 0x01, 0x00}
+	var expect, actual struct {
+		A uint8
+		_ uint32
+		B bool
+		C [3]int8
+	}
+
+	expect.A, actual.A = 9, 9
+	assert.NoError(t, binary.Read(bytes.NewReader(input), binary.LittleEndian, &expect))
+	assert.NoError(t, NewReader(bytes.NewBuffer(input)).ReadStruct(&actual))
+	assert.Equal(t, expect, actual)
+	assert.True(t, actual.B)
+
+	large := new(testLarge)
+	b := make([]byte, Size(large))
+	b[chunkSize], b[len(b)-4] = 3, 4
+	assert.NoError(t, NewReader(newNetworkSource(b)).ReadStruct(large))
+	assert.Equal(t, uint8(3), large.Data[chunkSize])
+	assert.Equal(t, uint32(4), large.Tail)
+}
+
+func TestReadStructInvalid(t *testing.T) {
+	for _, v := range []interface{}{
+		nil, testPoint{}, (*testPoint)(nil), new(int), []string{}, new(struct{ A []byte }),
+		(*[]testPoint)(nil), new([]string),
+	} {
+		assert.Error(t, NewReader(bytes.NewBuffer(make([]byte, 100))).ReadStruct(v), "%T", v)
+	}
+
+	// Truncated input must fail
+	for size := 0; size < 5; size++ {
+		var v testPoint
+		assert.Error(t, NewReader(bytes.NewBuffer(make([]byte, size))).ReadStruct(&v))
+		assert.Error(t, NewReader(newNetworkSource(make([]byte, size))).ReadStruct(&v))
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+)
+
+var (
+	errField    = errors.New("iostream: field number must be positive")
+	errWireType = errors.New("iostream: field has a different wire type")
+	errDepth    = errors.New("iostream: records are nested too deeply")
+)
+
+// WireType represents the encoding of a tagged field, which allows a reader to skip
+// the fields it does not know about.
+type WireType uint8
+
+// Various wire types of tagged fields
+const (
+	WireUvarint WireType = iota // A variable-size unsigned integer
+	WireVarint                  // A variable-size signed integer
+	WireFixed32                 // A 32-bit value, such as uint32 or float32
+	WireFixed64                 // A 64-bit value, such as uint64 or float64
+	WireBytes                   // A byte string prefixed with a variable-size integer size
+	WireRecord                  // A nested record, terminated by an end marker
+)
+
+// tagEnd is the key which marks the end of a record
+const tagEnd = 0
+
+// maxDepth is the maximum nesting of records which can be skipped
+const maxDepth = 1024
+
+// --------------------------- Tag Writer ---------------------------
+
+// TagWriter represents a writer for self-describing records. Each field is written
+// with its field number and wire type, which allows readers to skip fields they do
+// not know about and to leave the missing fields with their default values.
+type TagWriter struct {
+	out    *Writer
+	buffer *bytes.Buffer
+	value  *Writer
+}
+
+// NewTagWriter creates a new tagged writer.
+func NewTagWriter(out io.Writer) *TagWriter {
+	buffer := bytes.NewBuffer(nil)
+	return &TagWriter{
+		out:    NewWriter(out),
+		buffer: buffer,
+		value:  NewWriter(buffer),
+	}
+}
+
+// Offset returns the number of bytes written through the underlying writer.
+func (w *TagWriter) Offset() int64 {
+	return w.out.Offset()
+}
+
+// writeTag writes the key of a field, composed of its number and wire type
+func (w *TagWriter) writeTag(field int, wire WireType) error {
+	if field <= 0 {
+		return errField
+	}
+	return w.out.WriteUvarint(uint64(field)<<3 | uint64(wire))
+}
+
+// WriteUvarint writes a field with a variable-size unsigned integer
+func (w *TagWriter) WriteUvarint(field int, v uint64) error {
+	if err := w.writeTag(field, WireUvarint); err != nil {
+		return err
+	}
+	return w.out.WriteUvarint(v)
+}
+
+// WriteVarint writes a field with a variable-size signed integer
+func (w *TagWriter) WriteVarint(field int, v int64) error {
+	if err := w.writeTag(field, WireVarint); err != nil {
+		return err
+	}
+	return w.out.WriteVarint(v)
+}
+
+// WriteBool writes a field with a boolean value
+func (w *TagWriter) WriteBool(field int, v bool) error {
+	if v {
+		return w.WriteUvarint(field, 1)
+	}
+	return w.WriteUvarint(field, 0)
+}
+
+// WriteUint32 writes a field with a uint32 value
+func (w *TagWriter) WriteUint32(field int, v uint32) error {
+	if err := w.writeTag(field, WireFixed32); err != nil {
+		return err
+	}
+	return w.out.WriteUint32(v)
+}
+
+// WriteUint64 writes a field with a uint64 value
+func (w *TagWriter) WriteUint64(field int, v uint64) error {
+	if err := w.writeTag(field, WireFixed64); err != nil {
+		return err
+	}
+	return w.out.WriteUint64(v)
+}
+
+// WriteFloat32 writes a field with a float32 value
+func (w *TagWriter) WriteFloat32(field int, v float32) error {
+	return w.WriteUint32(field, math.Float32bits(v))
+}
+
+// WriteFloat64 writes a field with a float64 value
+func (w *TagWriter) WriteFloat64(field int, v float64) error {
+	return w.WriteUint64(field, math.Float64bits(v))
+}
+
+// WriteString writes a field with a string value
+func (w *TagWriter) WriteString(field int, v string) error {
+	if err := w.writeTag(field, WireBytes); err != nil {
+		return err
+	}
+	return w.out.WriteString(v)
+}
+
+// WriteBytes writes a field with a byte slice value
+func (w *TagWriter) WriteBytes(field int, v []byte) error {
+	if err := w.writeTag(field, WireBytes); err != nil {
+		return err
+	}
+	return w.out.WriteBytes(v)
+}
+
+// WriteValue writes a field with a value encoded by the provided function, such as
+// an array. The value is buffered and written as a byte string, so that it can be
+// skipped without knowing how it was encoded.
+func (w *TagWriter) WriteValue(field int, fn func(w *Writer) error) error {
+	w.buffer.Reset()
+	w.value.Reset(w.buffer)
+	if err := fn(w.value); err != nil {
+		return err
+	}
+
+	return w.WriteBytes(field, w.buffer.Bytes())
+}
+
+// WriteRecord writes a field with a nested record, which is written by the
+// provided function and terminated with an end marker.
+func (w *TagWriter) WriteRecord(field int, fn func(w *TagWriter) error) error {
+	if err := w.writeTag(field, WireRecord); err != nil {
+		return err
+	}
+
+	if err := fn(w); err != nil {
+		return err
+	}
+	return w.End()
+}
+
+// End writes the end marker of a record
+func (w *TagWriter) End() error {
+	return w.out.WriteUvarint(tagEnd)
+}
+
+// --------------------------- Tag Reader ---------------------------
+
+// TagReader represents a reader for self-describing records.
+//
+//	for r.Next() {
+//		switch r.Field() {
+//		case 1:
+//			p.Name, err = r.ReadString()
+//		default:
+//			err = r.Skip()
+//		}
+//	}
+type TagReader struct {
+	src   *Reader
+	field int
+	wire  WireType
+	ended bool
+	err   error
+}
+
+// NewTagReader creates a new tagged reader.
+func NewTagReader(src io.Reader) *TagReader {
+	return &TagReader{
+		src: NewReader(src),
+	}
+}
+
+// Offset returns the number of bytes read through the underlying reader.
+func (r *TagReader) Offset() int64 {
+	return r.src.Offset()
+}
+
+// Next reads the key of the next field of the record. It returns false once the
+// end of the record is reached or an error has occurred.
+func (r *TagReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	key, err := r.src.ReadUvarint()
+	switch {
+	case err != nil:
+		r.err = err
+		return false
+	case key == tagEnd:
+		r.ended = true
+		return false
+	default:
+		r.field = int(key >> 3)
+		r.wire = WireType(key & 0x7)
+		return true
+	}
+}
+
+// Field returns the number of the current field
+func (r *TagReader) Field() int {
+	return r.field
+}
+
+// Wire returns the wire type of the current field
+func (r *TagReader) Wire() WireType {
+	return r.wire
+}
+
+// Err returns the first error encountered while reading the keys
+func (r *TagReader) Err() error {
+	return r.err
+}
+
+// Skip skips the value of the current field, whichever its wire type is
+func (r *TagReader) Skip() error {
+	return r.skip(r.wire, 0)
+}
+
+// skip skips a value of the specified wire type, nested at the specified depth
+func (r *TagReader) skip(wire WireType, depth int) error {
+	switch wire {
+	case WireUvarint, WireVarint:
+		return r.src.SkipUvarint()
+	case WireFixed32:
+		return r.src.SkipN(4)
+	case WireFixed64:
+		return r.src.SkipN(8)
+	case WireBytes:
+		return r.src.SkipBytes()
+	case WireRecord:
+		if depth >= maxDepth {
+			return errDepth
+		}
+
+		for {
+			key, err := r.src.ReadUvarint()
+			if err != nil || key == tagEnd {
+				return err
+			}
+
+			if err := r.skip(WireType(key&0x7), depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return errWireType
+	}
+}
+
+// expect checks whether the current field has the expected wire type
+func (r *TagReader) expect(wire WireType) error {
+	if r.wire != wire {
+		return errWireType
+	}
+	return nil
+}
+
+// ReadUvarint reads the value of a variable-size unsigned integer field
+func (r *TagReader) ReadUvarint() (uint64, error) {
+	if err := r.expect(WireUvarint); err != nil {
+		return 0, err
+	}
+	return r.src.ReadUvarint()
+}
+
+// ReadVarint reads the value of a variable-size signed integer field
+func (r *TagReader) ReadVarint() (int64, error) {
+	if err := r.expect(WireVarint); err != nil {
+		return 0, err
+	}
+	return r.src.ReadVarint()
+}
+
+// ReadBool reads the value of a boolean field
+func (r *TagReader) ReadBool() (bool, error) {
+	v, err := r.ReadUvarint()
+	return v == 1, err
+}
+
+// ReadUint32 reads the value of a uint32 field
+func (r *TagReader) ReadUint32() (uint32, error) {
+	if err := r.expect(WireFixed32); err != nil {
+		return 0, err
+	}
+	return r.src.ReadUint32()
+}
+
+// ReadUint64 reads the value of a uint64 field
+func (r *TagReader) ReadUint64() (uint64, error) {
+	if err := r.expect(WireFixed64); err != nil {
+		return 0, err
+	}
+	return r.src.ReadUint64()
+}
+
+// ReadFloat32 reads the value of a float32 field
+func (r *TagReader) ReadFloat32() (float32, error) {
+	v, err := r.ReadUint32()
+	return math.Float32frombits(v), err
+}
+
+// ReadFloat64 reads the value of a float64 field
+func (r *TagReader) ReadFloat64() (float64, error) {
+	v, err := r.ReadUint64()
+	return math.Float64frombits(v), err
+}
+
+// ReadString reads the value of a string field
+func (r *TagReader) ReadString() (string, error) {
+	if err := r.expect(WireBytes); err != nil {
+		return "", err
+	}
+	return r.src.ReadString()
+}
+
+// ReadBytes reads the value of a byte slice field
+func (r *TagReader) ReadBytes() ([]byte, error) {
+	if err := r.expect(WireBytes); err != nil {
+		return nil, err
+	}
+	return r.src.ReadBytes()
+}
+
+// ReadValue reads the value of a field written with WriteValue, using the provided
+// function. The function is given a reader limited to the value.
+func (r *TagReader) ReadValue(fn func(r *Reader) error) error {
+	if err := r.expect(WireBytes); err != nil {
+		return err
+	}
+
+	b, err := r.src.sliceBytes() // Safe, since the function must not retain it
+	if err != nil {
+		return err
+	}
+
+	return fn(NewReader(bytes.NewBuffer(b)))
+}
+
+// ReadRecord reads the value of a nested record field, using the provided function.
+// Any fields of the nested record left unread by the function are skipped.
+func (r *TagReader) ReadRecord(fn func(r *TagReader) error) error {
+	if err := r.expect(WireRecord); err != nil {
+		return err
+	}
+
+	r.ended = false
+	if err := fn(r); err != nil {
+		return err
+	}
+
+	// Skip the rest of the record, if the function has returned early
+	if !r.ended && r.err == nil {
+		if err := r.skip(WireRecord, 0); err != nil {
+			return err
+		}
+	}
+
+	r.ended = false
+	return r.err
+}
@@ -0,0 +1,262 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package iostream
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagWriter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewTagWriter(buffer)
+	assert.NoError(t, w.WriteUvarint(1, 150))
+	assert.NoError(t, w.WriteString(2, "hi"))
+	assert.NoError(t, w.WriteRecord(3, func(w *TagWriter) error {
+		return w.WriteVarint(1, -1)
+	}))
+	assert.NoError(t, w.End())
+	assert.Equal(t, []byte{
+		0x8, 0x96, 0x1, // field 1, uvarint
+		0x14, 0x2, 'h', 'i', // field 2, bytes
+		0x1d, 0x9, 0x1, 0x0, // field 3, record
+		0x0, // end
+	}, buffer.Bytes())
+	assert.Equal(t, int64(buffer.Len()), w.Offset())
+}
+
+func TestTaggedEvolution(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, (&personV2{
+		Name:    "Roman",
+		Age:     36,
+		Score:   1.5,
+		Balance: -10,
+		Tags:    []string{"a", "b"},
+		Address: address{City: "Paris", Zip: 75001},
+		Active:  true,
+		Weight:  72.5,
+		Photo:   []byte{1, 2, 3},
+		Visits:  1 << 40,
+	}).encode(NewTagWriter(buffer)))
+
+	// An old reader skips the fields it doesn't know
+	r := NewTagReader(bytes.NewBuffer(buffer.Bytes()))
+	var v1 personV1
+	assert.NoError(t, v1.decode(r))
+	assert.Equal(t, personV1{Name: "Roman", Age: 36}, v1)
+	assert.Equal(t, int64(buffer.Len()), r.Offset())
+
+	// A new reader reads all of the fields
+	var v2 personV2
+	assert.NoError(t, v2.decode(NewTagReader(bytes.NewBuffer(buffer.Bytes()))))
+	assert.Equal(t, "Paris", v2.Address.City)
+	assert.Equal(t, []string{"a", "b"}, v2.Tags)
+	assert.Equal(t, float32(1.5), v2.Score)
+	assert.Equal(t, true, v2.Active)
+
+	// A new reader leaves the missing fields with their defaults
+	buffer.Reset()
+	assert.NoError(t, v1.encode(NewTagWriter(buffer)))
+	v2 = personV2{Score: 5}
+	assert.NoError(t, v2.decode(NewTagReader(buffer)))
+	assert.Equal(t, personV2{Name: "Roman", Age: 36, Score: 5}, v2)
+}
+
+func TestTaggedPartialRecord(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewTagWriter(buffer)
+	assert.NoError(t, w.WriteRecord(1, func(w *TagWriter) error {
+		w.WriteUvarint(1, 1)
+		w.WriteRecord(2, func(w *TagWriter) error { return w.WriteUvarint(1, 2) })
+		return w.WriteUvarint(3, 3)
+	}))
+	assert.NoError(t, w.WriteUvarint(2, 4))
+	assert.NoError(t, w.End())
+
+	var values []uint64
+	r := NewTagReader(buffer)
+	for r.Next() {
+		switch r.Field() {
+		case 1:
+			assert.NoError(t, r.ReadRecord(func(r *TagReader) error {
+				r.Next()
+				v, err := r.ReadUvarint()
+				values = append(values, v)
+				return err // Stop early
+			}))
+		case 2:
+			v, err := r.ReadUvarint()
+			assert.NoError(t, err)
+			values = append(values, v)
+		}
+	}
+	assert.NoError(t, r.Err())
+	assert.Equal(t, []uint64{1, 4}, values)
+}
+
+func TestTaggedErrors(t *testing.T) {
+	w := NewTagWriter(bytes.NewBuffer(nil))
+	assert.Error(t, w.WriteUvarint(0, 1))
+	assert.Error(t, w.WriteRecord(-1, nil))
+	assert.Error(t, w.WriteValue(1, func(w *Writer) error { return errField }))
+	assert.Error(t, NewTagWriter(newLimitWriter(1)).WriteRecord(1, func(w *TagWriter) error {
+		return w.WriteUvarint(1, 1)
+	}))
+
+	// Wrong wire types
+	r := NewTagReader(bytes.NewBuffer([]byte{0xf, 0x0}))
+	assert.True(t, r.Next())
+	assert.Equal(t, WireType(7), r.Wire())
+	_, err := r.ReadUvarint()
+	assert.Error(t, err)
+	_, err = r.ReadVarint()
+	assert.Error(t, err)
+	_, err = r.ReadUint32()
+	assert.Error(t, err)
+	_, err = r.ReadUint64()
+	assert.Error(t, err)
+	_, err = r.ReadString()
+	assert.Error(t, err)
+	_, err = r.ReadBytes()
+	assert.Error(t, err)
+	assert.Error(t, r.ReadValue(nil))
+	assert.Error(t, r.ReadRecord(nil))
+	assert.Error(t, r.Skip())
+
+	// Truncated streams
+	for _, input := range [][]byte{{}, {0x8}, {0x14, 0x2}, {0x1d, 0x8}, {0x1d, 0x1d, 0x0}} {
+		r := NewTagReader(bytes.NewBuffer(input))
+		for r.Next() {
+			if err = r.Skip(); err != nil {
+				break
+			}
+		}
+		assert.True(t, err != nil || r.Err() != nil, "%v", input)
+	}
+
+	// Deeply nested records
+	r = NewTagReader(bytes.NewBuffer(bytes.Repeat([]byte{0xd}, 2*maxDepth)))
+	assert.True(t, r.Next())
+	assert.Equal(t, errDepth, r.Skip())
+}
+
+// --------------------------- Test Records ---------------------------
+
+type personV1 struct {
+	Name string
+	Age  uint64
+}
+
+func (p *personV1) encode(w *TagWriter) error {
+	w.WriteString(1, p.Name)
+	w.WriteUvarint(2, p.Age)
+	return w.End()
+}
+
+func (p *personV1) decode(r *TagReader) (err error) {
+	for r.Next() && err == nil {
+		switch r.Field() {
+		case 1:
+			p.Name, err = r.ReadString()
+		case 2:
+			p.Age, err = r.ReadUvarint()
+		default:
+			err = r.Skip()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	return r.Err()
+}
+
+type address struct {
+	City string
+	Zip  uint32
+}
+
+type personV2 struct {
+	Name    string
+	Age     uint64
+	Score   float32
+	Balance int64
+	Tags    []string
+	Address address
+	Active  bool
+	Weight  float64
+	Photo   []byte
+	Visits  uint64
+}
+
+func (p *personV2) encode(w *TagWriter) error {
+	w.WriteString(1, p.Name)
+	w.WriteFloat32(3, p.Score)
+	w.WriteVarint(4, p.Balance)
+	w.WriteValue(5, func(w *Writer) error {
+		return w.WriteStrings(p.Tags)
+	})
+	w.WriteRecord(6, func(w *TagWriter) error {
+		w.WriteString(1, p.Address.City)
+		return w.WriteUint32(2, p.Address.Zip)
+	})
+	w.WriteBool(7, p.Active)
+	w.WriteFloat64(8, p.Weight)
+	w.WriteBytes(9, p.Photo)
+	w.WriteUint64(10, p.Visits)
+	w.WriteUvarint(2, p.Age)
+	return w.End()
+}
+
+func (p *personV2) decode(r *TagReader) (err error) {
+	for r.Next() && err == nil {
+		switch r.Field() {
+		case 1:
+			p.Name, err = r.ReadString()
+		case 2:
+			p.Age, err = r.ReadUvarint()
+		case 3:
+			p.Score, err = r.ReadFloat32()
+		case 4:
+			p.Balance, err = r.ReadVarint()
+		case 5:
+			err = r.ReadValue(func(r *Reader) (err error) {
+				p.Tags, err = r.ReadStrings()
+				return
+			})
+		case 6:
+			err = r.ReadRecord(func(r *TagReader) (err error) {
+				for r.Next() && err == nil {
+					switch r.Field() {
+					case 1:
+						p.Address.City, err = r.ReadString()
+					case 2:
+						p.Address.Zip, err = r.ReadUint32()
+					default:
+						err = r.Skip()
+					}
+				}
+				return
+			})
+		case 7:
+			p.Active, err = r.ReadBool()
+		case 8:
+			p.Weight, err = r.ReadFloat64()
+		case 9:
+			p.Photo, err = r.ReadBytes()
+		case 10:
+			p.Visits, err = r.ReadUint64()
+		default:
+			err = r.Skip()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	return r.Err()
+}
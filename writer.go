@@ -7,11 +7,24 @@ import (
 	"encoding"
 	"io"
 	"math"
+	"math/big"
+	"math/bits"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/kelindar/iostream/internal/convert"
+)
+
+const (
+	chunkSize   = 4096 // The maximum size of a single bulk write
+	packedBlock = 128  // The number of elements in a bit-packed block
 )
 
 // Writer represents a stream writer.
 type Writer struct {
 	scratch [10]byte
+	buffer  []byte
 	out     io.Writer
 	offset  int64
 }
@@ -55,6 +68,19 @@ func (w *Writer) write(p []byte) error {
 	return err
 }
 
+// alloc returns a temporary buffer for a bulk write of the specified size. The
+// buffer is capped to the chunk size, so larger writes need to be split.
+func (w *Writer) alloc(size int) []byte {
+	if size > chunkSize {
+		size = chunkSize
+	}
+
+	if len(w.buffer) < size {
+		w.buffer = make([]byte, capacityFor(uint(size)))
+	}
+	return w.buffer[:size]
+}
+
 // Flush flushes the writer to the underlying stream and returns its error. If
 // the underlying io.Writer does not have a Flush() error method, it's a no-op.
 func (w *Writer) Flush() error {
@@ -129,6 +155,15 @@ func (w *Writer) WriteUint64(v uint64) error {
 	return w.write(w.scratch[:8])
 }
 
+// WriteUint128 writes a 128-bit unsigned integer, given as its high and low
+// 64-bit halves, in little-endian byte order.
+func (w *Writer) WriteUint128(hi, lo uint64) error {
+	if err := w.WriteUint64(lo); err != nil {
+		return err
+	}
+	return w.WriteUint64(hi)
+}
+
 // WriteUint8s writes an array of uint8s
 func (w *Writer) WriteUint8s(v []uint8) error {
 	return w.WriteRange(len(v), func(i int, w *Writer) error {
@@ -164,6 +199,66 @@ func (w *Writer) WriteUints(v []uint) error {
 	})
 }
 
+// WriteUvarints writes an array of uint64s, each encoded as a variable-size integer
+func (w *Writer) WriteUvarints(v []uint64) error {
+	return w.WriteRange(len(v), func(i int, w *Writer) error {
+		return w.WriteUvarint(v[i])
+	})
+}
+
+// WriteDeltaUvarints writes an array of uint64s as the differences between the
+// consecutive elements, each encoded as a variable-size integer. This is most
+// compact for sorted arrays, but any array is written correctly.
+func (w *Writer) WriteDeltaUvarints(v []uint64) error {
+	var prev uint64
+	return w.WriteRange(len(v), func(i int, w *Writer) error {
+		delta := v[i] - prev
+		prev = v[i]
+		return w.WriteUvarint(delta)
+	})
+}
+
+// WritePackedUint32s writes an array of uint32s split into blocks of 128 elements.
+// Each block is written as its minimum value and bit width, followed by the offsets
+// of every element from the minimum, bit-packed using that width.
+func (w *Writer) WritePackedUint32s(v []uint32) error {
+	if err := w.WriteUvarint(uint64(len(v))); err != nil {
+		return err
+	}
+
+	for len(v) > 0 {
+		block := v
+		if len(block) > packedBlock {
+			block = block[:packedBlock]
+		}
+
+		min, max := block[0], block[0]
+		for _, x := range block {
+			if x < min {
+				min = x
+			}
+			if x > max {
+				max = x
+			}
+		}
+
+		width := uint(bits.Len32(max - min))
+		if err := w.WriteUvarint(uint64(min)); err != nil {
+			return err
+		}
+		if err := w.WriteUint8(uint8(width)); err != nil {
+			return err
+		}
+
+		buffer := w.alloc(packedSize(len(block), width))
+		if err := w.write(buffer[:packBits(buffer, block, min, width)]); err != nil {
+			return err
+		}
+		v = v[len(block):]
+	}
+	return nil
+}
+
 // --------------------------- Signed Integers ---------------------------
 
 // WriteVarint writes a variable size signed integer
@@ -243,6 +338,24 @@ func (w *Writer) WriteInts(v []int) error {
 	})
 }
 
+// WriteVarints writes an array of int64s, each encoded as a variable-size signed integer
+func (w *Writer) WriteVarints(v []int64) error {
+	return w.WriteRange(len(v), func(i int, w *Writer) error {
+		return w.WriteVarint(v[i])
+	})
+}
+
+// WriteDeltaVarints writes an array of int64s as the differences between the
+// consecutive elements, each encoded as a variable-size signed integer.
+func (w *Writer) WriteDeltaVarints(v []int64) error {
+	var prev int64
+	return w.WriteRange(len(v), func(i int, w *Writer) error {
+		delta := v[i] - prev
+		prev = v[i]
+		return w.WriteVarint(delta)
+	})
+}
+
 // --------------------------- Floats ---------------------------
 
 // WriteFloat32 a 32-bit floating point number
@@ -269,6 +382,375 @@ func (w *Writer) WriteFloat64s(v []float64) error {
 	})
 }
 
+// WriteFloat16 writes a 32-bit floating point number as an IEEE 754 half-precision
+// number, rounded to nearest even.
+func (w *Writer) WriteFloat16(v float32) error {
+	return w.WriteUint16(Float16bits(v))
+}
+
+// WriteBFloat16 writes a 32-bit floating point number as a bfloat16 number,
+// rounded to nearest even.
+func (w *Writer) WriteBFloat16(v float32) error {
+	return w.WriteUint16(BFloat16bits(v))
+}
+
+// WriteFloat16s writes an array of float32s as IEEE 754 half-precision numbers
+func (w *Writer) WriteFloat16s(v []float32) error {
+	return w.writeUint16s(len(v), func(i int) uint16 {
+		return Float16bits(v[i])
+	})
+}
+
+// WriteBFloat16s writes an array of float32s as bfloat16 numbers
+func (w *Writer) WriteBFloat16s(v []float32) error {
+	return w.writeUint16s(len(v), func(i int) uint16 {
+		return BFloat16bits(v[i])
+	})
+}
+
+// writeUint16s writes an array of 16-bit values returned by the encoder function,
+// which are written in chunks rather than one element at a time.
+func (w *Writer) writeUint16s(length int, fn func(i int) uint16) error {
+	if err := w.WriteUvarint(uint64(length)); err != nil {
+		return err
+	}
+
+	buffer := w.alloc(2 * length)
+	for i := 0; i < length; {
+		n := 0
+		for ; i < length && n < len(buffer); i++ {
+			v := fn(i)
+			buffer[n] = byte(v)
+			buffer[n+1] = byte(v >> 8)
+			n += 2
+		}
+
+		if err := w.write(buffer[:n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --------------------------- Big Numbers ---------------------------
+
+// WriteBigInt writes an arbitrary-precision integer as a byte string, which can be
+// skipped with SkipBytes. The byte string holds the sign, 0 for positive and 1 for
+// negative, followed by the big-endian magnitude. Zero and a nil integer are both
+// written as an empty byte string.
+func (w *Writer) WriteBigInt(v *big.Int) error {
+	if v == nil || v.Sign() == 0 {
+		return w.WriteUvarint(0)
+	}
+
+	b := v.Bytes()
+	if err := w.WriteUvarint(uint64(len(b)) + 1); err != nil {
+		return err
+	}
+
+	sign := byte(0)
+	if v.Sign() < 0 {
+		sign = 1
+	}
+
+	if err := w.WriteUint8(sign); err != nil {
+		return err
+	}
+	return w.write(b)
+}
+
+// WriteBigFloat writes an arbitrary-precision floating-point number, including
+// its precision and rounding mode, prefixed with a variable-size integer size.
+func (w *Writer) WriteBigFloat(v *big.Float) error {
+	out, err := v.GobEncode()
+	if err == nil {
+		err = w.WriteBytes(out)
+	}
+	return err
+}
+
+// WriteBigRat writes an arbitrary-precision rational number as a numerator and
+// a denominator. This can be used to represent decimal numbers exactly. A nil
+// number is written as zero.
+func (w *Writer) WriteBigRat(v *big.Rat) error {
+	if v == nil {
+		v = new(big.Rat)
+	}
+
+	if err := w.WriteBigInt(v.Num()); err != nil {
+		return err
+	}
+	return w.WriteBigInt(v.Denom())
+}
+
+// --------------------------- Time ---------------------------
+
+// WriteTime writes a time as a variable-size number of seconds since the Unix
+// epoch, followed by the nanoseconds and an optional zone offset. Times in UTC
+// (including the zero time) are written without the zone offset, any other
+// location is written as its offset from UTC in seconds.
+func (w *Writer) WriteTime(v time.Time) error {
+	_, offset := v.Zone()
+	header := uint64(v.Nanosecond()) << 1
+	if v.Location() != time.UTC {
+		header |= 1
+	}
+
+	if err := w.WriteVarint(v.Unix()); err != nil {
+		return err
+	}
+
+	if err := w.WriteUvarint(header); err != nil || header&1 == 0 {
+		return err
+	}
+
+	return w.WriteVarint(int64(offset))
+}
+
+// WriteDuration writes a duration as a variable-size number of nanoseconds
+func (w *Writer) WriteDuration(v time.Duration) error {
+	return w.WriteVarint(int64(v))
+}
+
+// WriteTimes writes an array of times
+func (w *Writer) WriteTimes(v []time.Time) error {
+	return w.WriteRange(len(v), func(i int, w *Writer) error {
+		return w.WriteTime(v[i])
+	})
+}
+
+// WriteDurations writes an array of durations
+func (w *Writer) WriteDurations(v []time.Duration) error {
+	return w.WriteRange(len(v), func(i int, w *Writer) error {
+		return w.WriteDuration(v[i])
+	})
+}
+
+// --------------------------- Time Series ---------------------------
+
+// WriteGorillaFloat64s writes an array of float64s compressed with the scheme
+// used by Facebook's Gorilla. Each value is XOR-ed with the previous one and only
+// the meaningful bits of the result are written, which works best for series of
+// slowly changing values.
+func (w *Writer) WriteGorillaFloat64s(v []float64) error {
+	if err := w.WriteUvarint(uint64(len(v))); err != nil || len(v) == 0 {
+		return err
+	}
+
+	bw := BitWriter{out: w}
+	prev := math.Float64bits(v[0])
+	if err := bw.WriteBits(prev, 64); err != nil {
+		return err
+	}
+
+	prevLead, prevTrail := 65, 0 // No window yet
+	for _, f := range v[1:] {
+		cur := math.Float64bits(f)
+		xor := cur ^ prev
+		prev = cur
+
+		// Same value as before, a single zero bit
+		if xor == 0 {
+			if err := bw.WriteBits(0, 1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		lead, trail := bits.LeadingZeros64(xor), bits.TrailingZeros64(xor)
+		if lead > 31 {
+			lead = 31
+		}
+
+		// Meaningful bits fit in the previous window, control bits are '10'
+		if lead >= prevLead && trail >= prevTrail {
+			if err := bw.WriteBits(0b01, 2); err != nil {
+				return err
+			}
+			if err := bw.WriteBits(xor>>uint(prevTrail), 64-prevLead-prevTrail); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// New window, control bits are '11' followed by the window
+		size := 64 - lead - trail
+		header := 0b11 | uint64(lead)<<2 | uint64(size-1)<<7
+		if err := bw.WriteBits(header, 13); err != nil {
+			return err
+		}
+		if err := bw.WriteBits(xor>>uint(trail), size); err != nil {
+			return err
+		}
+		prevLead, prevTrail = lead, trail
+	}
+
+	return bw.AlignByte()
+}
+
+// WriteGorillaTimestamps writes an array of timestamps compressed with the
+// delta-of-delta scheme used by Facebook's Gorilla. This works best for series of
+// timestamps taken at regular intervals.
+func (w *Writer) WriteGorillaTimestamps(v []int64) error {
+	if err := w.WriteUvarint(uint64(len(v))); err != nil || len(v) == 0 {
+		return err
+	}
+
+	bw := BitWriter{out: w}
+	if err := bw.WriteBits(uint64(v[0]), 64); err != nil {
+		return err
+	}
+
+	var delta int64
+	for i := 1; i < len(v); i++ {
+		dod := v[i] - v[i-1] - delta
+		delta = v[i] - v[i-1]
+
+		// Write the control bits followed by the delta-of-delta value
+		var err error
+		switch {
+		case dod == 0:
+			err = bw.WriteBits(0b0, 1)
+		case dod >= -64 && dod < 64:
+			err = bw.WriteBits(0b01|uint64(dod)<<2, 2+7)
+		case dod >= -256 && dod < 256:
+			err = bw.WriteBits(0b011|uint64(dod)<<3, 3+9)
+		case dod >= -2048 && dod < 2048:
+			err = bw.WriteBits(0b0111|uint64(dod)<<4, 4+12)
+		default:
+			if err = bw.WriteBits(0b1111, 4); err == nil {
+				err = bw.WriteBits(uint64(dod), 64)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.AlignByte()
+}
+
+// --------------------------- Run-Length Encoding ---------------------------
+
+// Tags written in front of arrays which are written by one of the auto writers
+const (
+	encodingPlain = 0 // The array is written with a plain writer
+	encodingRLE   = 1 // The array is written with a run-length writer
+)
+
+// maxRunLength is the maximum number of elements of a run-length encoded array,
+// which is 2^24. Since a single run can be arbitrarily long, the length of such an
+// array is not bounded by the size of its input and needs a limit of its own.
+const maxRunLength = 1 << 24
+
+// WriteRLEInt64s writes an array of int64s as runs of the same value, each run
+// being written as the value followed by its length as a variable-size integer.
+// Arrays of more than 2^24 elements are rejected.
+func (w *Writer) WriteRLEInt64s(v []int64) error {
+	if len(v) > maxRunLength {
+		return errSize
+	}
+
+	if err := w.WriteUvarint(uint64(len(v))); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(v); {
+		run := 1
+		for i+run < len(v) && v[i+run] == v[i] {
+			run++
+		}
+
+		if err := w.WriteInt64(v[i]); err != nil {
+			return err
+		}
+		if err := w.WriteUvarint(uint64(run)); err != nil {
+			return err
+		}
+		i += run
+	}
+	return nil
+}
+
+// WriteRLEUint8s writes an array of uint8s as runs of the same value, each run
+// being written as the value followed by its length as a variable-size integer.
+// Arrays of more than 2^24 elements are rejected.
+func (w *Writer) WriteRLEUint8s(v []uint8) error {
+	if len(v) > maxRunLength {
+		return errSize
+	}
+
+	if err := w.WriteUvarint(uint64(len(v))); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(v); {
+		run := 1
+		for i+run < len(v) && v[i+run] == v[i] {
+			run++
+		}
+
+		if err := w.WriteUint8(v[i]); err != nil {
+			return err
+		}
+		if err := w.WriteUvarint(uint64(run)); err != nil {
+			return err
+		}
+		i += run
+	}
+	return nil
+}
+
+// WriteAutoInt64s writes an array of int64s either with WriteRLEInt64s or with
+// WriteInt64s, depending on which one is estimated to be smaller. The choice is
+// written as a one-byte tag in front of the array, and arrays of more than 2^24
+// elements are always written with WriteInt64s.
+func (w *Writer) WriteAutoInt64s(v []int64) error {
+	runs := 0
+	for i := range v {
+		if i == 0 || v[i] != v[i-1] {
+			runs++
+		}
+	}
+
+	if runs*(8+1) < len(v)*8 && len(v) <= maxRunLength {
+		if err := w.WriteUint8(encodingRLE); err != nil {
+			return err
+		}
+		return w.WriteRLEInt64s(v)
+	}
+
+	if err := w.WriteUint8(encodingPlain); err != nil {
+		return err
+	}
+	return w.WriteInt64s(v)
+}
+
+// WriteAutoUint8s writes an array of uint8s either with WriteRLEUint8s or with
+// WriteUint8s, depending on which one is estimated to be smaller. The choice is
+// written as a one-byte tag in front of the array, and arrays of more than 2^24
+// elements are always written with WriteUint8s.
+func (w *Writer) WriteAutoUint8s(v []uint8) error {
+	runs := 0
+	for i := range v {
+		if i == 0 || v[i] != v[i-1] {
+			runs++
+		}
+	}
+
+	if runs*(1+1) < len(v) && len(v) <= maxRunLength {
+		if err := w.WriteUint8(encodingRLE); err != nil {
+			return err
+		}
+		return w.WriteRLEUint8s(v)
+	}
+
+	if err := w.WriteUint8(encodingPlain); err != nil {
+		return err
+	}
+	return w.WriteUint8s(v)
+}
+
 // --------------------------- Marshaled Types ---------------------------
 
 // WriteBinary marshals the type to its binary representation and writes it
@@ -298,6 +780,62 @@ func (w *Writer) WriteSelf(v io.WriterTo) error {
 	return err
 }
 
+// WriteStruct writes a fixed-size value, a slice of fixed-size values or a pointer
+// to either in little-endian order. The output is identical to binary.Write with
+// binary.LittleEndian: there is no size prefix and blank (_) fields are zeroed.
+func (w *Writer) WriteStruct(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if !value.IsValid() {
+		return errStruct
+	}
+
+	// Find out where the values are located in memory
+	var ptr unsafe.Pointer
+	var plan *structPlan
+	count := 1
+	switch value.Kind() {
+	case reflect.Slice:
+		if plan = planOf(value.Type().Elem()); plan == nil {
+			return errStruct
+		}
+		count, ptr = value.Len(), unsafe.Pointer(value.Pointer())
+	default:
+		if plan = planOf(value.Type()); plan == nil {
+			return errStruct
+		}
+
+		// Values passed by copy are not addressable, so copy them again
+		if !value.CanAddr() {
+			copied := reflect.New(value.Type()).Elem()
+			copied.Set(value)
+			value = copied
+		}
+		ptr = unsafe.Pointer(value.UnsafeAddr())
+	}
+
+	for done := 0; done < count; {
+		n := plan.batchOf(count - done)
+		size := n * plan.size
+
+		var buffer []byte
+		if size <= chunkSize {
+			buffer = w.alloc(size)
+		} else {
+			buffer = make([]byte, size)
+		}
+
+		for i := 0; i < n; i++ {
+			plan.encode(buffer[i*plan.size:], unsafe.Add(ptr, uintptr(done+i)*plan.stride))
+		}
+
+		if err := w.write(buffer); err != nil {
+			return err
+		}
+		done += n
+	}
+	return nil
+}
+
 // --------------------------- Strings ---------------------------
 
 // WriteString writes a string prefixed with a variable-size integer.
@@ -305,7 +843,7 @@ func (w *Writer) WriteString(v string) error {
 	if err := w.WriteUvarint(uint64(len(v))); err != nil {
 		return err
 	}
-	return w.write(toBytes(v))
+	return w.write(convert.ToBytes(v))
 }
 
 // WriteBytes writes a byte slice prefixed with a variable-size integer.
@@ -323,6 +861,24 @@ func (w *Writer) WriteStrings(v []string) error {
 	})
 }
 
+// WriteSortedStrings writes a sorted array of strings as a block of prefix-compressed
+// strings, prefixed with its size as a variable-size integer. The block contains
+// restart points which allow to search it without decoding every string.
+func (w *Writer) WriteSortedStrings(v []string) error {
+	return w.WriteSortedStringsN(v, sortedInterval)
+}
+
+// WriteSortedStringsN writes a sorted array of strings as a block of prefix-compressed
+// strings with a restart point every interval strings. A smaller interval makes the
+// lookups faster, at the expense of a larger block.
+func (w *Writer) WriteSortedStringsN(v []string, interval int) error {
+	if interval <= 0 {
+		return errInterval
+	}
+
+	return w.WriteBytes(encodeSortedBlock(v, interval))
+}
+
 // --------------------------- Other Types ---------------------------
 
 // WriteRange writes a specified length of an array and for each element of that
@@ -349,3 +905,60 @@ func (w *Writer) WriteBool(v bool) error {
 	}
 	return w.write(w.scratch[:1])
 }
+
+// WriteBools writes an array of booleans packed as a bitset, least significant
+// bit first, prefixed with the number of booleans as a variable-size integer.
+func (w *Writer) WriteBools(v []bool) error {
+	if err := w.WriteUvarint(uint64(len(v))); err != nil {
+		return err
+	}
+
+	buffer := w.alloc((len(v) + 7) / 8)
+	for i := 0; i < len(v); {
+		n := 0
+		for ; i < len(v) && n < len(buffer); n++ {
+			var b byte
+			for bit := 0; bit < 8 && i < len(v); bit, i = bit+1, i+1 {
+				if v[i] {
+					b |= 1 << bit
+				}
+			}
+			buffer[n] = b
+		}
+
+		if err := w.write(buffer[:n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBitmap writes a bitmap represented as an array of uint64s. This produces
+// the same output as WriteUint64s, but the words are written in chunks rather
+// than one at a time.
+func (w *Writer) WriteBitmap(v []uint64) error {
+	if err := w.WriteUvarint(uint64(len(v))); err != nil {
+		return err
+	}
+
+	buffer := w.alloc(8 * len(v))
+	for i := 0; i < len(v); {
+		n := 0
+		for ; i < len(v) && n < len(buffer); i, n = i+1, n+8 {
+			_ = buffer[n+7] // bounds check hint to compiler
+			buffer[n] = byte(v[i])
+			buffer[n+1] = byte(v[i] >> 8)
+			buffer[n+2] = byte(v[i] >> 16)
+			buffer[n+3] = byte(v[i] >> 24)
+			buffer[n+4] = byte(v[i] >> 32)
+			buffer[n+5] = byte(v[i] >> 40)
+			buffer[n+6] = byte(v[i] >> 48)
+			buffer[n+7] = byte(v[i] >> 56)
+		}
+
+		if err := w.write(buffer[:n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,6 +6,9 @@ package iostream
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -146,6 +149,81 @@ var Fixtures = map[string]struct {
 		Buffer: []byte{0x14, 0x31, 0x39, 0x37, 0x30, 0x2d, 0x30, 0x31, 0x2d, 0x30, 0x31, 0x54, 0x30, 0x30, 0x3a, 0x30, 0x31, 0x3a, 0x30, 0x30, 0x5a},
 		Value:  time.Unix(60, 0).UTC(),
 	},
+	"time": {
+		Encode: func(w *Writer) error { return w.WriteTime(time.Unix(60, 5).UTC()) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadTime() },
+		Buffer: []byte{0x78, 0xa},
+		Value:  time.Unix(60, 5).UTC(),
+	},
+	"time-zero": {
+		Encode: func(w *Writer) error { return w.WriteTime(time.Time{}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadTime() },
+		Buffer: []byte{0xff, 0xdb, 0x8f, 0xf9, 0xce, 0x3, 0x0},
+		Value:  time.Time{},
+	},
+	"time-zone": {
+		Encode: func(w *Writer) error { return w.WriteTime(time.Unix(60, 0).In(time.FixedZone("", 3600))) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadTime() },
+		Buffer: []byte{0x78, 0x1, 0xa0, 0x38},
+		Value:  time.Unix(60, 0).In(time.FixedZone("", 3600)),
+	},
+	"times": {
+		Encode: func(w *Writer) error { return w.WriteTimes([]time.Time{time.Unix(60, 5).UTC()}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadTimes() },
+		Buffer: []byte{0x1, 0x78, 0xa},
+		Value:  []time.Time{time.Unix(60, 5).UTC()},
+	},
+	"duration": {
+		Encode: func(w *Writer) error { return w.WriteDuration(-time.Millisecond) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadDuration() },
+		Buffer: []byte{0xff, 0x88, 0x7a},
+		Value:  -time.Millisecond,
+	},
+	"durations": {
+		Encode: func(w *Writer) error { return w.WriteDurations([]time.Duration{time.Second, -time.Millisecond}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadDurations() },
+		Buffer: []byte{0x2, 0x80, 0xa8, 0xd6, 0xb9, 0x7, 0xff, 0x88, 0x7a},
+		Value:  []time.Duration{time.Second, -time.Millisecond},
+	},
+	"uint128": {
+		Encode: func(w *Writer) error { return w.WriteUint128(0x1111111111111111, 0x2222222222222222) },
+		Decode: func(r *Reader) (interface{}, error) {
+			hi, lo, err := r.ReadUint128()
+			return [2]uint64{hi, lo}, err
+		},
+		Buffer: []byte{0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x11},
+		Value:  [2]uint64{0x1111111111111111, 0x2222222222222222},
+	},
+	"bigint": {
+		Encode: func(w *Writer) error { return w.WriteBigInt(big.NewInt(-256)) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadBigInt() },
+		Buffer: []byte{0x3, 0x1, 0x1, 0x0},
+		Value:  big.NewInt(-256),
+	},
+	"bigfloat": {
+		Encode: func(w *Writer) error { return w.WriteBigFloat(big.NewFloat(1.5)) },
+		Decode: func(r *Reader) (interface{}, error) {
+			out, err := r.ReadBigFloat()
+			if err != nil {
+				return nil, err
+			}
+			return out.Text('g', 10), nil
+		},
+		Buffer: []byte{0x12, 0x1, 0xa, 0x0, 0x0, 0x0, 0x35, 0x0, 0x0, 0x0, 0x1, 0xc0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0},
+		Value:  "1.5",
+	},
+	"bigrat": {
+		Encode: func(w *Writer) error { return w.WriteBigRat(big.NewRat(-314, 100)) },
+		Decode: func(r *Reader) (interface{}, error) {
+			out, err := r.ReadBigRat()
+			if err != nil {
+				return nil, err
+			}
+			return out.FloatString(2), nil
+		},
+		Buffer: []byte{0x2, 0x1, 0x9d, 0x2, 0x0, 0x32},
+		Value:  "-3.14",
+	},
 	"person": {
 		Encode: func(w *Writer) error {
 			return w.WriteSelf(&person{Name: "Roman"})
@@ -193,6 +271,30 @@ var Fixtures = map[string]struct {
 		Buffer: []byte{0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x31, 0x40},
 		Value:  []float64{0x11},
 	},
+	"float16": {
+		Encode: func(w *Writer) error { return w.WriteFloat16(1.5) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadFloat16() },
+		Buffer: []byte{0x0, 0x3e},
+		Value:  float32(1.5),
+	},
+	"bfloat16": {
+		Encode: func(w *Writer) error { return w.WriteBFloat16(1.5) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadBFloat16() },
+		Buffer: []byte{0xc0, 0x3f},
+		Value:  float32(1.5),
+	},
+	"float16s": {
+		Encode: func(w *Writer) error { return w.WriteFloat16s([]float32{1.5, -2}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadFloat16s() },
+		Buffer: []byte{0x2, 0x0, 0x3e, 0x0, 0xc0},
+		Value:  []float32{1.5, -2},
+	},
+	"bfloat16s": {
+		Encode: func(w *Writer) error { return w.WriteBFloat16s([]float32{1.5, -2}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadBFloat16s() },
+		Buffer: []byte{0x2, 0xc0, 0x3f, 0x0, 0xc0},
+		Value:  []float32{1.5, -2},
+	},
 	"uint8s": {
 		Encode: func(w *Writer) error { return w.WriteUint8s([]uint8{0x11}) },
 		Decode: func(r *Reader) (interface{}, error) { return r.ReadUint8s() },
@@ -253,6 +355,104 @@ var Fixtures = map[string]struct {
 		Buffer: []byte{0x1, 0x11, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0},
 		Value:  []int{0x11},
 	},
+	"packed-uint32s": {
+		Encode: func(w *Writer) error { return w.WritePackedUint32s([]uint32{100, 103, 101, 107}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadPackedUint32s() },
+		Buffer: []byte{0x4, 0x64, 0x3, 0x58, 0xe},
+		Value:  []uint32{100, 103, 101, 107},
+	},
+	"uvarints": {
+		Encode: func(w *Writer) error { return w.WriteUvarints([]uint64{1, 300, 0}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadUvarints() },
+		Buffer: []byte{0x3, 0x1, 0xac, 0x2, 0x0},
+		Value:  []uint64{1, 300, 0},
+	},
+	"varints": {
+		Encode: func(w *Writer) error { return w.WriteVarints([]int64{-1, 300, 0}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadVarints() },
+		Buffer: []byte{0x3, 0x1, 0xd8, 0x4, 0x0},
+		Value:  []int64{-1, 300, 0},
+	},
+	"delta-uvarints": {
+		Encode: func(w *Writer) error { return w.WriteDeltaUvarints([]uint64{1000, 1001, 1300, 5}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadDeltaUvarints() },
+		Buffer: []byte{0x4, 0xe8, 0x7, 0x1, 0xab, 0x2, 0xf1, 0xf5, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x1},
+		Value:  []uint64{1000, 1001, 1300, 5},
+	},
+	"delta-varints": {
+		Encode: func(w *Writer) error { return w.WriteDeltaVarints([]int64{-1000, -999, 300, 5}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadDeltaVarints() },
+		Buffer: []byte{0x4, 0xcf, 0xf, 0x2, 0xa6, 0x14, 0xcd, 0x4},
+		Value:  []int64{-1000, -999, 300, 5},
+	},
+	"bools": {
+		Encode: func(w *Writer) error {
+			return w.WriteBools([]bool{true, false, true, true, false, false, false, false, true})
+		},
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadBools() },
+		Buffer: []byte{0x9, 0xd, 0x1},
+		Value:  []bool{true, false, true, true, false, false, false, false, true},
+	},
+	"bitmap": {
+		Encode: func(w *Writer) error { return w.WriteBitmap([]uint64{0x11, 0x8000000000000000}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadBitmap() },
+		Buffer: []byte{0x2, 0x11, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x80},
+		Value:  []uint64{0x11, 0x8000000000000000},
+	},
+	"sorted-strings": {
+		Encode: func(w *Writer) error { return w.WriteSortedStrings([]string{"apple", "applet", "apply"}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadSortedStrings() },
+		Buffer: []byte{0x13, 0x3, 0x3, 0x0, 0x5, 0x61, 0x70, 0x70, 0x6c, 0x65, 0x5, 0x1, 0x74, 0x4, 0x1, 0x79, 0x0, 0x0, 0x0, 0x0},
+		Value:  []string{"apple", "applet", "apply"},
+	},
+	"gorilla-float64s": {
+		Encode: func(w *Writer) error { return w.WriteGorillaFloat64s([]float64{12, 12, 24, 15.5}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadGorillaFloat64s() },
+		Buffer: []byte{0x4, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x28, 0x40, 0x5e, 0xc0, 0x17, 0x71, 0x1},
+		Value:  []float64{12, 12, 24, 15.5},
+	},
+	"gorilla-timestamps": {
+		Encode: func(w *Writer) error { return w.WriteGorillaTimestamps([]int64{1000, 1060, 1120, 1190, 1190, 5000}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadGorillaTimestamps() },
+		Buffer: []byte{0x6, 0xe8, 0x3, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xf1, 0xa4, 0x98, 0xee, 0x17, 0x77, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0},
+		Value:  []int64{1000, 1060, 1120, 1190, 1190, 5000},
+	},
+	"rle-int64s": {
+		Encode: func(w *Writer) error { return w.WriteRLEInt64s([]int64{7, 7, 7, -1}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadRLEInt64s() },
+		Buffer: []byte{0x4, 0x7, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x1},
+		Value:  []int64{7, 7, 7, -1},
+	},
+	"rle-uint8s": {
+		Encode: func(w *Writer) error { return w.WriteRLEUint8s([]uint8{7, 7, 7, 1}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadRLEUint8s() },
+		Buffer: []byte{0x4, 0x7, 0x3, 0x1, 0x1},
+		Value:  []uint8{7, 7, 7, 1},
+	},
+	"auto-int64s-rle": {
+		Encode: func(w *Writer) error { return w.WriteAutoInt64s([]int64{7, 7}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadAutoInt64s() },
+		Buffer: []byte{0x1, 0x2, 0x7, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x2},
+		Value:  []int64{7, 7},
+	},
+	"auto-int64s-plain": {
+		Encode: func(w *Writer) error { return w.WriteAutoInt64s([]int64{7}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadAutoInt64s() },
+		Buffer: []byte{0x0, 0x1, 0x7, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0},
+		Value:  []int64{7},
+	},
+	"auto-uint8s-rle": {
+		Encode: func(w *Writer) error { return w.WriteAutoUint8s([]uint8{7, 7, 7}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadAutoUint8s() },
+		Buffer: []byte{0x1, 0x3, 0x7, 0x3},
+		Value:  []uint8{7, 7, 7},
+	},
+	"auto-uint8s-plain": {
+		Encode: func(w *Writer) error { return w.WriteAutoUint8s([]uint8{1, 2}) },
+		Decode: func(r *Reader) (interface{}, error) { return r.ReadAutoUint8s() },
+		Buffer: []byte{0x0, 0x2, 0x1, 0x2},
+		Value:  []uint8{1, 2},
+	},
 	"strings": {
 		Encode: func(w *Writer) error { return w.WriteStrings([]string{"hello"}) },
 		Decode: func(r *Reader) (interface{}, error) { return r.ReadStrings() },
@@ -285,6 +485,276 @@ func TestWriteFailures(t *testing.T) {
 	}
 }
 
+func TestWriteTimeLocal(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	now := time.Now()
+	assert.NoError(t, NewWriter(buffer).WriteTime(now))
+
+	out, err := NewReader(buffer).ReadTime()
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(out))
+
+	_, offset := now.Zone()
+	_, actual := out.Zone()
+	assert.Equal(t, offset, actual)
+}
+
+func TestReadTimesInvalidLength(t *testing.T) {
+	input := []byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x2}
+	for _, decode := range []func(*Reader) (interface{}, error){
+		func(r *Reader) (interface{}, error) { return r.ReadTimes() },
+		func(r *Reader) (interface{}, error) { return r.ReadDurations() },
+	} {
+		_, err := decode(NewReader(bytes.NewBuffer(input)))
+		assert.Equal(t, errSize, err)
+
+		_, err = decode(NewReader(newNetworkSource(input)))
+		assert.Error(t, err)
+	}
+}
+
+func TestReadDurationsStream(t *testing.T) {
+	input := make([]time.Duration, 2*maxPrealloc+1)
+	for i := range input {
+		input[i] = time.Duration(i)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteDurations(input))
+
+	output, err := NewReader(newNetworkSource(buffer.Bytes())).ReadDurations()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestWriteBigZero(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewWriter(buffer)
+	assert.NoError(t, w.WriteBigInt(nil))
+	assert.NoError(t, w.WriteBigRat(nil))
+	assert.NoError(t, w.WriteBigInt(big.NewInt(0)))
+	assert.Equal(t, []byte{0x0, 0x0, 0x2, 0x0, 0x1, 0x0}, buffer.Bytes())
+
+	r := NewReader(buffer)
+	i, err := r.ReadBigInt()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, i.Sign())
+
+	q, err := r.ReadBigRat()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Sign())
+}
+
+func TestReadBigRatInvalid(t *testing.T) {
+	_, err := NewReader(bytes.NewBuffer([]byte{0x2, 0x0, 0x1, 0x0})).ReadBigRat()
+	assert.Error(t, err)
+}
+
+func TestReadBigIntInvalid(t *testing.T) {
+	_, err := NewReader(bytes.NewBuffer([]byte{0x2, 0x2, 0x1})).ReadBigInt()
+	assert.Equal(t, errSign, err)
+
+	for _, input := range [][]byte{
+		{0xff, 0xff, 0xff, 0xff, 0x0f, 0x0, 0x1},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x0, 0x1},
+	} {
+		_, err := NewReader(bytes.NewBuffer(input)).ReadBigInt()
+		assert.Error(t, err)
+
+		_, err = NewReader(newNetworkSource(input)).ReadBigInt()
+		assert.Error(t, err)
+	}
+}
+
+func TestSkipBigInt(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	w := NewWriter(buffer)
+	assert.NoError(t, w.WriteBigInt(new(big.Int).Lsh(big.NewInt(-1), 100)))
+	assert.NoError(t, w.WriteBigRat(big.NewRat(1, 3)))
+	assert.NoError(t, w.WriteUint8(42))
+
+	r := NewReader(buffer)
+	assert.NoError(t, r.SkipBytes())
+	assert.NoError(t, r.SkipBytes())
+	assert.NoError(t, r.SkipBytes())
+
+	v, err := r.ReadUint8()
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(42), v)
+}
+
+func TestWriteFloat16sChunked(t *testing.T) {
+	input := make([]float32, 5000)
+	for i := range input {
+		input[i] = float32(i % 2048)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteFloat16s(input))
+
+	output, err := NewReader(buffer).ReadFloat16s()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestWriteBoolsChunked(t *testing.T) {
+	input := make([]bool, 8*chunkSize+3)
+	for i := range input {
+		input[i] = i%3 == 0
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteBools(input))
+	assert.Equal(t, 3+chunkSize+1, buffer.Len())
+
+	output, err := NewReader(buffer).ReadBools()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
+func TestWriteBitmapChunked(t *testing.T) {
+	input := make([]uint64, chunkSize)
+	for i := range input {
+		input[i] = uint64(i) * 0x0101010101010101
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteBitmap(input))
+
+	output, err := NewReader(bytes.NewBuffer(buffer.Bytes())).ReadBitmap()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	words, err := NewReader(buffer).ReadUint64s()
+	assert.NoError(t, err)
+	assert.Equal(t, input, words)
+}
+
+func TestReadBoolsInvalidLength(t *testing.T) {
+	for _, input := range [][]byte{
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+	} {
+		_, err := NewReader(bytes.NewBuffer(input)).ReadBools()
+		assert.Error(t, err)
+		_, err = NewReader(bytes.NewBuffer(input)).ReadBitmap()
+		assert.Error(t, err)
+	}
+}
+
+func TestWriteGorillaFloat64s(t *testing.T) {
+	input := []float64{math.NaN(), math.Inf(1), math.Inf(-1), 0, math.MaxFloat64, math.SmallestNonzeroFloat64}
+	for v := 100.0; len(input) < 1000; {
+		v += rand.NormFloat64()
+		input = append(input, v, v, math.Round(v))
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteGorillaFloat64s(input))
+	assert.NoError(t, NewWriter(buffer).WriteGorillaFloat64s(nil))
+
+	r := NewReader(buffer)
+	output, err := r.ReadGorillaFloat64s()
+	assert.NoError(t, err)
+	assert.Equal(t, len(input), len(output))
+	for i := range input {
+		assert.Equal(t, math.Float64bits(input[i]), math.Float64bits(output[i]))
+	}
+
+	empty, err := r.ReadGorillaFloat64s()
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestWriteGorillaTimestamps(t *testing.T) {
+	input := []int64{math.MinInt64, math.MaxInt64, 0}
+	for ts := time.Now().Unix(); len(input) < 1000; {
+		ts += int64(rand.Intn(5000)) - 100
+		input = append(input, ts, ts)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteGorillaTimestamps(input))
+	assert.NoError(t, NewWriter(buffer).WriteGorillaTimestamps(nil))
+
+	r := NewReader(buffer)
+	output, err := r.ReadGorillaTimestamps()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+
+	empty, err := r.ReadGorillaTimestamps()
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestReadGorillaInvalidWindow(t *testing.T) {
+	input := []byte{0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1}
+	_, err := NewReader(bytes.NewBuffer(input)).ReadGorillaFloat64s()
+	assert.Error(t, err)
+}
+
+func TestReadGorillaInvalidLength(t *testing.T) {
+	input := append([]byte{0xff, 0xff, 0xff, 0xff, 0x0f}, make([]byte, 16)...)
+	for _, decode := range []func(*Reader) (interface{}, error){
+		func(r *Reader) (interface{}, error) { return r.ReadGorillaFloat64s() },
+		func(r *Reader) (interface{}, error) { return r.ReadGorillaTimestamps() },
+	} {
+		_, err := decode(NewReader(bytes.NewBuffer(input)))
+		assert.Equal(t, errSize, err)
+
+		_, err = decode(NewReader(newNetworkSource(input)))
+		assert.Error(t, err)
+	}
+}
+
+func TestReadRLEInvalid(t *testing.T) {
+	inputs := [][]byte{
+		{0x2, 0x7, 0x0},      // zero run
+		{0x2, 0x7, 0x3},      // run too long
+		{0x2, 0x7, 0x1, 0x7}, // missing run
+	}
+
+	for _, input := range inputs {
+		_, err := NewReader(bytes.NewBuffer(input)).ReadRLEUint8s()
+		assert.Error(t, err)
+	}
+
+	_, err := NewReader(bytes.NewBuffer([]byte{0x2, 0x7, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0})).ReadRLEInt64s()
+	assert.Error(t, err)
+
+	_, err = NewReader(bytes.NewBuffer([]byte{0x2, 0x0})).ReadAutoInt64s()
+	assert.Error(t, err)
+
+	_, err = NewReader(bytes.NewBuffer([]byte{0x2, 0x0})).ReadAutoUint8s()
+	assert.Error(t, err)
+}
+
+func TestReadRLEInvalidLength(t *testing.T) {
+	input := []byte{0x80, 0x80, 0x80, 0x80, 0x1, 0x7, 0x80, 0x80, 0x80, 0x80, 0x1}
+	_, err := NewReader(bytes.NewBuffer(input)).ReadRLEUint8s()
+	assert.Equal(t, errSize, err)
+
+	// The limit itself is accepted, with the output growing per run
+	input = []byte{0x80, 0x80, 0x80, 0x8, 0x7, 0x80, 0x80, 0x80, 0x8}
+	out, err := NewReader(newNetworkSource(input)).ReadRLEUint8s()
+	assert.NoError(t, err)
+	assert.Len(t, out, maxRunLength)
+}
+
+func TestWriteRLELimit(t *testing.T) {
+	input := make([]uint8, maxRunLength+1)
+	assert.Equal(t, errSize, NewWriter(bytes.NewBuffer(nil)).WriteRLEUint8s(input))
+	assert.Equal(t, errSize, NewWriter(bytes.NewBuffer(nil)).WriteRLEInt64s(make([]int64, maxRunLength+1)))
+
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, NewWriter(buffer).WriteAutoUint8s(input))
+	assert.Equal(t, byte(encodingPlain), buffer.Bytes()[0])
+
+	output, err := NewReader(buffer).ReadAutoUint8s()
+	assert.NoError(t, err)
+	assert.Equal(t, input, output)
+}
+
 func TestWriteMethod(t *testing.T) {
 	w := NewWriter(bytes.NewBuffer(nil))
 	_, err := w.Write(nil)